@@ -0,0 +1,166 @@
+// Package awssource provides the getenv.Source that resolves the registered
+// variables from AWS Systems Manager Parameter Store and AWS Secrets Manager.
+//
+// The values are fetched in batches when the Source is created, so that the
+// Source can be chained after the process environment:
+//
+//	src, err := awssource.NewSSM(ctx, ssm.NewFromConfig(cfg), getenv.Names(),
+//		awssource.WithPath("/myapp/prod"))
+//	if err != nil {
+//		return err
+//	}
+//	getenv.SetSources(getenv.EnvSource(), src)
+//
+// The existing parameter trees that follow the conventions of chamber, such
+// as /myapp/prod/db_host, are read by NewSSMPath with ChamberMapper.
+package awssource
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/mah0x211/go-getenv/getenv"
+)
+
+// SSMAPI is the subset of the ssm.Client used by NewSSM.
+type SSMAPI interface {
+	GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error)
+}
+
+// SecretsManagerAPI is the subset of the secretsmanager.Client used by
+// NewSecretsManager.
+type SecretsManagerAPI interface {
+	BatchGetSecretValue(ctx context.Context, params *secretsmanager.BatchGetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.BatchGetSecretValueOutput, error)
+}
+
+const (
+	// maximum number of the names of a GetParameters request
+	ssmBatchSize = 10
+	// maximum number of the secret ids of a BatchGetSecretValue request
+	secretsManagerBatchSize = 20
+)
+
+type options struct {
+	mapper Mapper
+}
+
+// Option configures the Source.
+type Option func(o *options)
+
+func newOptions(opts []Option) *options {
+	o := &options{mapper: PathMapper("")}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithPath resolves the variables under the path, e.g. DB_HOST is resolved
+// from the parameter /myapp/prod/DB_HOST with the path "/myapp/prod". The
+// trailing slash is added if missing. It is the shorthand for
+// WithMapper(PathMapper(path)).
+func WithPath(path string) Option {
+	return WithMapper(PathMapper(path))
+}
+
+// ids returns the map of the parameter or secret ids to the names. It returns
+// ErrConflict if more than one name is translated into the same id.
+func ids(names []string, opts []Option) (map[string]string, error) {
+	o := newOptions(opts)
+	id2names := make(map[string]string, len(names))
+	for _, name := range names {
+		id := o.mapper.ID(name)
+		if other, found := id2names[id]; found && other != name {
+			return nil, fmt.Errorf("%w: %q and %q are both translated into %q", ErrConflict, other, name, id)
+		}
+		id2names[id] = name
+	}
+	return id2names, nil
+}
+
+// batches splits the ids into the batches of size.
+func batches(id2names map[string]string, size int) [][]string {
+	var list [][]string
+	var batch []string
+	for id := range id2names {
+		if batch = append(batch, id); len(batch) == size {
+			list = append(list, batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		list = append(list, batch)
+	}
+	return list
+}
+
+// NewSSM returns the Source named "ssm" that supplies the values of the
+// parameters of names fetched from the Parameter Store. The SecureString
+// parameters are decrypted, and the parameters that do not exist are treated
+// as undefined.
+func NewSSM(ctx context.Context, client SSMAPI, names []string, opts ...Option) (getenv.Source, error) {
+	id2names, err := ids(names, opts)
+	if err != nil {
+		return nil, fmt.Errorf("ssm: %w", err)
+	}
+	m := getenv.MapLookuper{}
+	for _, batch := range batches(id2names, ssmBatchSize) {
+		out, err := client.GetParameters(ctx, &ssm.GetParametersInput{
+			Names:          batch,
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ssm: %w", err)
+		}
+		for _, p := range out.Parameters {
+			if name, ok := id2names[aws.ToString(p.Name)]; ok && p.Value != nil {
+				m[name] = *p.Value
+			}
+		}
+	}
+	return getenv.NewSource("ssm", m), nil
+}
+
+// NewSecretsManager returns the Source named "secretsmanager" that supplies
+// the values of the secrets of names fetched from the Secrets Manager. The
+// binary secrets are used as is, and the secrets that do not exist are
+// treated as undefined.
+func NewSecretsManager(ctx context.Context, client SecretsManagerAPI, names []string, opts ...Option) (getenv.Source, error) {
+	id2names, err := ids(names, opts)
+	if err != nil {
+		return nil, fmt.Errorf("secretsmanager: %w", err)
+	}
+	m := getenv.MapLookuper{}
+	for _, batch := range batches(id2names, secretsManagerBatchSize) {
+		in := &secretsmanager.BatchGetSecretValueInput{SecretIdList: batch}
+		for {
+			out, err := client.BatchGetSecretValue(ctx, in)
+			if err != nil {
+				return nil, fmt.Errorf("secretsmanager: %w", err)
+			}
+			for _, e := range out.Errors {
+				if aws.ToString(e.ErrorCode) != "ResourceNotFoundException" {
+					return nil, fmt.Errorf("secretsmanager: %q %s: %s", aws.ToString(e.SecretId), aws.ToString(e.ErrorCode), aws.ToString(e.Message))
+				}
+			}
+			for _, v := range out.SecretValues {
+				name, ok := id2names[aws.ToString(v.Name)]
+				if !ok {
+					continue
+				} else if v.SecretString != nil {
+					m[name] = *v.SecretString
+				} else if v.SecretBinary != nil {
+					m[name] = string(v.SecretBinary)
+				}
+			}
+			if out.NextToken == nil {
+				break
+			}
+			in.NextToken = out.NextToken
+		}
+	}
+	return getenv.NewSource("secretsmanager", m), nil
+}
@@ -0,0 +1,125 @@
+package awssource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSSM struct {
+	params   map[string]string
+	requests [][]string
+	err      error
+}
+
+func (f *fakeSSM) GetParameters(ctx context.Context, in *ssm.GetParametersInput, _ ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	} else if !aws.ToBool(in.WithDecryption) {
+		return nil, fmt.Errorf("not decrypted")
+	}
+	f.requests = append(f.requests, in.Names)
+	out := &ssm.GetParametersOutput{}
+	for _, name := range in.Names {
+		if v, ok := f.params[name]; ok {
+			out.Parameters = append(out.Parameters, ssmtypes.Parameter{Name: aws.String(name), Value: aws.String(v)})
+		} else {
+			out.InvalidParameters = append(out.InvalidParameters, name)
+		}
+	}
+	return out, nil
+}
+
+func TestNewSSM(t *testing.T) {
+	names := []string{"DB_HOST", "DB_PASSWORD", "MISSING"}
+	for i := 0; i < 20; i++ {
+		names = append(names, fmt.Sprintf("EXTRA_%d", i))
+	}
+	client := &fakeSSM{params: map[string]string{
+		"/myapp/prod/DB_HOST":     "db.example.com",
+		"/myapp/prod/DB_PASSWORD": "s3cret",
+		"DB_HOST":                 "unprefixed",
+	}}
+
+	// test that resolves the names under the path in batches
+	src, err := NewSSM(context.Background(), client, names, WithPath("/myapp/prod"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ssm", src.Name())
+	assert.Len(t, client.requests, 3)
+	for _, batch := range client.requests {
+		assert.LessOrEqual(t, len(batch), ssmBatchSize)
+	}
+	v, ok := src.Lookup("DB_HOST")
+	assert.True(t, ok)
+	assert.Equal(t, "db.example.com", v)
+	v, _ = src.Lookup("DB_PASSWORD")
+	assert.Equal(t, "s3cret", v)
+	_, ok = src.Lookup("MISSING")
+	assert.False(t, ok)
+
+	// test that returns the error of the client
+	client.err = errors.New("throttled")
+	_, err = NewSSM(context.Background(), client, names)
+	assert.True(t, errors.Is(err, client.err))
+}
+
+type fakeSecretsManager struct {
+	secrets map[string]smtypes.SecretValueEntry
+	errs    map[string]string
+}
+
+func (f *fakeSecretsManager) BatchGetSecretValue(ctx context.Context, in *secretsmanager.BatchGetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.BatchGetSecretValueOutput, error) {
+	// return one secret per page to test the pagination
+	out := &secretsmanager.BatchGetSecretValueOutput{}
+	start := 0
+	if in.NextToken != nil {
+		fmt.Sscan(*in.NextToken, &start)
+	}
+	for i := start; i < len(in.SecretIdList); i++ {
+		id := in.SecretIdList[i]
+		if code, ok := f.errs[id]; ok {
+			out.Errors = append(out.Errors, smtypes.APIErrorType{SecretId: aws.String(id), ErrorCode: aws.String(code), Message: aws.String("error")})
+		} else if v, ok := f.secrets[id]; ok {
+			out.SecretValues = append(out.SecretValues, v)
+		}
+		if i+1 < len(in.SecretIdList) {
+			out.NextToken = aws.String(fmt.Sprint(i + 1))
+			break
+		}
+	}
+	return out, nil
+}
+
+func TestNewSecretsManager(t *testing.T) {
+	client := &fakeSecretsManager{
+		secrets: map[string]smtypes.SecretValueEntry{
+			"prod/API_KEY": {Name: aws.String("prod/API_KEY"), SecretString: aws.String("key")},
+			"prod/CERT":    {Name: aws.String("prod/CERT"), SecretBinary: []byte("cert")},
+		},
+		errs: map[string]string{"prod/MISSING": "ResourceNotFoundException"},
+	}
+
+	// test that resolves the secrets and ignores the missing secrets
+	src, err := NewSecretsManager(context.Background(), client, []string{"API_KEY", "CERT", "MISSING"}, WithPath("prod"))
+	assert.NoError(t, err)
+	assert.Equal(t, "secretsmanager", src.Name())
+	v, _ := src.Lookup("API_KEY")
+	assert.Equal(t, "key", v)
+	v, _ = src.Lookup("CERT")
+	assert.Equal(t, "cert", v)
+	_, ok := src.Lookup("MISSING")
+	assert.False(t, ok)
+
+	// test that returns the error of the secret
+	client.errs["prod/DENIED"] = "AccessDeniedException"
+	_, err = NewSecretsManager(context.Background(), client, []string{"API_KEY", "DENIED"}, WithPath("prod/"))
+	assert.Contains(t, err.Error(), "AccessDeniedException")
+}
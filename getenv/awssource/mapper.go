@@ -0,0 +1,145 @@
+package awssource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/mah0x211/go-getenv/getenv"
+)
+
+// Mapper translates the names of the variables into the ids of the parameters
+// or the secrets in the hierarchy, and back.
+type Mapper interface {
+	// Path returns the path of the hierarchy that has the ids.
+	Path() string
+	// ID returns the id of the variable of name.
+	ID(name string) string
+	// Name returns the name of the variable of id, and false if id is not
+	// the id of any variable.
+	Name(id string) (string, bool)
+}
+
+// ErrConflict is returned if the Mapper translates more than one name into
+// the same id, or more than one id into the same name, e.g. /myapp/db-host and
+// /myapp/db_host into DB_HOST by ChamberMapper.
+var ErrConflict = fmt.Errorf("conflicting mapping")
+
+// WithMapper resolves the variables from the ids translated by m.
+func WithMapper(m Mapper) Option {
+	return func(o *options) {
+		o.mapper = m
+	}
+}
+
+type pathMapper string
+
+// PathMapper returns the Mapper that translates the names into the ids under
+// the path as they are, e.g. DB_HOST into /myapp/prod/DB_HOST with the path
+// "/myapp/prod". The trailing slash is added if missing.
+func PathMapper(path string) Mapper {
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return pathMapper(path)
+}
+
+func (m pathMapper) Path() string {
+	return string(m)
+}
+
+func (m pathMapper) ID(name string) string {
+	return string(m) + name
+}
+
+func (m pathMapper) Name(id string) (string, bool) {
+	if !strings.HasPrefix(id, string(m)) {
+		return "", false
+	} else if name := id[len(m):]; name != "" && !strings.Contains(name, "/") {
+		return name, true
+	}
+	return "", false
+}
+
+type chamberMapper string
+
+// ChamberMapper returns the Mapper that follows the conventions of chamber,
+// which stores the keys of the service in lower case under the path of the
+// service, e.g. DB_HOST into /myapp/prod/db_host with the service
+// "myapp/prod". The keys are translated back into the names in upper case
+// with "-" replaced by "_" like "chamber exec", e.g. /myapp/prod/db-host
+// into DB_HOST. The keys are stored under the root path "/" if service is
+// empty.
+func ChamberMapper(service string) Mapper {
+	service = strings.Trim(strings.ToLower(service), "/")
+	if service == "" {
+		return chamberMapper("/")
+	}
+	return chamberMapper("/" + service + "/")
+}
+
+func (m chamberMapper) Path() string {
+	return string(m)
+}
+
+func (m chamberMapper) ID(name string) string {
+	return string(m) + strings.ToLower(name)
+}
+
+func (m chamberMapper) Name(id string) (string, bool) {
+	key, ok := pathMapper(m).Name(id)
+	if !ok {
+		return "", false
+	}
+	return strings.ReplaceAll(strings.ToUpper(key), "-", "_"), true
+}
+
+// SSMPathAPI is the subset of the ssm.Client used by NewSSMPath.
+type SSMPathAPI interface {
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+}
+
+// NewSSMPath returns the Source named "ssm" that supplies the values of all
+// the parameters directly under the path of the Mapper set by WithMapper or
+// WithPath, so that the existing parameter tree is read without listing the
+// names, and the variables collected by the prefix are also supplied. The
+// ids are translated back into the names by the Mapper, and the parameters
+// whose ids are not translated are ignored. The SecureString parameters are
+// decrypted. The path defaults to "/". ErrConflict is returned if more than
+// one parameter is translated into the same name.
+func NewSSMPath(ctx context.Context, client SSMPathAPI, opts ...Option) (getenv.Source, error) {
+	o := newOptions(opts)
+	if o.mapper.Path() == "" {
+		o.mapper = PathMapper("/")
+	}
+	in := &ssm.GetParametersByPathInput{
+		Path:           aws.String(o.mapper.Path()),
+		WithDecryption: aws.Bool(true),
+	}
+
+	m := getenv.MapLookuper{}
+	name2ids := map[string]string{}
+	for {
+		out, err := client.GetParametersByPath(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("ssm: %w", err)
+		}
+		for _, p := range out.Parameters {
+			id := aws.ToString(p.Name)
+			if name, ok := o.mapper.Name(id); ok && p.Value != nil {
+				if other, found := name2ids[name]; found {
+					return nil, fmt.Errorf("ssm: %w: %q and %q are both translated into %q", ErrConflict, other, id, name)
+				}
+				name2ids[name] = id
+				m[name] = *p.Value
+			}
+		}
+		if out.NextToken == nil {
+			break
+		}
+		in.NextToken = out.NextToken
+	}
+	return getenv.NewSource("ssm", m), nil
+}
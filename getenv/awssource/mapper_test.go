@@ -0,0 +1,185 @@
+package awssource
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/mah0x211/go-getenv/getenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathMapper(t *testing.T) {
+	m := PathMapper("/myapp/prod")
+	assert.Equal(t, "/myapp/prod/", m.Path())
+	assert.Equal(t, "/myapp/prod/DB_HOST", m.ID("DB_HOST"))
+	for id, want := range map[string]string{
+		"/myapp/prod/DB_HOST":      "DB_HOST",
+		"/myapp/prod/":             "",
+		"/myapp/prod/nested/VALUE": "",
+		"/myapp/dev/DB_HOST":       "",
+	} {
+		name, ok := m.Name(id)
+		assert.Equal(t, want, name, id)
+		assert.Equal(t, want != "", ok, id)
+	}
+
+	// test that the empty path maps the names as they are
+	m = PathMapper("")
+	assert.Equal(t, "DB_HOST", m.ID("DB_HOST"))
+	name, ok := m.Name("DB_HOST")
+	assert.True(t, ok)
+	assert.Equal(t, "DB_HOST", name)
+}
+
+func TestChamberMapper(t *testing.T) {
+	for _, service := range []string{"myapp/prod", "/MyApp/prod/"} {
+		m := ChamberMapper(service)
+		assert.Equal(t, "/myapp/prod/", m.Path())
+		assert.Equal(t, "/myapp/prod/db_host", m.ID("DB_HOST"))
+	}
+
+	// test that the empty service is the root path
+	for _, service := range []string{"", "/", "//"} {
+		m := ChamberMapper(service)
+		assert.Equal(t, "/", m.Path())
+		assert.Equal(t, "/db_host", m.ID("DB_HOST"))
+		name, ok := m.Name("/db-host")
+		assert.True(t, ok)
+		assert.Equal(t, "DB_HOST", name)
+	}
+
+	m := ChamberMapper("myapp/prod")
+	for id, want := range map[string]string{
+		"/myapp/prod/db_host":      "DB_HOST",
+		"/myapp/prod/db-password":  "DB_PASSWORD",
+		"/myapp/prod/nested/value": "",
+		"/other/db_host":           "",
+	} {
+		name, ok := m.Name(id)
+		assert.Equal(t, want, name, id)
+		assert.Equal(t, want != "", ok, id)
+	}
+}
+
+type fakeSSMPath struct {
+	params map[string]string
+	paths  []string
+	err    error
+}
+
+func (f *fakeSSMPath) GetParametersByPath(ctx context.Context, in *ssm.GetParametersByPathInput, _ ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.paths = append(f.paths, aws.ToString(in.Path))
+
+	var ids []string
+	for id := range f.params {
+		if strings.HasPrefix(id, aws.ToString(in.Path)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	// return a parameter per page
+	i := 0
+	if in.NextToken != nil {
+		for i < len(ids) && ids[i] != *in.NextToken {
+			i++
+		}
+	}
+	out := &ssm.GetParametersByPathOutput{}
+	if i < len(ids) {
+		out.Parameters = []ssmtypes.Parameter{{Name: aws.String(ids[i]), Value: aws.String(f.params[ids[i]])}}
+		if i+1 < len(ids) {
+			out.NextToken = aws.String(ids[i+1])
+		}
+	}
+	return out, nil
+}
+
+func TestNewSSMPath(t *testing.T) {
+	client := &fakeSSMPath{params: map[string]string{
+		"/myapp/prod/db_host":          "db.example.com",
+		"/myapp/prod/db-password":      "s3cret",
+		"/myapp/prod/header_accept":    "text/plain",
+		"/myapp/prod/nested/ignored":   "x",
+		"/myapp/dev/db_host":           "dev.example.com",
+		"/myapp/prodextra/not_a_child": "x",
+	}}
+
+	// test that reads the parameter tree of the chamber service
+	src, err := NewSSMPath(context.Background(), client, WithMapper(ChamberMapper("myapp/prod")))
+	assert.NoError(t, err)
+	assert.Equal(t, "ssm", src.Name())
+	assert.Equal(t, []string{
+		"DB_HOST=db.example.com",
+		"DB_PASSWORD=s3cret",
+		"HEADER_ACCEPT=text/plain",
+	}, src.(getenv.EnvironLookuper).Environ())
+	for _, path := range client.paths {
+		assert.Equal(t, "/myapp/prod/", path)
+	}
+
+	// test that the values are parsed into the registered variables
+	set := getenv.NewEnvSet()
+	var host string
+	headers := map[string]string{}
+	assert.NoError(t, set.Set("DB_HOST", "", &host))
+	assert.NoError(t, set.CollectPrefix("HEADER_", "", &headers))
+	assert.NoError(t, set.ParseFrom(src))
+	assert.Equal(t, "db.example.com", host)
+	assert.Equal(t, map[string]string{"ACCEPT": "text/plain"}, headers)
+
+	// test that the registered names resolve the same parameters by NewSSM
+	ssmClient := &fakeSSM{params: client.params}
+	src, err = NewSSM(context.Background(), ssmClient, []string{"DB_HOST"}, WithMapper(ChamberMapper("myapp/prod")))
+	assert.NoError(t, err)
+	v, ok := src.Lookup("DB_HOST")
+	assert.True(t, ok)
+	assert.Equal(t, "db.example.com", v)
+
+	// test that the ids are kept as they are by WithPath
+	client = &fakeSSMPath{params: map[string]string{
+		"/myapp/prod/DB_HOST": "db.example.com",
+		"/DB_HOST":            "root",
+	}}
+	src, err = NewSSMPath(context.Background(), client, WithPath("/myapp/prod"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DB_HOST=db.example.com"}, src.(getenv.EnvironLookuper).Environ())
+
+	// test that the path defaults to the root
+	client.paths = nil
+	src, err = NewSSMPath(context.Background(), client)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/"}, client.paths[:1])
+	assert.Equal(t, []string{"DB_HOST=root"}, src.(getenv.EnvironLookuper).Environ())
+
+	// test that returns ErrConflict if the ids are translated into the same
+	// name
+	client = &fakeSSMPath{params: map[string]string{
+		"/myapp/prod/db_host": "a",
+		"/myapp/prod/db-host": "b",
+	}}
+	_, err = NewSSMPath(context.Background(), client, WithMapper(ChamberMapper("myapp/prod")))
+	assert.True(t, errors.Is(err, ErrConflict))
+	assert.Contains(t, err.Error(), `are both translated into "DB_HOST"`)
+
+	// test that returns ErrConflict if the names are translated into the same
+	// id
+	_, err = NewSSM(context.Background(), ssmClient, []string{"DB_HOST", "db_host"}, WithMapper(ChamberMapper("myapp/prod")))
+	assert.True(t, errors.Is(err, ErrConflict))
+	assert.Equal(t, `ssm: conflicting mapping: "DB_HOST" and "db_host" are both translated into "/myapp/prod/db_host"`, err.Error())
+
+	// test that the error of the request is returned
+	client.err = errors.New("access denied")
+	_, err = NewSSMPath(context.Background(), client)
+	assert.True(t, errors.Is(err, client.err))
+	assert.Equal(t, "ssm: access denied", err.Error())
+}
@@ -0,0 +1,556 @@
+package getenv
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bindField is the field of the struct to be registered by Bind.
+type bindField struct {
+	name       string
+	desc       string
+	defval     *string
+	required   bool
+	value      interface{}
+	parse      ParseFunc
+	check      CheckFunc
+	sep        string
+	noTrim     bool
+	allowEmpty bool
+	file       bool
+	secret     bool
+}
+
+// envTag is the parsed `env` tag.
+type envTag struct {
+	name       string
+	required   bool
+	noTrim     bool
+	allowEmpty bool
+	file       bool
+	secret     bool
+}
+
+// parseEnvTag parses the `env` tag value in the form of "NAME[,option...]".
+func parseEnvTag(tag string) (envTag, error) {
+	opts := strings.Split(tag, ",")
+	et := envTag{name: strings.TrimSpace(opts[0])}
+	for _, opt := range opts[1:] {
+		switch opt = strings.TrimSpace(opt); opt {
+		case "required":
+			et.required = true
+		case "notrim":
+			et.noTrim = true
+		case "allowempty":
+			et.allowEmpty = true
+		case "file":
+			et.file = true
+		case "secret":
+			et.secret = true
+		case "":
+		default:
+			return envTag{}, fmt.Errorf("unknown env tag option %q", opt)
+		}
+	}
+	return et, nil
+}
+
+// NamingFunc derives the environment variable name from the field name of the
+// struct that does not have the name in the `env` tag.
+type NamingFunc func(field string) string
+
+// ScreamingSnakeCase converts the field name to SCREAMING_SNAKE_CASE. The
+// consecutive upper case letters are treated as an acronym, e.g. "HTTPPort"
+// is converted to "HTTP_PORT".
+func ScreamingSnakeCase(field string) string {
+	b := make([]byte, 0, len(field)+4)
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if i > 0 && isUpper(c) {
+			prev := field[i-1]
+			if isLower(prev) || isDigit(prev) ||
+				(isUpper(prev) && i+1 < len(field) && isLower(field[i+1])) {
+				b = append(b, '_')
+			}
+		}
+		if isLower(c) {
+			c -= 'a' - 'A'
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+// isNestedStruct returns true if t is a struct type that is not parsed as a
+// single value.
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !isScalarType(t)
+}
+
+// nestedPrefix returns the prefix of the fields of the nested struct field.
+// The `prefix` tag is used as is, otherwise the name of the `env` tag or the
+// name derived from the field name is used with a trailing underscore.
+func nestedPrefix(sf reflect.StructField, namefn NamingFunc) (string, error) {
+	if prefix, ok := sf.Tag.Lookup("prefix"); ok {
+		return prefix, nil
+	}
+
+	name := namefn(sf.Name)
+	if sf.Anonymous {
+		// embedded struct is flattened by default
+		name = ""
+	}
+	if tag, ok := sf.Tag.Lookup("env"); ok {
+		et, err := parseEnvTag(tag)
+		if err != nil {
+			return "", err
+		} else if et.required {
+			return "", fmt.Errorf("required option cannot be used for struct")
+		} else if et.noTrim || et.allowEmpty {
+			return "", fmt.Errorf("notrim and allowempty options cannot be used for struct")
+		} else if et.file || et.secret {
+			return "", fmt.Errorf("file and secret options cannot be used for struct")
+		} else if et.name != "" {
+			name = et.name
+		}
+	}
+	if name == "" {
+		return "", nil
+	}
+	return name + "_", nil
+}
+
+// binder collects the fields of the struct to be registered.
+type binder struct {
+	set    *EnvSet
+	namefn NamingFunc
+	// collect the fields without modifying the struct, and expand the
+	// elements of the slice of struct into the fields
+	readonly bool
+	fields   []bindField
+	lists    []*envList
+}
+
+func (b *binder) collect(ref reflect.Value, prefix string) error {
+	t := ref.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("env")
+		if tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		fv := ref.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Ptr && isNestedStruct(sf.Type.Elem()) {
+			// allocate the embedded struct pointer to register its fields
+			if fv.IsNil() {
+				if b.readonly {
+					continue
+				}
+				fv.Set(reflect.New(sf.Type.Elem()))
+			}
+			fv = fv.Elem()
+		}
+		if isNestedStruct(fv.Type()) {
+			pfx, err := nestedPrefix(sf, b.namefn)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", sf.Name, err)
+			} else if err = b.collect(fv, prefix+pfx); err != nil {
+				return fmt.Errorf("field %s: %w", sf.Name, err)
+			}
+			continue
+		}
+
+		et, err := parseEnvTag(tag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		name := et.name
+		if name == "" {
+			name = b.namefn(sf.Name)
+		}
+
+		if fv.Kind() == reflect.Slice && isNestedStruct(fv.Type().Elem()) {
+			// slice of struct is populated from the indexed variables
+			if b.readonly {
+				for j := 0; j < fv.Len(); j++ {
+					if err := b.collect(fv.Index(j), prefix+name+"_"+strconv.Itoa(j)+"_"); err != nil {
+						return fmt.Errorf("field %s: %w", sf.Name, err)
+					}
+				}
+				continue
+			}
+			l, err := b.newEnvList(prefix+name+"_", sf.Tag.Get("desc"), et.required, fv)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", sf.Name, err)
+			}
+			b.lists = append(b.lists, l)
+			continue
+		}
+
+		f := bindField{
+			name:       prefix + name,
+			desc:       sf.Tag.Get("desc"),
+			required:   et.required,
+			value:      fv.Addr().Interface(),
+			sep:        defaultSeparator,
+			noTrim:     et.noTrim,
+			allowEmpty: et.allowEmpty,
+			file:       et.file,
+			secret:     et.secret,
+		}
+		if s, ok := sf.Tag.Lookup("default"); ok {
+			f.defval = &s
+		}
+		if sep, ok := sf.Tag.Lookup("sep"); ok {
+			if sep == "" {
+				return fmt.Errorf("field %s: sep tag must not be empty", sf.Name)
+			}
+			f.parse = SliceParseFunc(sep)
+			f.sep = sep
+		}
+		b.fields = append(b.fields, f)
+	}
+	return nil
+}
+
+// prepare checks the collected fields and stores the default values and the
+// default checkers in the fields.
+func (b *binder) prepare() error {
+	seen := map[string]bool{}
+	for _, f := range b.fields {
+		if err := checkName(f.name); err != nil {
+			return fmt.Errorf("%q: %w", f.name, err)
+		} else if seen[b.set.seenKey(f.name)] || b.set.name2envs[b.set.envName(f.name)] != nil {
+			return fmt.Errorf("%w: %q already registered", ErrNameAlready, f.name)
+		} else if _, err = checkValue(f.value, false); err != nil {
+			return fmt.Errorf("%q: %w", f.name, err)
+		}
+		seen[b.set.seenKey(f.name)] = true
+	}
+	for _, l := range b.lists {
+		if seen[b.set.seenKey(l.prefix)] || b.set.prefix2collectors[b.set.collectorPrefix(l.prefix)] != nil {
+			return fmt.Errorf("%w: %q already registered", ErrNameAlready, l.prefix+indexPlaceholder)
+		}
+		seen[b.set.seenKey(l.prefix)] = true
+	}
+
+	// parse the default values into the copies not to change the struct
+	// unless all of them are valid
+	staged := make([]reflect.Value, len(b.fields))
+	for i, f := range b.fields {
+		if f.parse == nil {
+			b.fields[i].parse = defaultParseFunc
+		}
+		ref := reflect.ValueOf(f.value).Elem()
+		staged[i] = cloneValue(ref)
+		if f.defval != nil {
+			if err := b.fields[i].parse(staged[i].Interface(), f.name, *f.defval); err != nil {
+				return fmt.Errorf("default value of %q: %w", f.name, err)
+			}
+		}
+	}
+	for i, f := range b.fields {
+		reflect.ValueOf(f.value).Elem().Set(staged[i].Elem())
+		b.fields[i].check = defaultCheck(staged[i].Elem().Interface())
+	}
+	return nil
+}
+
+// render stores the formatted values of the collected fields into m.
+func (b *binder) render(m map[string]string) error {
+	for _, f := range b.fields {
+		s, ok, err := formatValue(reflect.ValueOf(f.value).Elem(), f.sep)
+		if err != nil {
+			return fmt.Errorf("%q: %w", f.name, err)
+		} else if ok {
+			m[f.name] = s
+		}
+	}
+	return nil
+}
+
+// Bind registers the exported fields of the struct pointed to by v. The tags
+// are interpreted as follows:
+//
+//	Port  int      `env:"PORT,required" default:"8080" desc:"listen port"`
+//	Hosts []string `env:"HOSTS" default:"a;b" sep:";"`
+//
+// The `env` tag is the environment variable name optionally followed by the
+// options: "required", "notrim" that is the same as WithNoTrim,
+// "allowempty" that is the same as WithAllowEmpty, "file" that is the same
+// as WithFile, and "secret" that is the same as WithSecret.
+// If the name is omitted, the name is derived from the field name by
+// ScreamingSnakeCase. The field with the `env:"-"` tag is ignored. The `sep`
+// tag is the separator of the slice and map values. The `default` tag is
+// parsed in the same way as the environment variable value and stored in the
+// field before registering, and the `desc` tag is used as the description.
+//
+// The fields of the nested struct are registered with the prefix that is
+// composed of the prefixes of the enclosing structs. The prefix of the nested
+// struct is the `prefix` tag if specified, otherwise the name of the `env`
+// tag or the name derived from the field name followed by an underscore:
+//
+//	type Config struct {
+//		DB struct {
+//			Host string `env:"HOST"` // DB_HOST
+//		}
+//		Cache struct {
+//			Host string `env:"HOST"` // REDIS_HOST
+//		} `env:"REDIS"`
+//	}
+//
+// The fields of the embedded struct, or the pointer to struct that is
+// allocated if nil, are flattened into the enclosing struct unless the
+// prefix is specified by the `prefix` or `env` tag.
+//
+// The slice of struct is populated by Parse from the indexed environment
+// variables such as UPSTREAM_0_HOST and UPSTREAM_1_HOST. The indexes are
+// discovered from the environment and the elements are stored in ascending
+// order of the indexes. The fields of the element are interpreted in the same
+// way as the struct fields, and the "required" option of the slice requires
+// at least one element.
+//
+// If any field fails to register, none of the fields are registered.
+func Bind(v interface{}) error {
+	return defaultSet.BindWithNaming(v, nil)
+}
+
+// Bind registers the fields of the struct to the set like the Bind function.
+func (s *EnvSet) Bind(v interface{}) error {
+	return s.BindWithNaming(v, nil)
+}
+
+// BindWithNaming is like Bind but derives the names of the fields by namefn.
+// If namefn is nil, ScreamingSnakeCase is used.
+func BindWithNaming(v interface{}, namefn NamingFunc) error {
+	return defaultSet.BindWithNaming(v, namefn)
+}
+
+// BindWithNaming registers the fields of the struct to the set like the
+// BindWithNaming function.
+func (s *EnvSet) BindWithNaming(v interface{}, namefn NamingFunc) error {
+	if namefn == nil {
+		namefn = ScreamingSnakeCase
+	}
+
+	ref := reflect.ValueOf(v)
+	if ref.Kind() != reflect.Ptr || ref.IsNil() || ref.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T is not a pointer to struct", ErrValue, v)
+	}
+
+	b := &binder{set: s, namefn: namefn}
+	if err := b.collect(ref.Elem(), s.prefix); err != nil {
+		return err
+	} else if err = b.prepare(); err != nil {
+		return err
+	}
+
+	for _, f := range b.fields {
+		opts := []Option{WithParse(f.parse)}
+		if f.sep != defaultSeparator {
+			opts = append(opts, WithSeparator(f.sep))
+		}
+		if f.required {
+			opts = append(opts, WithRequired())
+		}
+		if f.noTrim {
+			opts = append(opts, WithNoTrim())
+		}
+		if f.allowEmpty {
+			opts = append(opts, WithAllowEmpty())
+		}
+		if f.file {
+			opts = append(opts, WithFile())
+		}
+		if f.secret {
+			opts = append(opts, WithSecret())
+		}
+		// the names are already prefixed
+		if err := s.set(f.name, f.desc, f.value, false, opts); err != nil {
+			return err
+		}
+	}
+	for _, l := range b.lists {
+		s.prefix2collectors[l.prefix] = l
+	}
+	return nil
+}
+
+// indexPlaceholder is the placeholder of the index of the indexed variables
+// that is shown in the usage.
+const indexPlaceholder = "<N>"
+
+// envList is the slice of struct that is populated from the indexed
+// environment variables.
+type envList struct {
+	// prefix of the indexed variables, e.g. "UPSTREAM_"
+	prefix   string
+	desc     string
+	required bool
+	value    reflect.Value
+	defval   reflect.Value
+	set      *EnvSet
+	namefn   NamingFunc
+	// fields of the element for the usage
+	fields []bindField
+}
+
+func (b *binder) newEnvList(prefix, desc string, required bool, value reflect.Value) (*envList, error) {
+	l := &envList{
+		prefix:   prefix,
+		desc:     desc,
+		required: required,
+		value:    value,
+		defval:   reflect.ValueOf(value.Interface()),
+		set:      b.set,
+		namefn:   b.namefn,
+	}
+
+	// check the element fields with the first index
+	b, err := l.bindElem(reflect.New(value.Type().Elem()).Elem(), 0)
+	if err != nil {
+		return nil, err
+	}
+	first := prefix + "0_"
+	for _, f := range b.fields {
+		f.name = prefix + indexPlaceholder + "_" + strings.TrimPrefix(f.name, first)
+		l.fields = append(l.fields, f)
+	}
+	return l, nil
+}
+
+func (l *envList) bindElem(elem reflect.Value, idx int) (*binder, error) {
+	b := &binder{set: l.set, namefn: l.namefn}
+	if err := b.collect(elem, l.prefix+strconv.Itoa(idx)+"_"); err != nil {
+		return nil, err
+	} else if err = b.prepare(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// indexes returns the indexes of the variables found by lu in ascending
+// order.
+func (l *envList) indexes(lu Lookuper) []int {
+	found := map[int]bool{}
+	for name := range lookupPrefix(lu, l.prefix) {
+		if i := strings.IndexByte(name, '_'); i > 0 && i < len(name)-1 && isDigit(name[0]) {
+			if idx, err := strconv.Atoi(name[:i]); err == nil {
+				found[idx] = true
+			}
+		}
+	}
+
+	list := make([]int, 0, len(found))
+	for idx := range found {
+		list = append(list, idx)
+	}
+	sort.Ints(list)
+	return list
+}
+
+func (l *envList) render(m map[string]string) error {
+	for i := 0; i < l.value.Len(); i++ {
+		b := &binder{namefn: l.namefn, readonly: true}
+		if err := b.collect(l.value.Index(i), l.prefix+strconv.Itoa(i)+"_"); err != nil {
+			return err
+		} else if err = b.render(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *envList) env() *Env {
+	return &Env{
+		Name:         l.prefix,
+		Description:  l.desc,
+		DefaultValue: l.defval.Interface(),
+		Value:        l.value.Addr().Interface(),
+		Required:     l.required,
+	}
+}
+
+func (l *envList) reset() {
+	l.value.Set(l.defval)
+}
+
+func (l *envList) usage() []usageEntry {
+	entries := make([]usageEntry, 0, len(l.fields))
+	for _, f := range l.fields {
+		desc := f.desc
+		if desc == "" {
+			desc = l.desc
+		}
+		entries = append(entries, usageEntry{
+			name:     f.name,
+			desc:     desc,
+			defval:   reflect.ValueOf(f.value).Elem().Interface(),
+			required: f.required,
+		})
+	}
+	return entries
+}
+
+func (l *envList) parse(ctx context.Context, lu Lookuper) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	indexes := l.indexes(lu)
+	if len(indexes) == 0 {
+		if l.required {
+			return nil, fmt.Errorf("%w: %q", ErrNotDefined, l.prefix+indexPlaceholder+"_*")
+		}
+		return func() {}, nil
+	}
+
+	list := reflect.MakeSlice(l.value.Type(), 0, len(indexes))
+	for _, idx := range indexes {
+		elem := reflect.New(l.value.Type().Elem()).Elem()
+		b, err := l.bindElem(elem, idx)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range b.fields {
+			if v, _, ok, err := lookupFileValue(ctx, lu, f.name, f.noTrim, f.allowEmpty, f.file); err != nil {
+				return nil, err
+			} else if ok {
+				if v, err = l.set.expand(ctx, lu, f.name, v); err != nil {
+					return nil, err
+				} else if v, ok, err = l.set.indirect(f.name, v, f.noTrim, f.allowEmpty); err != nil {
+					return nil, err
+				} else if !ok {
+					if f.required {
+						return nil, fmt.Errorf("%w: %q", ErrNotDefined, f.name)
+					}
+					continue
+				} else if v, err = l.set.decrypt(f.name, v); err != nil {
+					return nil, err
+				} else if err = f.parse(f.value, f.name, v); err != nil {
+					return nil, fmt.Errorf("%w: %q %w", ErrEnvVar, f.name, err)
+				} else if err = f.check(f.value, f.name); err != nil {
+					return nil, fmt.Errorf("%w: %q %w", ErrEnvVar, f.name, err)
+				}
+			} else if f.required {
+				return nil, fmt.Errorf("%w: %q", ErrNotDefined, f.name)
+			}
+		}
+		for _, nl := range b.lists {
+			// the element is not visible until committed
+			commit, err := nl.parse(ctx, lu)
+			if err != nil {
+				return nil, err
+			}
+			commit()
+		}
+		list = reflect.Append(list, elem)
+	}
+	return func() {
+		l.value.Set(list)
+	}, nil
+}
@@ -0,0 +1,391 @@
+package getenv
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBind(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+		os.Unsetenv("TEST_HOSTS")
+	}()
+
+	type config struct {
+		Port    int           `env:"TEST_PORT,required" default:"8080" desc:"listen port"`
+		Hosts   []string      `env:"TEST_HOSTS"`
+		Timeout time.Duration `env:"TEST_TIMEOUT" default:"5s"`
+		Ignored string        `env:"-"`
+		NoTag   string
+		private string `env:"TEST_PRIVATE"`
+	}
+
+	// test that register the exported fields
+	cfg := config{}
+	assert.NoError(t, Bind(&cfg))
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Len(t, defaultSet.name2envs, 4)
+	assert.Contains(t, defaultSet.name2envs, "NO_TAG")
+	env := defaultSet.name2envs["TEST_PORT"]
+	assert.Equal(t, "listen port", env.Description)
+	assert.Equal(t, 8080, env.DefaultValue)
+	assert.True(t, env.Required)
+	assert.False(t, defaultSet.name2envs["TEST_HOSTS"].Required)
+
+	// test that Parse sets the fields
+	os.Setenv("TEST_PORT", "9090")
+	os.Setenv("TEST_HOSTS", "a,b")
+	assert.NoError(t, Parse())
+	assert.Equal(t, 9090, cfg.Port)
+	assert.Equal(t, []string{"a", "b"}, cfg.Hosts)
+	assert.Equal(t, "", cfg.private)
+
+	// test that returns ErrNameAlready without registering any fields
+	defaultSet = NewEnvSet()
+	assert.NoError(t, Set("TEST_TIMEOUT", "", new(string)))
+	err := Bind(&config{})
+	assert.True(t, errors.Is(err, ErrNameAlready))
+	assert.Len(t, defaultSet.name2envs, 1)
+
+	// test that returns ErrNameAlready if the fields have the same name
+	defaultSet = NewEnvSet()
+	err = Bind(&struct {
+		A string `env:"TEST_DUP"`
+		B string `env:"TEST_DUP"`
+	}{})
+	assert.True(t, errors.Is(err, ErrNameAlready))
+	assert.Empty(t, defaultSet.name2envs)
+
+	// test that returns error if the field type is not supported
+	err = Bind(&struct {
+		A string   `env:"TEST_A"`
+		B chan int `env:"TEST_B"`
+	}{})
+	assert.True(t, errors.Is(err, ErrValue))
+	assert.Empty(t, defaultSet.name2envs)
+
+	// test that returns error if the name is invalid
+	err = Bind(&struct {
+		A string `env:"TEST A"`
+	}{})
+	assert.True(t, errors.Is(err, ErrName))
+
+	// test that returns error if the tag option is unknown
+	err = Bind(&struct {
+		A string `env:"TEST_A,optional"`
+	}{})
+	assert.Equal(t, `field A: unknown env tag option "optional"`, err.Error())
+
+	// test that returns error and the struct is not changed if the default
+	// value is invalid
+	invalid := struct {
+		A int `env:"TEST_A" default:"5"`
+		B int `env:"TEST_B" default:"foo"`
+	}{A: 1}
+	err = Bind(&invalid)
+	assert.False(t, errors.Is(err, ErrValue))
+	assert.Contains(t, err.Error(), `default value of "TEST_B": strconv.ParseInt`)
+	assert.Equal(t, 1, invalid.A)
+	assert.Empty(t, defaultSet.name2envs)
+
+	// test that returns ErrValue if the value is not a pointer to struct
+	for _, v := range []interface{}{nil, cfg, (*config)(nil), new(int)} {
+		assert.True(t, errors.Is(Bind(v), ErrValue))
+	}
+}
+
+func TestBindNested(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	type dbConfig struct {
+		Host string `env:"HOST" default:"localhost"`
+		Port int    `env:"PORT"`
+	}
+	type config struct {
+		DB    dbConfig
+		Cache struct {
+			Host string `env:"HOST"`
+			Pool struct {
+				Size int `env:"SIZE"`
+			}
+		} `env:"REDIS"`
+		Replica dbConfig  `prefix:"RO_"`
+		Flat    dbConfig  `prefix:""`
+		Created time.Time `env:"CREATED"`
+		Skipped dbConfig  `env:"-"`
+	}
+
+	// test that register the fields of nested structs with composed prefixes
+	cfg := config{}
+	assert.NoError(t, Bind(&cfg))
+	names := []string{}
+	for name := range defaultSet.name2envs {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{
+		"DB_HOST", "DB_PORT",
+		"REDIS_HOST", "REDIS_POOL_SIZE",
+		"RO_HOST", "RO_PORT",
+		"HOST", "PORT",
+		"CREATED",
+	}, names)
+	assert.Equal(t, "localhost", cfg.DB.Host)
+	assert.Equal(t, "localhost", cfg.Flat.Host)
+
+	// test that returns error if the required option is used for struct
+	defaultSet = NewEnvSet()
+	err := Bind(&struct {
+		DB dbConfig `env:"DB,required"`
+	}{})
+	assert.Equal(t, "field DB: required option cannot be used for struct", err.Error())
+
+	// test that returns error with the field path
+	err = Bind(&struct {
+		DB struct {
+			Port int `env:"PORT,foo"`
+		}
+	}{})
+	assert.Equal(t, `field DB: field Port: unknown env tag option "foo"`, err.Error())
+	assert.Empty(t, defaultSet.name2envs)
+}
+
+func TestScreamingSnakeCase(t *testing.T) {
+	// test that convert the field names to SCREAMING_SNAKE_CASE
+	for field, exp := range map[string]string{
+		"Port":       "PORT",
+		"HTTPPort":   "HTTP_PORT",
+		"ListenAddr": "LISTEN_ADDR",
+		"DB":         "DB",
+		"UserID":     "USER_ID",
+		"Level2":     "LEVEL2",
+		"Snake_Case": "SNAKE_CASE",
+		"APIKeyV2":   "API_KEY_V2",
+	} {
+		assert.Equal(t, exp, ScreamingSnakeCase(field), field)
+	}
+}
+
+func TestBindWithNaming(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	type config struct {
+		HTTPPort int `env:",required"`
+		DB       struct {
+			UserName string
+		}
+		Explicit string `env:"EXPLICIT_NAME"`
+	}
+
+	// test that derive the names by the default naming strategy
+	assert.NoError(t, Bind(&config{}))
+	assert.Contains(t, defaultSet.name2envs, "HTTP_PORT")
+	assert.True(t, defaultSet.name2envs["HTTP_PORT"].Required)
+	assert.Contains(t, defaultSet.name2envs, "DB_USER_NAME")
+	assert.Contains(t, defaultSet.name2envs, "EXPLICIT_NAME")
+
+	// test that derive the names by the specified naming strategy
+	defaultSet = NewEnvSet()
+	assert.NoError(t, BindWithNaming(&config{}, func(field string) string {
+		return "APP_" + strings.ToUpper(field)
+	}))
+	assert.Contains(t, defaultSet.name2envs, "APP_HTTPPORT")
+	assert.Contains(t, defaultSet.name2envs, "APP_DB_APP_USERNAME")
+	assert.Contains(t, defaultSet.name2envs, "EXPLICIT_NAME")
+}
+
+type TLSConfig struct {
+	CertFile string `env:"TLS_CERT_FILE"`
+	KeyFile  string `env:"TLS_KEY_FILE"`
+}
+
+type Logging struct {
+	Level string `default:"info"`
+}
+
+func TestBindEmbedded(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	type api struct {
+		TLSConfig
+		*Logging
+		Port int
+	}
+	type worker struct {
+		TLSConfig `env:"WORKER"`
+		*Logging  `prefix:"W_"`
+	}
+
+	// test that flatten the fields of embedded structs
+	cfg := api{}
+	assert.NoError(t, Bind(&cfg))
+	names := []string{}
+	for name := range defaultSet.name2envs {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"TLS_CERT_FILE", "TLS_KEY_FILE", "LEVEL", "PORT"}, names)
+	assert.NotNil(t, cfg.Logging)
+	assert.Equal(t, "info", cfg.Level)
+
+	// test that embedded struct is prefixed if specified
+	defaultSet = NewEnvSet()
+	logging := &Logging{}
+	wcfg := worker{Logging: logging}
+	assert.NoError(t, Bind(&wcfg))
+	names = []string{}
+	for name := range defaultSet.name2envs {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"WORKER_TLS_CERT_FILE", "WORKER_TLS_KEY_FILE", "W_LEVEL"}, names)
+	assert.Same(t, logging, wcfg.Logging)
+	assert.Equal(t, "info", logging.Level)
+}
+
+func TestBindTags(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_HOSTS")
+	}()
+
+	type config struct {
+		Hosts  []string          `env:"TEST_HOSTS,required" default:"a;b" sep:";" desc:"list of hosts"`
+		Labels map[string]string `env:"TEST_LABELS" default:"k1=v1|k2=v2" sep:"|"`
+		Ports  []int             `env:"TEST_PORTS" default:"80,443"`
+	}
+
+	// test that the default values are parsed with the separator
+	cfg := config{}
+	assert.NoError(t, Bind(&cfg))
+	assert.Equal(t, []string{"a", "b"}, cfg.Hosts)
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, cfg.Labels)
+	assert.Equal(t, []int{80, 443}, cfg.Ports)
+	env := defaultSet.name2envs["TEST_HOSTS"]
+	assert.Equal(t, "list of hosts", env.Description)
+	assert.Equal(t, []string{"a", "b"}, env.DefaultValue)
+	assert.True(t, env.Required)
+
+	// test that the environment variable is parsed with the separator
+	os.Setenv("TEST_HOSTS", "x;y;z")
+	assert.NoError(t, Parse())
+	assert.Equal(t, []string{"x", "y", "z"}, cfg.Hosts)
+
+	// test that returns error if the separator is empty
+	defaultSet = NewEnvSet()
+	err := Bind(&struct {
+		Hosts []string `sep:""`
+	}{})
+	assert.Equal(t, "field Hosts: sep tag must not be empty", err.Error())
+}
+
+func TestBindIndexedSlice(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		for _, name := range []string{
+			"UPSTREAM_0_HOST", "UPSTREAM_0_PORT", "UPSTREAM_2_HOST",
+			"UPSTREAM_10_HOST", "UPSTREAM_X_HOST", "UPSTREAM_1",
+		} {
+			os.Unsetenv(name)
+		}
+	}()
+
+	type upstream struct {
+		Host string `env:",required" desc:"upstream host"`
+		Port int    `default:"80"`
+	}
+	type config struct {
+		Upstreams []upstream `env:"UPSTREAM" desc:"upstream server"`
+	}
+
+	// test that the element fields are shown in the usage with the placeholder
+	cfg := config{}
+	assert.NoError(t, Bind(&cfg))
+	usage := map[string][]interface{}{}
+	Usage(func(name, desc string, defval interface{}, required bool) {
+		usage[name] = []interface{}{desc, defval, required}
+	})
+	assert.Equal(t, map[string][]interface{}{
+		"UPSTREAM_<N>_HOST": {"upstream host", "", true},
+		"UPSTREAM_<N>_PORT": {"upstream server", 80, false},
+	}, usage)
+
+	// test that the slice is not changed if no indexed variables are defined
+	assert.NoError(t, Parse())
+	assert.Nil(t, cfg.Upstreams)
+
+	// test that populate the slice in ascending order of the indexes
+	os.Setenv("UPSTREAM_10_HOST", "host10")
+	os.Setenv("UPSTREAM_0_HOST", "host0")
+	os.Setenv("UPSTREAM_0_PORT", "8080")
+	os.Setenv("UPSTREAM_2_HOST", "host2")
+	os.Setenv("UPSTREAM_X_HOST", "ignored")
+	os.Setenv("UPSTREAM_1", "ignored")
+	assert.NoError(t, Parse())
+	assert.Equal(t, []upstream{
+		{Host: "host0", Port: 8080},
+		{Host: "host2", Port: 80},
+		{Host: "host10", Port: 80},
+	}, cfg.Upstreams)
+
+	// test that returns error if the required field of element is not defined
+	os.Unsetenv("UPSTREAM_2_HOST")
+	os.Setenv("UPSTREAM_2_PORT", "8080")
+	defer os.Unsetenv("UPSTREAM_2_PORT")
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrNotDefined))
+	assert.Contains(t, err.Error(), `"UPSTREAM_2_HOST"`)
+
+	// test that returns error if the field of element is invalid
+	os.Setenv("UPSTREAM_2_HOST", "host2")
+	os.Setenv("UPSTREAM_2_PORT", "foo")
+	err = Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), `"UPSTREAM_2_PORT"`)
+
+	// test that the field of element is checked by the default checker
+	defaultSet = NewEnvSet()
+	var prices struct {
+		Items []struct {
+			Price Decimal `default:"0.00"`
+		} `env:"ITEM"`
+	}
+	assert.NoError(t, Bind(&prices))
+	err = ParseMap(map[string]string{"ITEM_0_PRICE": "0.125"})
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), `"ITEM_0_PRICE" decimal 0.125 must have at most 2 digits`)
+	assert.NoError(t, ParseMap(map[string]string{"ITEM_0_PRICE": "0.12"}))
+	assert.Equal(t, "0.12", prices.Items[0].Price.String())
+
+	// test that returns error if the required slice has no elements
+	defaultSet = NewEnvSet()
+	var required struct {
+		Backends []upstream `env:",required"`
+	}
+	assert.NoError(t, Bind(&required))
+	err = Parse()
+	assert.True(t, errors.Is(err, ErrNotDefined))
+	assert.Contains(t, err.Error(), `"BACKENDS_<N>_*"`)
+
+	// test that returns ErrNameAlready if the slice is already registered
+	err = Bind(&required)
+	assert.True(t, errors.Is(err, ErrNameAlready))
+
+	// test that returns error if the element field is not supported
+	err = Bind(&struct {
+		Backends []struct {
+			C chan int
+		}
+	}{})
+	assert.True(t, errors.Is(err, ErrValue))
+}
@@ -0,0 +1,179 @@
+package getenv
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Builder builds the definition of the environment variable to be registered.
+type Builder struct {
+	set      *EnvSet
+	name     string
+	desc     string
+	defval   interface{}
+	required bool
+	parsefn  ParseFunc
+	checkfns []CheckFunc
+}
+
+// Var returns the Builder of the environment variable named name.
+//
+//	err := getenv.Var("PORT").Desc("listen port").Default(8080).Required().BindInt(&port)
+func Var(name string) *Builder {
+	return defaultSet.Var(name)
+}
+
+// Var returns the Builder of the environment variable that is registered to
+// the set.
+func (s *EnvSet) Var(name string) *Builder {
+	return &Builder{set: s, name: name}
+}
+
+// Desc sets the description.
+func (b *Builder) Desc(desc string) *Builder {
+	b.desc = desc
+	return b
+}
+
+// Default sets the default value that is stored in the value when it is
+// bound. The type of v must be assignable or convertible to the type of the
+// value.
+func (b *Builder) Default(v interface{}) *Builder {
+	b.defval = v
+	return b
+}
+
+// Required makes the environment variable required.
+func (b *Builder) Required() *Builder {
+	b.required = true
+	return b
+}
+
+// Parse sets the parser of the environment variable value.
+func (b *Builder) Parse(parsefn ParseFunc) *Builder {
+	b.parsefn = parsefn
+	return b
+}
+
+// Check adds the checker of the parsed value. The checkers are called in the
+// order they were added.
+func (b *Builder) Check(checkfn CheckFunc) *Builder {
+	b.checkfns = append(b.checkfns, checkfn)
+	return b
+}
+
+func (b *Builder) options() []Option {
+	opts := []Option{WithParse(b.parsefn)}
+	if b.required {
+		opts = append(opts, WithRequired())
+	}
+	if checkfn := b.checkFunc(); checkfn != nil {
+		opts = append(opts, WithCheck(checkfn))
+	}
+	return opts
+}
+
+func (b *Builder) checkFunc() CheckFunc {
+	switch len(b.checkfns) {
+	case 0:
+		return nil
+	case 1:
+		return b.checkfns[0]
+	}
+
+	checkfns := b.checkfns
+	return func(iv interface{}, envName string) error {
+		for _, fn := range checkfns {
+			if err := fn(iv, envName); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// Bind registers the environment variable with value that must be a pointer
+// to the type supported by Set. The numeric default value is converted to the
+// type of the value.
+func (b *Builder) Bind(value interface{}) error {
+	if b.defval == nil {
+		return b.set.Set(b.name, b.desc, value, b.options()...)
+	}
+
+	ref := reflect.ValueOf(value)
+	if ref.Kind() != reflect.Ptr || ref.IsNil() {
+		return fmt.Errorf("%w: %T is not a non-nil pointer", ErrValue, value)
+	}
+	ref = ref.Elem()
+	dv := reflect.ValueOf(b.defval)
+	t := ref.Type()
+	switch {
+	case dv.Type().AssignableTo(t):
+	case isNumberKind(dv.Kind()) && isNumberKind(t.Kind()):
+		dv = dv.Convert(t)
+	default:
+		return fmt.Errorf("%w: default value %T is not assignable to %v", ErrValue, b.defval, t)
+	}
+
+	// restore the original value if failed to register
+	orig := reflect.New(t).Elem()
+	orig.Set(ref)
+	ref.Set(dv)
+	if err := b.set.Set(b.name, b.desc, value, b.options()...); err != nil {
+		ref.Set(orig)
+		return err
+	}
+	return nil
+}
+
+// BindString registers the environment variable with the string value.
+func (b *Builder) BindString(value *string) error {
+	return b.Bind(value)
+}
+
+// BindBool registers the environment variable with the bool value.
+func (b *Builder) BindBool(value *bool) error {
+	return b.Bind(value)
+}
+
+// BindInt registers the environment variable with the int value.
+func (b *Builder) BindInt(value *int) error {
+	return b.Bind(value)
+}
+
+// BindInt64 registers the environment variable with the int64 value.
+func (b *Builder) BindInt64(value *int64) error {
+	return b.Bind(value)
+}
+
+// BindUint registers the environment variable with the uint value.
+func (b *Builder) BindUint(value *uint) error {
+	return b.Bind(value)
+}
+
+// BindFloat64 registers the environment variable with the float64 value.
+func (b *Builder) BindFloat64(value *float64) error {
+	return b.Bind(value)
+}
+
+// BindDuration registers the environment variable with the time.Duration
+// value.
+func (b *Builder) BindDuration(value *time.Duration) error {
+	return b.Bind(value)
+}
+
+// BindStrings registers the environment variable with the slice of strings.
+func (b *Builder) BindStrings(value *[]string) error {
+	return b.Bind(value)
+}
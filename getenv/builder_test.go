@@ -0,0 +1,76 @@
+package getenv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+	}()
+
+	positive := func(iv interface{}, envName string) error {
+		if *iv.(*int) <= 0 {
+			return fmt.Errorf("must be positive")
+		}
+		return nil
+	}
+	even := func(iv interface{}, envName string) error {
+		if *iv.(*int)%2 != 0 {
+			return fmt.Errorf("must be even")
+		}
+		return nil
+	}
+
+	// test that register the variable with the definition
+	var port int
+	err := Var("TEST_PORT").Desc("listen port").Default(8080).Required().
+		Check(positive).Check(even).BindInt(&port)
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, port)
+	env := defaultSet.name2envs["TEST_PORT"]
+	assert.Equal(t, "listen port", env.Description)
+	assert.Equal(t, 8080, env.DefaultValue)
+	assert.True(t, env.Required)
+
+	// test that all checkers are called in order
+	os.Setenv("TEST_PORT", "-1")
+	assert.Contains(t, Parse().Error(), "must be positive")
+	os.Setenv("TEST_PORT", "9091")
+	assert.Contains(t, Parse().Error(), "must be even")
+	os.Setenv("TEST_PORT", "9090")
+	assert.NoError(t, Parse())
+	assert.Equal(t, 9090, port)
+
+	// test that the numeric default value is converted
+	var ratio float64
+	var timeout time.Duration
+	var hosts []string
+	var level int
+	assert.NoError(t, Var("TEST_RATIO").Default(1).BindFloat64(&ratio))
+	assert.NoError(t, Var("TEST_TIMEOUT").Default(5*time.Second).BindDuration(&timeout))
+	assert.NoError(t, Var("TEST_HOSTS").Default([]string{"a"}).BindStrings(&hosts))
+	assert.NoError(t, Var("TEST_LEVEL").Parse(IntParseFunc(16)).Bind(&level))
+	assert.Equal(t, 1.0, ratio)
+	assert.Equal(t, 5*time.Second, timeout)
+	assert.Equal(t, []string{"a"}, hosts)
+
+	// test that returns error if the default value is not assignable
+	var name string
+	err = Var("TEST_NAME").Default(1).BindString(&name)
+	assert.True(t, errors.Is(err, ErrValue))
+	assert.Empty(t, name)
+
+	// test that the value is not changed if failed to register
+	port = 1
+	err = Var("TEST_PORT").Default(2).BindInt(&port)
+	assert.True(t, errors.Is(err, ErrNameAlready))
+	assert.Equal(t, 1, port)
+}
@@ -0,0 +1,91 @@
+package getenv
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// SchemeCheckFunc returns the CheckFunc that allows only the URL values with
+// the scheme contained in schemes. The value must be a url.URL or a slice of
+// *url.URL. The schemes are compared case-insensitively.
+func SchemeCheckFunc(schemes ...string) CheckFunc {
+	checkScheme := func(u *url.URL) error {
+		for _, scheme := range schemes {
+			if strings.EqualFold(u.Scheme, scheme) {
+				return nil
+			}
+		}
+		return fmt.Errorf("scheme %q is not allowed, must be one of %s", u.Scheme, strings.Join(schemes, ", "))
+	}
+
+	return func(iv interface{}, envName string) error {
+		switch v := iv.(type) {
+		case *url.URL:
+			return checkScheme(v)
+
+		case *[]*url.URL:
+			for i, u := range *v {
+				if err := checkScheme(u); err != nil {
+					return fmt.Errorf("element %d of %s: %w", i, envName, err)
+				}
+			}
+			return nil
+
+		default:
+			return fmt.Errorf("%w: %T is not *url.URL or *[]*url.URL", ErrValue, iv)
+		}
+	}
+}
+
+// LenCheckFunc returns the CheckFunc that checks the length of the string,
+// slice, array or map value is between min and max. If max is less than 0,
+// the maximum length is not checked.
+func LenCheckFunc(min, max int) CheckFunc {
+	return func(iv interface{}, envName string) error {
+		ref := reflect.Indirect(reflect.ValueOf(iv))
+		switch ref.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		default:
+			return fmt.Errorf("%w: the length of %T cannot be checked", ErrValue, iv)
+		}
+
+		n := ref.Len()
+		if n < min || (max >= 0 && n > max) {
+			if min == max {
+				return fmt.Errorf("length must be %d, got %d", min, n)
+			} else if max < 0 {
+				return fmt.Errorf("length must be at least %d, got %d", min, n)
+			}
+			return fmt.Errorf("length must be between %d and %d, got %d", min, max, n)
+		}
+		return nil
+	}
+}
+
+// RangeCheckFunc returns the CheckFunc that checks the integer or float value
+// is between min and max inclusive.
+func RangeCheckFunc(min, max float64) CheckFunc {
+	return func(iv interface{}, envName string) error {
+		var v float64
+		ref := reflect.Indirect(reflect.ValueOf(iv))
+		switch ref.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v = float64(ref.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+			reflect.Uint64, reflect.Uintptr:
+			v = float64(ref.Uint())
+		case reflect.Float32, reflect.Float64:
+			v = ref.Float()
+		default:
+			return fmt.Errorf("%w: the range of %T cannot be checked", ErrValue, iv)
+		}
+
+		if v < min || v > max || math.IsNaN(v) {
+			return fmt.Errorf("value must be between %v and %v, got %v", min, max, ref.Interface())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+package getenv
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemeCheckFunc(t *testing.T) {
+	checkfn := SchemeCheckFunc("http", "https")
+
+	// test that allow the specified schemes
+	for _, s := range []string{"http://example.com", "HTTPS://example.com/path"} {
+		u, err := url.Parse(s)
+		assert.NoError(t, err)
+		assert.NoError(t, checkfn(u, "URL"))
+	}
+
+	// test that returns error if the scheme is not allowed
+	for _, s := range []string{"ftp://example.com", "example.com"} {
+		u, err := url.Parse(s)
+		assert.NoError(t, err)
+		err = checkfn(u, "URL")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is not allowed")
+	}
+
+	// test that returns ErrValue if the value is not *url.URL
+	s := "http://example.com"
+	assert.True(t, errors.Is(checkfn(&s, "URL"), ErrValue))
+}
+
+func TestLenCheckFunc(t *testing.T) {
+	// test that check the length of the value
+	key := []byte("0123456789abcdef0123456789abcdef")
+	assert.NoError(t, LenCheckFunc(32, 32)(&key, "KEY"))
+	assert.NoError(t, LenCheckFunc(16, -1)(&key, "KEY"))
+	assert.NoError(t, LenCheckFunc(0, 32)(&key, "KEY"))
+	str := "foo"
+	assert.NoError(t, LenCheckFunc(1, 3)(&str, "STR"))
+	m := map[string]string{"a": "1"}
+	assert.NoError(t, LenCheckFunc(1, 1)(&m, "MAP"))
+
+	// test that returns error if the length is out of range
+	err := LenCheckFunc(16, 16)(&key, "KEY")
+	assert.Equal(t, "length must be 16, got 32", err.Error())
+	err = LenCheckFunc(64, -1)(&key, "KEY")
+	assert.Equal(t, "length must be at least 64, got 32", err.Error())
+	err = LenCheckFunc(4, 8)(&str, "STR")
+	assert.Equal(t, "length must be between 4 and 8, got 3", err.Error())
+
+	// test that returns ErrValue if the value has no length
+	n := 1
+	assert.True(t, errors.Is(LenCheckFunc(0, 1)(&n, "INT"), ErrValue))
+}
+
+func TestRangeCheckFunc(t *testing.T) {
+	checkfn := RangeCheckFunc(0, 1)
+
+	// test that check the value is in range
+	for _, v := range []interface{}{
+		float64(0), float32(0.5), float64(1), int(1), uint8(0),
+	} {
+		ref := reflect.New(reflect.TypeOf(v))
+		ref.Elem().Set(reflect.ValueOf(v))
+		assert.NoError(t, checkfn(ref.Interface(), "RATE"), v)
+	}
+
+	// test that returns error if the value is out of range
+	f := 1.5
+	err := checkfn(&f, "RATE")
+	assert.Equal(t, "value must be between 0 and 1, got 1.5", err.Error())
+	i := -1
+	assert.Error(t, checkfn(&i, "RATE"))
+	nan := math.NaN()
+	assert.Error(t, checkfn(&nan, "RATE"))
+
+	// test that returns ErrValue if the value is not a number
+	s := "0.5"
+	assert.True(t, errors.Is(checkfn(&s, "RATE"), ErrValue))
+}
@@ -0,0 +1,147 @@
+package getenv
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// usageEntry is the entry of the usage of the variables that are collected
+// by the prefix.
+type usageEntry struct {
+	name     string
+	desc     string
+	defval   interface{}
+	required bool
+}
+
+// collector populates the value from the environment variables that have the
+// prefix.
+type collector interface {
+	// parse returns the function that stores the value parsed from l
+	parse(ctx context.Context, l Lookuper) (func(), error)
+	usage() []usageEntry
+	// render stores the formatted values of the variables into m
+	render(m map[string]string) error
+	// reset restores the value to the default value
+	reset()
+	// env returns the Env that describes the collector
+	env() *Env
+}
+
+// envPrefix collects the environment variables that have the prefix into a
+// map value.
+type envPrefix struct {
+	prefix   string
+	desc     string
+	defval   interface{}
+	required bool
+	value    reflect.Value
+}
+
+func (e *envPrefix) usage() []usageEntry {
+	return []usageEntry{{
+		name:     e.prefix + "*",
+		desc:     e.desc,
+		defval:   e.defval,
+		required: e.required,
+	}}
+}
+
+func (e *envPrefix) env() *Env {
+	return &Env{
+		Name:         e.prefix,
+		Description:  e.desc,
+		DefaultValue: e.defval,
+		Value:        e.value.Addr().Interface(),
+		Required:     e.required,
+	}
+}
+
+func (e *envPrefix) reset() {
+	e.value.Set(reflect.ValueOf(e.defval))
+}
+
+func (e *envPrefix) render(m map[string]string) error {
+	iter := e.value.MapRange()
+	for iter.Next() {
+		name := e.prefix + iter.Key().String()
+		s, ok, err := formatValue(iter.Value(), defaultSeparator)
+		if err != nil {
+			return fmt.Errorf("%q: %w", name, err)
+		} else if ok {
+			m[name] = s
+		}
+	}
+	return nil
+}
+
+func (e *envPrefix) parse(ctx context.Context, l Lookuper) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	found := lookupPrefix(l, e.prefix)
+	if len(found) == 0 {
+		if e.required {
+			return nil, fmt.Errorf("%w: %q", ErrNotDefined, e.prefix+"*")
+		}
+		return func() {}, nil
+	}
+
+	t := e.value.Type()
+	m := reflect.MakeMapWithSize(t, len(found))
+	for key, v := range found {
+		name := e.prefix + key
+		elem := reflect.New(t.Elem()).Elem()
+		if err := defaultParser.setValue(elem, name, v); err != nil {
+			return nil, fmt.Errorf("%w: %q %w", ErrEnvVar, name, err)
+		}
+		m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+	}
+	return func() {
+		e.value.Set(m)
+	}, nil
+}
+
+// CollectPrefix registers the map value that collects all the environment
+// variables that have the prefix, e.g. HEADER_ACCEPT and HEADER_USER_AGENT
+// are collected as {"ACCEPT": ..., "USER_AGENT": ...} by the prefix
+// "HEADER_". The value must be a pointer to a map that has the string keys,
+// and the map is replaced by Parse if any variable is found. The values of the
+// map are parsed in the same way as the values of the map variable. The opts
+// configure the collector like Set, of which only WithRequired takes effect.
+func CollectPrefix(prefix, desc string, value interface{}, opts ...Option) error {
+	return defaultSet.CollectPrefix(prefix, desc, value, opts...)
+}
+
+// CollectPrefix registers the map value to the set like the CollectPrefix
+// function.
+func (s *EnvSet) CollectPrefix(prefix, desc string, value interface{}, opts ...Option) error {
+	env := &Env{}
+	for _, opt := range opts {
+		opt(env)
+	}
+
+	prefix = s.prefix + prefix
+	var defval interface{}
+	if err := checkName(prefix); err != nil {
+		return err
+	} else if s.prefix2collectors[prefix] != nil {
+		return fmt.Errorf("%w: %q already registered", ErrNameAlready, prefix+"*")
+	} else if other := s.collectorPrefix(prefix); other != prefix {
+		return fmt.Errorf("%w: %q already registered as %q", ErrNameAlready, prefix+"*", other+"*")
+	} else if defval, err = checkValue(value, false); err != nil {
+		return err
+	} else if t := reflect.TypeOf(defval); t.Kind() != reflect.Map {
+		return fmt.Errorf("%w: %T is not a pointer to map", ErrValue, value)
+	}
+
+	s.prefix2collectors[prefix] = &envPrefix{
+		prefix:   prefix,
+		desc:     desc,
+		defval:   defval,
+		required: env.Required,
+		value:    reflect.ValueOf(value).Elem(),
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package getenv
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectPrefix(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		for _, name := range []string{
+			"TEST_HEADER_ACCEPT", "TEST_HEADER_USER_AGENT", "TEST_HEADER_EMPTY",
+			"TEST_HEADER_", "TEST_LIMIT_A", "TEST_LIMIT_B",
+		} {
+			os.Unsetenv(name)
+		}
+	}()
+
+	// test that the prefix is shown in the usage
+	headers := map[string]string{"X_DEFAULT": "1"}
+	assert.NoError(t, CollectPrefix("TEST_HEADER_", "extra headers", &headers))
+	Usage(func(name, desc string, defval interface{}, required bool) {
+		assert.Equal(t, "TEST_HEADER_*", name)
+		assert.Equal(t, "extra headers", desc)
+		assert.Equal(t, map[string]string{"X_DEFAULT": "1"}, defval)
+		assert.False(t, required)
+	})
+
+	// test that the map is not changed if no variables are found
+	assert.NoError(t, Parse())
+	assert.Equal(t, map[string]string{"X_DEFAULT": "1"}, headers)
+
+	// test that collect the variables by stripping the prefix
+	os.Setenv("TEST_HEADER_ACCEPT", "text/plain")
+	os.Setenv("TEST_HEADER_USER_AGENT", " getenv ")
+	os.Setenv("TEST_HEADER_EMPTY", "")
+	os.Setenv("TEST_HEADER_", "ignored")
+	assert.NoError(t, Parse())
+	assert.Equal(t, map[string]string{
+		"ACCEPT":     "text/plain",
+		"USER_AGENT": "getenv",
+	}, headers)
+
+	// test that parse the values of the map
+	limits := map[string]int{}
+	assert.NoError(t, CollectPrefix("TEST_LIMIT_", "", &limits, WithRequired()))
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrNotDefined))
+	assert.Contains(t, err.Error(), `"TEST_LIMIT_*"`)
+	os.Setenv("TEST_LIMIT_A", "10")
+	os.Setenv("TEST_LIMIT_B", "foo")
+	err = Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), `"TEST_LIMIT_B"`)
+	os.Setenv("TEST_LIMIT_B", "20")
+	assert.NoError(t, Parse())
+	assert.Equal(t, map[string]int{"A": 10, "B": 20}, limits)
+
+	// test that returns error if the arguments are invalid
+	assert.True(t, errors.Is(CollectPrefix("TEST_HEADER_", "", &headers), ErrNameAlready))
+	assert.True(t, errors.Is(CollectPrefix("", "", &headers), ErrName))
+	assert.True(t, errors.Is(CollectPrefix("TEST_P_", "", headers), ErrValue))
+	assert.True(t, errors.Is(CollectPrefix("TEST_P_", "", new([]string)), ErrValue))
+	assert.True(t, errors.Is(CollectPrefix("TEST_P_", "", new(map[string][]string)), ErrValue))
+}
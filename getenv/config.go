@@ -0,0 +1,219 @@
+package getenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ErrConfigFile is returned if the config file has the invalid content.
+var ErrConfigFile = fmt.Errorf("invalid config file")
+
+// configValue formats the value of the config file into the string that can
+// be parsed by the default parser. The arrays are joined by the separator,
+// and the objects are formatted as the "key=value" pairs joined by the
+// separator, with the separators and the backslashes in the elements escaped
+// by backslash. The ok is false if v is null.
+func configValue(v interface{}) (s string, ok bool, err error) {
+	switch v := v.(type) {
+	case nil:
+		return "", false, nil
+
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for i, elem := range v {
+			s, ok, err := configScalar(elem)
+			if err != nil {
+				return "", false, fmt.Errorf("element %d: %w", i, err)
+			} else if ok {
+				list = append(list, escapeValue(s, defaultSeparator))
+			}
+		}
+		return strings.Join(list, defaultSeparator), true, nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		list := make([]string, 0, len(keys))
+		for _, k := range keys {
+			s, ok, err := configScalar(v[k])
+			if err != nil {
+				return "", false, fmt.Errorf("value of key %q: %w", k, err)
+			} else if ok {
+				list = append(list, escapeValue(k, defaultSeparator, "=")+"="+escapeValue(s, defaultSeparator))
+			}
+		}
+		return strings.Join(list, defaultSeparator), true, nil
+	}
+	return configScalar(v)
+}
+
+// configScalar formats the scalar value of the config file.
+func configScalar(v interface{}) (s string, ok bool, err error) {
+	switch v := v.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return v, true, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true, nil
+	case []interface{}, map[string]interface{}:
+		return "", false, fmt.Errorf("nested %T is not supported", v)
+	}
+	return fmt.Sprint(v), true, nil
+}
+
+// configMap converts the values of m into the map of the names and the
+// values. The null values are omitted.
+func configMap(m map[string]interface{}) (map[string]string, error) {
+	vars := map[string]string{}
+	for name, v := range m {
+		s, ok, err := configValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q %w", ErrConfigFile, name, err)
+		} else if ok {
+			vars[name] = s
+		}
+	}
+	return vars, nil
+}
+
+// ReadJSON reads the JSON object from r, and returns the map of the names and
+// the values of the top-level keys. The numbers and the booleans are
+// formatted as is, the arrays of the scalar values are joined by ",", and the
+// objects of the scalar values are formatted as "key=value" pairs joined by
+// ",", so that they can be parsed into the slice and the map values. The keys
+// of the null values are omitted.
+func ReadJSON(r io.Reader) (map[string]string, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigFile, err)
+	} else if m == nil {
+		return nil, fmt.Errorf("%w: not a JSON object", ErrConfigFile)
+	}
+	return configMap(m)
+}
+
+// LoadJSON reads the variables from the JSON file of path by ReadJSON, and
+// parses the registered variables from the process environment and the file
+// like Parse. The file is consulted only if the variable is not defined in
+// the process environment, or supplied by the Sources set by SetSources.
+func LoadJSON(path string) error {
+	return defaultSet.LoadJSON(path)
+}
+
+// LoadJSON parses the variables of the set from the process environment and
+// the JSON file like the LoadJSON function.
+func (s *EnvSet) LoadJSON(path string) error {
+	src, err := JSONSource(path)
+	if err != nil {
+		return err
+	}
+	return s.ParseFrom(s.withFallback(src))
+}
+
+// configName converts the key of the config file to the variable name, e.g.
+// "db" and "maxConns" are converted to "DB" and "MAX_CONNS".
+func configName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, ScreamingSnakeCase(key))
+}
+
+// flattenConfig stores the values of m into vars with the names converted by
+// configName and prefixed by prefix. The tables are flattened with the name
+// of the table as the prefix, and the arrays of tables are flattened into the
+// indexed variables, e.g. [db] host and [[servers]] host are stored as
+// DB_HOST and SERVERS_0_HOST.
+func flattenConfig(prefix string, m map[string]interface{}, vars map[string]string) error {
+	for key, v := range m {
+		name := prefix + configName(key)
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if err := flattenConfig(name+"_", v, vars); err != nil {
+				return err
+			}
+		case []map[string]interface{}:
+			for i, t := range v {
+				if err := flattenConfig(name+"_"+strconv.Itoa(i)+"_", t, vars); err != nil {
+					return err
+				}
+			}
+		default:
+			s, ok, err := configValue(v)
+			if err != nil {
+				return fmt.Errorf("%w: %q %w", ErrConfigFile, name, err)
+			} else if ok {
+				vars[name] = s
+			}
+		}
+	}
+	return nil
+}
+
+// ReadTOML reads the TOML document from r, and returns the map of the names
+// and the values. The keys are converted to SCREAMING_SNAKE_CASE, and the
+// tables are flattened with the name of the table as the prefix, e.g. host
+// of [db] is stored as DB_HOST. The arrays of tables are flattened into the
+// indexed variables such as SERVERS_0_HOST, which are parsed into the slice
+// of struct by Bind. The other values are formatted in the same way as
+// ReadJSON.
+func ReadTOML(r io.Reader) (map[string]string, error) {
+	var m map[string]interface{}
+	if _, err := toml.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigFile, err)
+	}
+	vars := map[string]string{}
+	if err := flattenConfig("", m, vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// LoadTOML reads the variables from the TOML file of path by ReadTOML, and
+// parses the registered variables from the process environment and the file
+// like Parse. The file is consulted only if the variable is not defined in
+// the process environment, or supplied by the Sources set by SetSources.
+func LoadTOML(path string) error {
+	return defaultSet.LoadTOML(path)
+}
+
+// LoadTOML parses the variables of the set from the process environment and
+// the TOML file like the LoadTOML function.
+func (s *EnvSet) LoadTOML(path string) error {
+	src, err := TOMLSource(path)
+	if err != nil {
+		return err
+	}
+	return s.ParseFrom(s.withFallback(src))
+}
+
+// readConfigFile reads the config file of path by read.
+func readConfigFile(path string, read func(io.Reader) (map[string]string, error)) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", path, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,184 @@
+package getenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadJSON(t *testing.T) {
+	// test that formats the values to be parsed by the default parser
+	m, err := ReadJSON(strings.NewReader(`{
+		"HOST": "example.com",
+		"PORT": 8080,
+		"RATE": 0.5,
+		"DEBUG": true,
+		"NONE": null,
+		"HOSTS": ["a", "b,c", null],
+		"LIMITS": {"b": 2, "a": "x=y"}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"HOST":   "example.com",
+		"PORT":   "8080",
+		"RATE":   "0.5",
+		"DEBUG":  "true",
+		"HOSTS":  `a,b\,c`,
+		"LIMITS": "a=x=y,b=2",
+	}, m)
+
+	// test that returns ErrConfigFile
+	for _, src := range []string{`[1]`, `null`, `{`, `{"A": [[1]]}`, `{"A": {"b": {}}}`} {
+		_, err := ReadJSON(strings.NewReader(src))
+		assert.True(t, errors.Is(err, ErrConfigFile), src)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_HOST")
+	}()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{
+		"TEST_HOST": "example.com",
+		"TEST_HOSTS": ["a", "b", "C:\\dir", "c,d"],
+		"TEST_LIMITS": {"a": 1}
+	}`), 0o600))
+
+	host := "localhost"
+	var hosts []string
+	var limits map[string]int
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+	assert.NoError(t, Set("TEST_HOSTS", "", &hosts))
+	assert.NoError(t, Set("TEST_LIMITS", "", &limits))
+
+	// test that the file is consulted if the variable is not defined
+	os.Setenv("TEST_HOST", "example.org")
+	assert.NoError(t, LoadJSON(path))
+	assert.Equal(t, "example.org", host)
+	assert.Equal(t, []string{"a", "b", `C:\dir`, "c,d"}, hosts)
+	assert.Equal(t, map[string]int{"a": 1}, limits)
+
+	// test that the Sources of the set take precedence over the file
+	SetSources(NewSource("remote", MapLookuper{"TEST_LIMITS": "a=2"}))
+	assert.NoError(t, LoadJSON(path))
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, map[string]int{"a": 2}, limits)
+	SetSources()
+
+	// test that returns the error of the file
+	assert.True(t, errors.Is(LoadJSON(path+".none"), os.ErrNotExist))
+	assert.NoError(t, os.WriteFile(path, []byte(`[]`), 0o600))
+	err := LoadJSON(path)
+	assert.True(t, errors.Is(err, ErrConfigFile))
+	assert.Contains(t, err.Error(), path)
+}
+
+func TestReadTOML(t *testing.T) {
+	// test that flattens the tables into the prefixed names
+	m, err := ReadTOML(strings.NewReader(`
+debug = true
+log-level = "info"
+hosts = ["a", "b"]
+paths = ['C:\dir', "c,d"]
+started = 2024-01-02T03:04:05Z
+
+[db]
+host = "localhost"
+maxConns = 10
+timeout = 1.5
+
+[db.replica]
+host = "replica"
+
+[labels]
+env = "prod"
+
+[[servers]]
+host = "s0"
+
+[[servers]]
+host = "s1"
+port = 8080
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"DEBUG":           "true",
+		"LOG_LEVEL":       "info",
+		"HOSTS":           "a,b",
+		"PATHS":           `C:\\dir,c\,d`,
+		"STARTED":         "2024-01-02T03:04:05Z",
+		"DB_HOST":         "localhost",
+		"DB_MAX_CONNS":    "10",
+		"DB_TIMEOUT":      "1.5",
+		"DB_REPLICA_HOST": "replica",
+		"LABELS_ENV":      "prod",
+		"SERVERS_0_HOST":  "s0",
+		"SERVERS_1_HOST":  "s1",
+		"SERVERS_1_PORT":  "8080",
+	}, m)
+
+	// test that returns ErrConfigFile
+	for _, src := range []string{`a = `, `a = [[1], [2]]`} {
+		_, err := ReadTOML(strings.NewReader(src))
+		assert.True(t, errors.Is(err, ErrConfigFile), src)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("DB_HOST")
+	}()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+[db]
+host = "localhost"
+port = 5432
+
+[[servers]]
+host = "s0"
+paths = ['C:\dir', "c,d"]
+`), 0o600))
+
+	var cfg struct {
+		DB struct {
+			Host string
+			Port int
+		}
+		Servers []struct {
+			Host  string
+			Paths []string
+		}
+	}
+	assert.NoError(t, Bind(&cfg))
+
+	// test that the file is consulted if the variable is not defined
+	os.Setenv("DB_HOST", "db.example.com")
+	assert.NoError(t, LoadTOML(path))
+	assert.Equal(t, "db.example.com", cfg.DB.Host)
+	assert.Equal(t, 5432, cfg.DB.Port)
+	assert.Len(t, cfg.Servers, 1)
+	assert.Equal(t, "s0", cfg.Servers[0].Host)
+	assert.Equal(t, []string{`C:\dir`, "c,d"}, cfg.Servers[0].Paths)
+
+	// test that the Sources of the set take precedence over the file
+	SetSources(NewSource("remote", MapLookuper{"DB_PORT": "6543"}))
+	assert.NoError(t, LoadTOML(path))
+	assert.Equal(t, "localhost", cfg.DB.Host)
+	assert.Equal(t, 6543, cfg.DB.Port)
+	SetSources()
+
+	// test that returns the error of the file
+	assert.NoError(t, os.WriteFile(path, []byte(`[db`), 0o600))
+	err := LoadTOML(path)
+	assert.True(t, errors.Is(err, ErrConfigFile))
+	assert.Contains(t, err.Error(), path)
+}
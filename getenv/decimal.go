@@ -0,0 +1,124 @@
+package getenv
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Decimal represents a fixed-point decimal number such as "19.99". Unlike
+// float64, it holds the value exactly as the unscaled integer and the number
+// of digits after the decimal point. The default value of the registered Decimal
+// limits the number of digits after the decimal point, e.g. the values such as
+// "19.999" are rejected by default if the default value is "0.00". The zero
+// value does not limit it.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// ParseDecimal parses the decimal number such as "100", "-0.25" and "19.990".
+// The exponent notation is not allowed. The scale of the returned value is the
+// number of digits after the decimal point, including trailing zeros.
+func ParseDecimal(s string) (Decimal, error) {
+	str := s
+	if str != "" && (str[0] == '+' || str[0] == '-') {
+		str = str[1:]
+	}
+	intPart, fracPart := str, ""
+	if i := strings.IndexByte(str, '.'); i != -1 {
+		intPart, fracPart = str[:i], str[i+1:]
+		if fracPart == "" {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+	}
+	if intPart == "" && fracPart == "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for i := 0; i < len(part); i++ {
+			if !isDigit(part[i]) {
+				return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+			}
+		}
+	}
+
+	v, _ := new(big.Int).SetString(intPart+fracPart, 10)
+	if s[0] == '-' {
+		v.Neg(v)
+	}
+	return Decimal{unscaled: v, scale: len(fracPart)}, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (d *Decimal) UnmarshalText(b []byte) error {
+	v, err := ParseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Unscaled returns a copy of the unscaled integer value. For example, the
+// unscaled value of "19.99" is 1999.
+func (d Decimal) Unscaled() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(d.unscaled)
+}
+
+// Rat returns the value as a big.Rat.
+func (d Decimal) Rat() *big.Rat {
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil)
+	return new(big.Rat).SetFrac(d.Unscaled(), denom)
+}
+
+// Float64 returns the nearest float64 value of d.
+func (d Decimal) Float64() float64 {
+	v, _ := d.Rat().Float64()
+	return v
+}
+
+// Cmp compares d and x and returns -1 if d < x, 0 if d == x and +1 if d > x.
+func (d Decimal) Cmp(x Decimal) int {
+	return d.Rat().Cmp(x.Rat())
+}
+
+// String returns the decimal representation of d with its scale.
+func (d Decimal) String() string {
+	v := d.Unscaled()
+	neg := v.Sign() < 0
+	digits := v.Abs(v).String()
+	if d.scale > 0 {
+		if n := d.scale + 1 - len(digits); n > 0 {
+			digits = strings.Repeat("0", n) + digits
+		}
+		i := len(digits) - d.scale
+		digits = digits[:i] + "." + digits[i:]
+	}
+	if neg {
+		return "-" + digits
+	}
+	return digits
+}
+
+// ScaleCheckFunc returns the CheckFunc that checks the number of digits after
+// the decimal point of the Decimal value is at most max.
+func ScaleCheckFunc(max int) CheckFunc {
+	return func(iv interface{}, envName string) error {
+		d, ok := iv.(*Decimal)
+		if !ok {
+			return fmt.Errorf("%w: %T is not *Decimal", ErrValue, iv)
+		} else if d.scale > max {
+			return fmt.Errorf("decimal %s must have at most %d digits after the decimal point", d, max)
+		}
+		return nil
+	}
+}
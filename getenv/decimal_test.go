@@ -0,0 +1,105 @@
+package getenv
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecimal(t *testing.T) {
+	// test that parse decimal numbers exactly
+	for s, exp := range map[string]struct {
+		str      string
+		unscaled int64
+		scale    int
+	}{
+		"100":     {"100", 100, 0},
+		"19.99":   {"19.99", 1999, 2},
+		"-0.25":   {"-0.25", -25, 2},
+		"+1.500":  {"1.500", 1500, 3},
+		".5":      {"0.5", 5, 1},
+		"0.001":   {"0.001", 1, 3},
+		"-0.0001": {"-0.0001", -1, 4},
+	} {
+		v, err := ParseDecimal(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, exp.str, v.String(), s)
+		assert.Equal(t, big.NewInt(exp.unscaled), v.Unscaled(), s)
+		assert.Equal(t, exp.scale, v.Scale(), s)
+	}
+
+	// test that keep the precision that float64 cannot represent
+	v, err := ParseDecimal("12345678901234567890.123456789")
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678901234567890.123456789", v.String())
+
+	// test that returns error if the value is invalid
+	for _, s := range []string{"", "-", ".", "1.", "1e3", "1.2.3", "0x10", "1,000", " 1"} {
+		_, err := ParseDecimal(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	// test that parse decimal as encoding.TextUnmarshaler
+	var v Decimal
+	assert.NoError(t, defaultParseFunc(&v, "FEE", "0.30"))
+	assert.Equal(t, "0.30", v.String())
+	assert.Error(t, defaultParseFunc(&v, "FEE", "0.3.0"))
+	assert.Equal(t, "0.30", v.String())
+
+	// test that convert to other representations
+	assert.Equal(t, big.NewRat(3, 10), v.Rat())
+	assert.Equal(t, 0.3, v.Float64())
+	x, _ := ParseDecimal("0.3")
+	assert.Equal(t, 0, v.Cmp(x))
+	x, _ = ParseDecimal("0.31")
+	assert.Equal(t, -1, v.Cmp(x))
+
+	// test that zero value is zero
+	assert.Equal(t, "0", Decimal{}.String())
+	assert.Equal(t, 0, Decimal{}.Cmp(Decimal{}))
+}
+
+func TestScaleCheckFunc(t *testing.T) {
+	checkfn := ScaleCheckFunc(2)
+
+	// test that check the scale of the value
+	v, _ := ParseDecimal("19.99")
+	assert.NoError(t, checkfn(&v, "PRICE"))
+	v, _ = ParseDecimal("19.999")
+	err := checkfn(&v, "PRICE")
+	assert.Equal(t, "decimal 19.999 must have at most 2 digits after the decimal point", err.Error())
+
+	// test that returns ErrValue if the value is not a Decimal
+	f := 19.99
+	assert.True(t, errors.Is(checkfn(&f, "PRICE"), ErrValue))
+
+	// test that checked by Parse
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PRICE")
+	}()
+	assert.NoError(t, Set("TEST_PRICE", "", &v, WithCheck(checkfn)))
+	os.Setenv("TEST_PRICE", "0.125")
+	assert.True(t, errors.Is(Parse(), ErrEnvVar))
+	os.Setenv("TEST_PRICE", "0.12")
+	assert.NoError(t, Parse())
+	assert.Equal(t, "0.12", v.String())
+
+	// test that the scale is limited to the scale of the default value by
+	// default
+	fee, _ := ParseDecimal("0.00")
+	var amount Decimal
+	assert.NoError(t, Set("TEST_FEE", "", &fee))
+	assert.NoError(t, Set("TEST_AMOUNT", "", &amount))
+	err = ParseMap(map[string]string{"TEST_FEE": "0.125"})
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), "at most 2 digits")
+	assert.NoError(t, ParseMap(map[string]string{"TEST_FEE": "0.1", "TEST_AMOUNT": "0.125"}))
+	assert.Equal(t, "0.1", fee.String())
+	assert.Equal(t, "0.125", amount.String())
+}
@@ -0,0 +1,63 @@
+package getenv
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSONParseFunc returns the ParseFunc that decodes the environment variable
+// value as JSON into the value by json.Unmarshal.
+func JSONParseFunc() ParseFunc {
+	return func(iv interface{}, envName, envValue string) error {
+		return json.Unmarshal([]byte(envValue), iv)
+	}
+}
+
+// YAMLParseFunc returns the ParseFunc that decodes the environment variable
+// value as YAML into the value by yaml.Unmarshal.
+func YAMLParseFunc() ParseFunc {
+	return func(iv interface{}, envName, envValue string) error {
+		return yaml.Unmarshal([]byte(envValue), iv)
+	}
+}
+
+// HexParseFunc returns the ParseFunc that decodes the hex encoded environment
+// variable value into a []byte or a byte array value. The value can be
+// prefixed with "0x". If the value is a byte array, the length of the decoded
+// bytes must be equal to the length of the array.
+func HexParseFunc() ParseFunc {
+	return func(iv interface{}, envName, envValue string) error {
+		ref := reflect.ValueOf(iv)
+		if ref.Kind() != reflect.Ptr || ref.IsNil() {
+			return fmt.Errorf("%w: %T is not a non-nil pointer", ErrValue, iv)
+		}
+		ref = ref.Elem()
+		if (ref.Kind() != reflect.Slice && ref.Kind() != reflect.Array) ||
+			ref.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("%w: %T is not a pointer to []byte or byte array", ErrValue, iv)
+		}
+
+		s := envValue
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+			s = s[2:]
+		}
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return err
+		}
+
+		if ref.Kind() == reflect.Slice {
+			ref.SetBytes(b)
+		} else if len(b) != ref.Len() {
+			return fmt.Errorf("decoded length must be %d bytes, got %d", ref.Len(), len(b))
+		} else {
+			reflect.Copy(ref, reflect.ValueOf(b))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package getenv
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testService struct {
+	Name string   `json:"name" yaml:"name"`
+	Tags []string `json:"tags" yaml:"tags"`
+	Port int      `json:"port" yaml:"port"`
+}
+
+func TestJSONParseFunc(t *testing.T) {
+	parsefn := JSONParseFunc()
+
+	// test that decode JSON into struct
+	var v testService
+	assert.NoError(t, parsefn(&v, "SERVICE", `{"name":"db","tags":["a","b"],"port":5432}`))
+	assert.Equal(t, testService{Name: "db", Tags: []string{"a", "b"}, Port: 5432}, v)
+
+	// test that decode JSON into json.RawMessage
+	var raw json.RawMessage
+	assert.NoError(t, parsefn(&raw, "RAW", `{"foo": [1, 2]}`))
+	assert.Equal(t, `{"foo": [1, 2]}`, string(raw))
+
+	// test that returns error if the value is not a valid JSON
+	assert.Error(t, parsefn(&v, "SERVICE", `{"name":`))
+	assert.Error(t, parsefn(&v, "SERVICE", `{"port":"5432"}`))
+
+	// test that any pointer can be registered with JSONParseFunc
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_SERVICE")
+	}()
+	v = testService{Name: "default"}
+	assert.True(t, errors.Is(Set("TEST_SERVICE", "", &v), ErrValue))
+	assert.NoError(t, Set("TEST_SERVICE", "", &v, WithParse(parsefn)))
+	assert.Equal(t, testService{Name: "default"}, defaultSet.name2envs["TEST_SERVICE"].DefaultValue)
+	os.Setenv("TEST_SERVICE", `{"name":"cache","port":6379}`)
+	assert.NoError(t, Parse())
+	assert.Equal(t, testService{Name: "cache", Port: 6379}, v)
+}
+
+func TestYAMLParseFunc(t *testing.T) {
+	parsefn := YAMLParseFunc()
+
+	// test that decode YAML into struct
+	var v testService
+	assert.NoError(t, parsefn(&v, "SERVICE", "name: db\ntags: [a, b]\nport: 5432\n"))
+	assert.Equal(t, testService{Name: "db", Tags: []string{"a", "b"}, Port: 5432}, v)
+
+	// test that decode YAML flow style into map
+	var m map[string]int
+	assert.NoError(t, parsefn(&m, "MAP", "{a: 1, b: 2}"))
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, m)
+
+	// test that returns error if the value is not a valid YAML
+	assert.Error(t, parsefn(&v, "SERVICE", "name: [db"))
+	assert.Error(t, parsefn(&v, "SERVICE", "port: foo"))
+}
+
+func TestHexParseFunc(t *testing.T) {
+	parsefn := HexParseFunc()
+
+	// test that decode hex string into []byte
+	var b []byte
+	assert.NoError(t, parsefn(&b, "KEY", "deadbeef"))
+	assert.Equal(t, []byte{0xde, 0xad, 0xbe, 0xef}, b)
+	assert.NoError(t, parsefn(&b, "KEY", "0xCAFE"))
+	assert.Equal(t, []byte{0xca, 0xfe}, b)
+
+	// test that decode hex string into byte array of the same length
+	var a [4]byte
+	assert.NoError(t, parsefn(&a, "KEY", "deadbeef"))
+	assert.Equal(t, [4]byte{0xde, 0xad, 0xbe, 0xef}, a)
+	err := parsefn(&a, "KEY", "cafe")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "decoded length must be 4 bytes, got 2")
+	assert.Equal(t, [4]byte{0xde, 0xad, 0xbe, 0xef}, a)
+
+	// test that returns error if the value is not hex string
+	assert.Error(t, parsefn(&b, "KEY", "xyz"))
+	assert.Error(t, parsefn(&b, "KEY", "abc"))
+	assert.Equal(t, []byte{0xca, 0xfe}, b)
+
+	// test that returns ErrValue if the value is not []byte or byte array
+	var s string
+	assert.True(t, errors.Is(parsefn(&s, "KEY", "cafe"), ErrValue))
+	var u16s []uint16
+	assert.True(t, errors.Is(parsefn(&u16s, "KEY", "cafe"), ErrValue))
+	assert.True(t, errors.Is(parsefn(b, "KEY", "cafe"), ErrValue))
+}
@@ -0,0 +1,53 @@
+package getenv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// EncryptedPrefix is the marker of the encrypted value. The rest of the value
+// is the base64 encoded ciphertext.
+const EncryptedPrefix = "enc:"
+
+// Decrypter decrypts the ciphertext of the encrypted value.
+type Decrypter interface {
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// DecryptFunc is the adapter to use the function as the Decrypter.
+type DecryptFunc func(ciphertext []byte) ([]byte, error)
+
+// Decrypt calls fn(ciphertext).
+func (fn DecryptFunc) Decrypt(ciphertext []byte) ([]byte, error) {
+	return fn(ciphertext)
+}
+
+// SetDecrypter sets the Decrypter that decrypts the values that have
+// EncryptedPrefix before parsing, such as "enc:YWdlLWVuY3J5cHRpb24...". The
+// values are used as is if no Decrypter is set.
+func SetDecrypter(d Decrypter) {
+	defaultSet.SetDecrypter(d)
+}
+
+// SetDecrypter sets the Decrypter of the set like the SetDecrypter function.
+// The sets returned by Sub after the call inherit the Decrypter.
+func (s *EnvSet) SetDecrypter(d Decrypter) {
+	s.decrypter = d
+}
+
+// decrypt returns the plaintext of v if v has EncryptedPrefix and the
+// Decrypter is set, otherwise returns v as is.
+func (s *EnvSet) decrypt(name, v string) (string, error) {
+	if s.decrypter == nil || !strings.HasPrefix(v, EncryptedPrefix) {
+		return v, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v[len(EncryptedPrefix):]))
+	if err != nil {
+		return "", fmt.Errorf("%w: %q cannot decode the encrypted value: %w", ErrEnvVar, name, err)
+	}
+	if b, err = s.decrypter.Decrypt(b); err != nil {
+		return "", fmt.Errorf("%w: %q cannot decrypt the value: %w", ErrEnvVar, name, err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,70 @@
+package getenv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDecrypter(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		for _, name := range []string{"TEST_PASSWORD", "TEST_HOST", "TEST_KEYS_0_SECRET"} {
+			os.Unsetenv(name)
+		}
+	}()
+
+	// reverses the bytes
+	reverse := DecryptFunc(func(b []byte) ([]byte, error) {
+		if bytes.HasPrefix(b, []byte("bad")) {
+			return nil, errors.New("authentication failed")
+		}
+		v := make([]byte, len(b))
+		for i := range b {
+			v[len(b)-1-i] = b[i]
+		}
+		return v, nil
+	})
+	encrypted := EncryptedPrefix + base64.StdEncoding.EncodeToString([]byte("terc3s"))
+
+	password := ""
+	host := ""
+	var cfg struct {
+		Keys []struct {
+			Secret string
+		} `env:"TEST_KEYS"`
+	}
+	assert.NoError(t, Set("TEST_PASSWORD", "", &password))
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+	assert.NoError(t, Bind(&cfg))
+	os.Setenv("TEST_PASSWORD", encrypted)
+	os.Setenv("TEST_HOST", "example.com")
+	os.Setenv("TEST_KEYS_0_SECRET", encrypted)
+
+	// test that the values are used as is without the Decrypter
+	assert.NoError(t, Parse())
+	assert.Equal(t, encrypted, password)
+
+	// test that decrypts the values that have the prefix
+	SetDecrypter(reverse)
+	assert.NoError(t, Parse())
+	assert.Equal(t, "s3cret", password)
+	assert.Equal(t, "example.com", host)
+	assert.Len(t, cfg.Keys, 1)
+	assert.Equal(t, "s3cret", cfg.Keys[0].Secret)
+	assert.NoError(t, ParseValue("TEST_HOST", encrypted))
+	assert.Equal(t, "s3cret", host)
+
+	// test that returns ErrEnvVar if cannot decrypt the value
+	os.Setenv("TEST_PASSWORD", EncryptedPrefix+"!")
+	assert.True(t, errors.Is(Parse(), ErrEnvVar))
+	os.Setenv("TEST_PASSWORD", EncryptedPrefix+base64.StdEncoding.EncodeToString([]byte("bad")))
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), "authentication failed")
+	assert.Equal(t, "s3cret", password)
+}
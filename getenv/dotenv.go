@@ -0,0 +1,255 @@
+package getenv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrDotenv is returned if the dotenv file has the invalid syntax.
+var ErrDotenv = fmt.Errorf("invalid dotenv syntax")
+
+// ReadDotenv reads the variables from r in the dotenv syntax, and returns the
+// map of the names and the values. The syntax is as follows:
+//
+//	# comment line
+//	NAME=value            # unquoted, trailing comment and spaces are removed
+//	export NAME=value     # "export" prefix is ignored
+//	NAME='literal value'  # no escape sequences
+//	NAME="line1\nline2"   # \n, \r, \t, \", \\ and \$ are unescaped
+//
+// The quoted values can span multiple lines, and the later definition wins if
+// the name appears more than once.
+func ReadDotenv(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	src := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	m := map[string]string{}
+	for lineno := 1; src != ""; {
+		var line string
+		line, src, _ = strings.Cut(src, "\n")
+		start := lineno
+		lineno++
+
+		line = strings.TrimLeft(line, " \t")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimLeft(rest, " \t")
+		}
+
+		name, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: missing '='", ErrDotenv, start)
+		}
+		name = strings.TrimRight(name, " \t")
+		if checkName(name) != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid name %q", ErrDotenv, start, name)
+		}
+
+		v = strings.TrimLeft(v, " \t")
+		if v == "" || (v[0] != '\'' && v[0] != '"') {
+			m[name] = unquotedDotenvValue(v)
+			continue
+		}
+
+		// the quoted value may continue to the following lines
+		quote := v[0]
+		v = v[1:]
+		for {
+			if end := closingQuote(v, quote); end >= 0 {
+				rest := strings.TrimLeft(v[end+1:], " \t")
+				if rest != "" && rest[0] != '#' {
+					return nil, fmt.Errorf("%w: line %d: unexpected characters after the quoted value of %q", ErrDotenv, lineno-1, name)
+				}
+				v = v[:end]
+				break
+			} else if src == "" {
+				return nil, fmt.Errorf("%w: line %d: unterminated quoted value of %q", ErrDotenv, start, name)
+			}
+			line, src, _ = strings.Cut(src, "\n")
+			lineno++
+			v += "\n" + line
+		}
+		if quote == '"' {
+			v = unescapeDotenvValue(v)
+		}
+		m[name] = v
+	}
+	return m, nil
+}
+
+// unquotedDotenvValue removes the trailing comment that follows the spaces,
+// and the trailing spaces from v.
+func unquotedDotenvValue(v string) string {
+	for i := 1; i < len(v); i++ {
+		if v[i] == '#' && (v[i-1] == ' ' || v[i-1] == '\t') {
+			v = v[:i]
+			break
+		}
+	}
+	return strings.TrimRight(v, " \t")
+}
+
+// closingQuote returns the index of the quote that closes the quoted value v,
+// or -1 if not found. The double quote escaped by the backslash is skipped.
+func closingQuote(v string, quote byte) int {
+	for i := 0; i < len(v); i++ {
+		if quote == '"' && v[i] == '\\' {
+			i++
+		} else if v[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeDotenvValue unescapes the escape sequences in the double quoted
+// value. The unknown escape sequences are kept as is.
+func unescapeDotenvValue(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i == len(v)-1 {
+			b.WriteByte(v[i])
+			continue
+		}
+		i++
+		switch v[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$':
+			b.WriteByte(v[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+// LoadDotenv reads the variables from the dotenv files of paths, and parses
+// the registered variables from the process environment and the files like
+// Parse. The later file overrides the earlier ones, and the variables defined
+// in the process environment, or supplied by the Sources set by SetSources,
+// take precedence over all the files. The process environment is not
+// modified.
+func LoadDotenv(paths ...string) error {
+	return defaultSet.LoadDotenv(paths...)
+}
+
+// LoadDotenv parses the variables of the set from the process environment
+// and the dotenv files like the LoadDotenv function.
+func (s *EnvSet) LoadDotenv(paths ...string) error {
+	src, err := DotenvSource(paths...)
+	if err != nil {
+		return err
+	}
+	return s.ParseFrom(s.withFallback(src))
+}
+
+// ErrSignature is returned by the Verifier if the signature is invalid.
+var ErrSignature = fmt.Errorf("invalid signature")
+
+// Verifier verifies the contents of the file before the variables are read,
+// e.g. by the signature file next to the file. It should return the error
+// that wraps ErrSignature if the signature is invalid.
+type Verifier interface {
+	Verify(path string, data []byte) error
+}
+
+// VerifyFunc is the adapter to use the function as the Verifier.
+type VerifyFunc func(path string, data []byte) error
+
+// Verify calls fn(path, data).
+func (fn VerifyFunc) Verify(path string, data []byte) error {
+	return fn(path, data)
+}
+
+// LoadVerifiedDotenv is like LoadDotenv but verifies the contents of the
+// files by v before reading them, so that no variable is parsed if any file
+// has been tampered with.
+func LoadVerifiedDotenv(v Verifier, paths ...string) error {
+	return defaultSet.LoadVerifiedDotenv(v, paths...)
+}
+
+// LoadVerifiedDotenv parses the variables of the set from the process
+// environment and the verified dotenv files like the LoadVerifiedDotenv
+// function.
+func (s *EnvSet) LoadVerifiedDotenv(v Verifier, paths ...string) error {
+	src, err := VerifiedDotenvSource(v, paths...)
+	if err != nil {
+		return err
+	}
+	return s.ParseFrom(s.withFallback(src))
+}
+
+// DotenvFiles returns the conventional list of the dotenv files in dir in the
+// order of the precedence from low to high: .env, .env.local, .env.<appEnv>
+// and .env.<appEnv>.local. The files of appEnv are omitted if appEnv is empty.
+func DotenvFiles(dir, appEnv string) []string {
+	names := []string{".env", ".env.local"}
+	if appEnv != "" {
+		names = append(names, ".env."+appEnv, ".env."+appEnv+".local")
+	}
+	for i, name := range names {
+		names[i] = filepath.Join(dir, name)
+	}
+	return names
+}
+
+// LoadDotenvCascade is like LoadDotenv but loads the files of DotenvFiles(dir,
+// appEnv) that exist. The missing files are skipped.
+func LoadDotenvCascade(dir, appEnv string) error {
+	return defaultSet.LoadDotenvCascade(dir, appEnv)
+}
+
+// LoadDotenvCascade parses the variables of the set from the process
+// environment and the existing dotenv files like the LoadDotenvCascade
+// function.
+func (s *EnvSet) LoadDotenvCascade(dir, appEnv string) error {
+	var paths []string
+	for _, path := range DotenvFiles(dir, appEnv) {
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return s.LoadDotenv(paths...)
+}
+
+// readDotenvFile reads the variables from the dotenv file of path into m.
+// The contents are verified by v if v is not nil.
+func readDotenvFile(path string, m map[string]string, v Verifier) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	} else if v != nil {
+		if err = v.Verify(path, data); err != nil {
+			return fmt.Errorf("%q: %w", path, err)
+		}
+	}
+
+	vars, err := ReadDotenv(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%q: %w", path, err)
+	}
+	for name, v := range vars {
+		m[name] = v
+	}
+	return nil
+}
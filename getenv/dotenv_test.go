@@ -0,0 +1,175 @@
+package getenv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadDotenv(t *testing.T) {
+	// test that reads the dotenv syntax
+	m, err := ReadDotenv(strings.NewReader("\xef\xbb\xbf" + `# comment
+HOST=example.com
+  PORT = 8080 # trailing comment
+export USER=admin
+EMPTY=
+HASH=a#b
+SINGLE='$HOME \n "x"' # comment
+DOUBLE="say \"hi\"\n\$HOME\t\x"
+MULTI="line1
+line2"
+CRLF=value` + "\r\n" + `HOST=example.org
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"HOST":   "example.org",
+		"PORT":   "8080",
+		"USER":   "admin",
+		"EMPTY":  "",
+		"HASH":   "a#b",
+		"SINGLE": `$HOME \n "x"`,
+		"DOUBLE": "say \"hi\"\n$HOME\t\\x",
+		"MULTI":  "line1\nline2",
+		"CRLF":   "value",
+	}, m)
+
+	// test that returns ErrDotenv with the line number
+	for src, line := range map[string]string{
+		"A=1\nB":                  "line 2",
+		"A=1\n1A=2":               "line 2",
+		"A=1\nB=\"unterminated\n": "line 2",
+		"A='x' y":                 "line 1",
+		"A=\"x\ny\" z":            "line 2",
+	} {
+		_, err := ReadDotenv(strings.NewReader(src))
+		assert.True(t, errors.Is(err, ErrDotenv), src)
+		assert.Contains(t, err.Error(), line, src)
+	}
+}
+
+func TestLoadDotenv(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_HOST")
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	assert.NoError(t, os.WriteFile(path, []byte("TEST_HOST=example.com\nTEST_PORT=8080\n"), 0o600))
+
+	host := "localhost"
+	port := 80
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+	assert.NoError(t, Set("TEST_PORT", "", &port, WithRequired()))
+
+	// test that the process environment takes precedence over the file
+	os.Setenv("TEST_HOST", "example.org")
+	assert.NoError(t, LoadDotenv(path))
+	assert.Equal(t, "example.org", host)
+	assert.Equal(t, 8080, port)
+	_, ok := os.LookupEnv("TEST_PORT")
+	assert.False(t, ok)
+
+	// test that the Sources of the set take precedence over the file
+	SetSources(NewSource("remote", MapLookuper{"TEST_PORT": "9090"}))
+	assert.NoError(t, LoadDotenv(path))
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, 9090, port)
+	assert.Equal(t, "remote", defaultSet.name2envs["TEST_PORT"].Source)
+	SetSources()
+
+	// test that returns the error of the file
+	assert.True(t, errors.Is(LoadDotenv(filepath.Join(dir, "none")), os.ErrNotExist))
+	assert.NoError(t, os.WriteFile(path, []byte("TEST_PORT\n"), 0o600))
+	err := LoadDotenv(path)
+	assert.True(t, errors.Is(err, ErrDotenv))
+	assert.Contains(t, err.Error(), path)
+
+	// test that the values are reported as the same as Parse
+	assert.NoError(t, os.WriteFile(path, []byte("TEST_PORT=http\n"), 0o600))
+	assert.True(t, errors.Is(LoadDotenv(path), ErrEnvVar))
+}
+
+func TestLoadDotenvCascade(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_D")
+	}()
+
+	// test that returns the conventional files
+	assert.Equal(t, []string{
+		filepath.Join("conf", ".env"),
+		filepath.Join("conf", ".env.local"),
+	}, DotenvFiles("conf", ""))
+	assert.Equal(t, []string{
+		filepath.Join("conf", ".env"),
+		filepath.Join("conf", ".env.local"),
+		filepath.Join("conf", ".env.prod"),
+		filepath.Join("conf", ".env.prod.local"),
+	}, DotenvFiles("conf", "prod"))
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		".env":       "TEST_A=env\nTEST_B=env\nTEST_C=env\nTEST_D=env\n",
+		".env.local": "TEST_B=local\nTEST_C=local\n",
+		".env.prod":  "TEST_C=prod\n",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600))
+	}
+
+	var a, b, c, d string
+	for name, v := range map[string]*string{"TEST_A": &a, "TEST_B": &b, "TEST_C": &c, "TEST_D": &d} {
+		assert.NoError(t, Set(name, "", v))
+	}
+
+	// test that the later files override the earlier ones and the process
+	// environment overrides all, and the missing files are skipped
+	os.Setenv("TEST_D", "os")
+	assert.NoError(t, LoadDotenvCascade(dir, "prod"))
+	assert.Equal(t, []string{"env", "local", "prod", "os"}, []string{a, b, c, d})
+
+	// test that LoadDotenv requires all the files
+	err := LoadDotenv(DotenvFiles(dir, "prod")...)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	assert.NoError(t, LoadDotenv(filepath.Join(dir, ".env.prod"), filepath.Join(dir, ".env")))
+	assert.Equal(t, "env", c)
+}
+
+func TestLoadVerifiedDotenv(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	assert.NoError(t, os.WriteFile(path, []byte("TEST_HOST=example.com\n"), 0o600))
+	host := "localhost"
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+
+	var verified []string
+	v := VerifyFunc(func(path string, data []byte) error {
+		verified = append(verified, path)
+		if !bytes.Equal(data, []byte("TEST_HOST=example.com\n")) {
+			return fmt.Errorf("%w: tampered", ErrSignature)
+		}
+		return nil
+	})
+
+	// test that verifies the files before reading them
+	assert.NoError(t, LoadVerifiedDotenv(v, path))
+	assert.Equal(t, []string{path}, verified)
+	assert.Equal(t, "example.com", host)
+
+	// test that returns ErrSignature and parses nothing
+	assert.NoError(t, os.WriteFile(path, []byte("TEST_HOST=evil.example.com\n"), 0o600))
+	err := LoadVerifiedDotenv(v, path)
+	assert.True(t, errors.Is(err, ErrSignature))
+	assert.Contains(t, err.Error(), path)
+	assert.Equal(t, "example.com", host)
+}
@@ -0,0 +1,75 @@
+package getenv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+func formatAllowed(allowed []string) string {
+	return "one of " + strings.Join(allowed, ", ")
+}
+
+// EnumCheckFunc returns the CheckFunc that allows only the string values
+// contained in allowed. The value must be a string or a slice of strings.
+func EnumCheckFunc(allowed ...string) CheckFunc {
+	isAllowed := func(v string) error {
+		for _, s := range allowed {
+			if v == s {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not allowed, must be %s", v, formatAllowed(allowed))
+	}
+
+	return func(iv interface{}, envName string) error {
+		ref := reflect.Indirect(reflect.ValueOf(iv))
+		switch {
+		case ref.Kind() == reflect.String:
+			return isAllowed(ref.String())
+
+		case ref.Kind() == reflect.Slice && ref.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < ref.Len(); i++ {
+				if err := isAllowed(ref.Index(i).String()); err != nil {
+					return fmt.Errorf("element %d of %s: %w", i, envName, err)
+				}
+			}
+			return nil
+
+		default:
+			return fmt.Errorf("%w: %T is not a pointer to string or []string", ErrValue, iv)
+		}
+	}
+}
+
+// SetEnum registers the environment variable that allows only the values
+// contained in allowed. The value must be a pointer to a string or a slice of
+// strings. The allowed values are appended to the description, and also
+// reported in the error returned by Parse. The opts configure the variable
+// like Set, except that the checker is replaced by the check of the allowed
+// values.
+func SetEnum(name, desc string, value interface{}, allowed []string, opts ...Option) error {
+	return defaultSet.SetEnum(name, desc, value, allowed, opts...)
+}
+
+// SetEnum registers the environment variable to the set like the SetEnum
+// function.
+func (s *EnvSet) SetEnum(name, desc string, value interface{}, allowed []string, opts ...Option) error {
+	ref := reflect.ValueOf(value)
+	if ref.Kind() != reflect.Ptr || ref.IsNil() {
+		return fmt.Errorf("%w: %T is not a non-nil pointer", ErrValue, value)
+	} else if t := ref.Elem().Type(); t.Kind() != reflect.String &&
+		(t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.String) {
+		return fmt.Errorf("%w: %T is not a pointer to string or []string", ErrValue, value)
+	} else if len(allowed) == 0 {
+		return fmt.Errorf("allowed values must be specified")
+	}
+
+	if desc == "" {
+		desc = formatAllowed(allowed)
+	} else {
+		desc += " (" + formatAllowed(allowed) + ")"
+	}
+	opts = append(opts[:len(opts):len(opts)], WithCheck(EnumCheckFunc(allowed...)))
+	return s.Set(name, desc, value, opts...)
+}
@@ -0,0 +1,74 @@
+package getenv
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnumCheckFunc(t *testing.T) {
+	checkfn := EnumCheckFunc("dev", "staging", "prod")
+
+	// test that allow only the specified values
+	mode := "staging"
+	assert.NoError(t, checkfn(&mode, "MODE"))
+	mode = "qa"
+	err := checkfn(&mode, "MODE")
+	assert.Equal(t, `"qa" is not allowed, must be one of dev, staging, prod`, err.Error())
+
+	// test that check each element of slice
+	type Mode string
+	modes := []Mode{"dev", "prod"}
+	assert.NoError(t, checkfn(&modes, "MODES"))
+	modes = append(modes, "Prod")
+	err = checkfn(&modes, "MODES")
+	assert.Equal(t, `element 2 of MODES: "Prod" is not allowed, must be one of dev, staging, prod`, err.Error())
+
+	// test that returns ErrValue if the value is not string
+	n := 1
+	assert.True(t, errors.Is(checkfn(&n, "MODE"), ErrValue))
+}
+
+func TestSetEnum(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_MODE")
+	}()
+
+	// test that register the enum with the allowed values in the description
+	mode := "dev"
+	assert.NoError(t, SetEnum("TEST_MODE", "running mode", &mode, []string{"dev", "staging", "prod"}))
+	Usage(func(name, desc string, defval interface{}, required bool) {
+		assert.Equal(t, "TEST_MODE", name)
+		assert.Equal(t, "running mode (one of dev, staging, prod)", desc)
+		assert.Equal(t, "dev", defval)
+	})
+
+	// test that Parse reports the allowed values
+	os.Setenv("TEST_MODE", "qa")
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), "must be one of dev, staging, prod")
+	os.Setenv("TEST_MODE", "prod")
+	assert.NoError(t, Parse())
+	assert.Equal(t, "prod", mode)
+
+	// test that the description is the allowed values if it is empty
+	var modes []string
+	assert.NoError(t, SetEnum("TEST_MODES", "", &modes, []string{"a", "b"}))
+	assert.Equal(t, "one of a, b", defaultSet.name2envs["TEST_MODES"].Description)
+
+	// test that the options configure the variable
+	var level string
+	assert.NoError(t, SetEnum("TEST_LEVEL", "", &level, []string{"a"}, WithRequired()))
+	assert.True(t, defaultSet.name2envs["TEST_LEVEL"].Required)
+
+	// test that returns error if the arguments are invalid
+	n := 1
+	assert.True(t, errors.Is(SetEnum("TEST_ENUM", "", &n, []string{"a"}), ErrValue))
+	assert.True(t, errors.Is(SetEnum("TEST_ENUM", "", mode, []string{"a"}), ErrValue))
+	assert.Error(t, SetEnum("TEST_ENUM", "", &mode, nil))
+	assert.True(t, errors.Is(SetEnum("TEST_MODE", "", &mode, []string{"a"}), ErrNameAlready))
+}
@@ -0,0 +1,63 @@
+package getenv
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// ReadEnviron reads the NUL-separated "NAME=VALUE" entries from r in the
+// format of environ(7), such as /proc/<pid>/environ and the environment
+// dumped by "systemctl show-environment --null" or "env -0". The empty
+// entries are ignored, and the entries are returned in order, so that they
+// can be passed to ParseEnviron.
+func ReadEnviron(r io.Reader) ([]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var environ []string
+	for _, kv := range bytes.Split(b, []byte{0}) {
+		if len(kv) > 0 {
+			environ = append(environ, string(kv))
+		}
+	}
+	return environ, nil
+}
+
+// EnvironSource returns the Source named "environ" that looks up the
+// variables from the file of path read by ReadEnviron, e.g.
+// EnvironSource("/proc/1234/environ") supplies the environment of the process
+// 1234. The last entry wins if the name appears more than once.
+func EnvironSource(path string) (Source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	environ, err := ReadEnviron(f)
+	if err != nil {
+		return nil, err
+	}
+	return NewSource("environ", environMap(environ)), nil
+}
+
+// ParseEnvironFile is like Parse but reads the values only from the file of
+// path read by ReadEnviron instead of the process environment, e.g. to
+// validate the registered variables against the environment of another
+// process.
+func ParseEnvironFile(path string, names ...string) error {
+	return defaultSet.ParseEnvironFile(path, names...)
+}
+
+// ParseEnvironFile reads the variables of the set from the file of path like
+// the ParseEnvironFile function.
+func (s *EnvSet) ParseEnvironFile(path string, names ...string) error {
+	src, err := EnvironSource(path)
+	if err != nil {
+		return err
+	}
+	return s.ParseFrom(src, names...)
+}
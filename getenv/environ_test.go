@@ -0,0 +1,70 @@
+package getenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadEnviron(t *testing.T) {
+	// test that the entries are split by NUL
+	environ, err := ReadEnviron(strings.NewReader("PATH=/usr/bin\x00MULTI=line1\nline2\x00EMPTY=\x00\x00NAME=a=b\x00"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"PATH=/usr/bin", "MULTI=line1\nline2", "EMPTY=", "NAME=a=b"}, environ)
+
+	// test that the last entry does not need the trailing NUL
+	environ, err = ReadEnviron(strings.NewReader("A=1\x00B=2"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A=1", "B=2"}, environ)
+
+	// test that the empty input has no entry
+	environ, err = ReadEnviron(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Empty(t, environ)
+}
+
+func TestParseEnvironFile(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+	}()
+
+	port := 80
+	name := ""
+	headers := map[string]string{}
+	assert.NoError(t, Set("TEST_PORT", "", &port, WithRequired()))
+	assert.NoError(t, Set("TEST_NAME", "", &name))
+	assert.NoError(t, CollectPrefix("TEST_HEADER_", "", &headers))
+
+	path := filepath.Join(t.TempDir(), "environ")
+	assert.NoError(t, os.WriteFile(path, []byte("TEST_PORT=8080\x00TEST_NAME=a\x00TEST_HEADER_ACCEPT=text/plain\x00TEST_NAME=b\x00"), 0o600))
+
+	// test that the values are read only from the file
+	os.Setenv("TEST_PORT", "9000")
+	assert.NoError(t, ParseEnvironFile(path))
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, "b", name)
+	assert.Equal(t, map[string]string{"ACCEPT": "text/plain"}, headers)
+
+	// test that the Source records its name
+	env, _ := Lookup("TEST_PORT")
+	assert.Equal(t, "environ", env.Source)
+
+	// test that the missing variables of the file are reported
+	assert.NoError(t, os.WriteFile(path, []byte("TEST_NAME=c\x00"), 0o600))
+	err := ParseEnvironFile(path)
+	assert.True(t, errors.Is(err, ErrNotDefined))
+	assert.Equal(t, "b", name)
+
+	// test that only the variables of the names are read
+	assert.NoError(t, ParseEnvironFile(path, "TEST_NAME"))
+	assert.Equal(t, "c", name)
+
+	// test that the error of the file is returned
+	err = ParseEnvironFile(filepath.Join(t.TempDir(), "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
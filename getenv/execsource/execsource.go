@@ -0,0 +1,74 @@
+// Package execsource provides the getenv.Source that resolves the variables
+// by running the commands of the CLI secret managers such as 1Password CLI
+// and pass.
+//
+//	// run the command for each variable
+//	src, err := execsource.New(ctx, []string{"DB_PASSWORD"}, "op", "read", "op://dev/{name}/password")
+//
+//	// run the command once and read its output in the dotenv syntax
+//	src, err := execsource.NewBatch(ctx, "op", "inject", "-i", "dev.env.tpl")
+package execsource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/mah0x211/go-getenv/getenv"
+)
+
+// NamePlaceholder is replaced with the variable name in the arguments of the
+// command run by New.
+const NamePlaceholder = "{name}"
+
+// run runs the command and returns its standard output. The error includes
+// the standard error of the command.
+func run(ctx context.Context, name string, args []string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("exec: %s: %w: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("exec: %s: %w", name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// New returns the Source named "exec" that supplies the value of each of
+// names by the standard output of the command, which is run with
+// NamePlaceholder in args replaced with the name. The trailing newline of the
+// output is removed. The error is returned if any command fails.
+func New(ctx context.Context, names []string, command string, args ...string) (getenv.Source, error) {
+	m := getenv.MapLookuper{}
+	for _, name := range names {
+		list := make([]string, len(args))
+		for i, arg := range args {
+			list[i] = strings.ReplaceAll(arg, NamePlaceholder, name)
+		}
+		b, err := run(ctx, command, list)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", name, err)
+		}
+		v := strings.TrimSuffix(string(b), "\n")
+		m[name] = strings.TrimSuffix(v, "\r")
+	}
+	return getenv.NewSource("exec", m), nil
+}
+
+// NewBatch returns the Source named "exec" that supplies the variables read
+// from the standard output of the command by getenv.ReadDotenv.
+func NewBatch(ctx context.Context, command string, args ...string) (getenv.Source, error) {
+	b, err := run(ctx, command, args)
+	if err != nil {
+		return nil, err
+	}
+	m, err := getenv.ReadDotenv(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("exec: %s: %w", command, err)
+	}
+	return getenv.NewSource("exec", getenv.MapLookuper(m)), nil
+}
@@ -0,0 +1,51 @@
+package execsource
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/mah0x211/go-getenv/getenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// test that runs the command for each name
+	src, err := New(context.Background(), []string{"DB_PASSWORD", "API_KEY"}, "sh", "-c", `echo "secret of {name}"`)
+	assert.NoError(t, err)
+	assert.Equal(t, "exec", src.Name())
+	v, _ := src.Lookup("DB_PASSWORD")
+	assert.Equal(t, "secret of DB_PASSWORD", v)
+	v, _ = src.Lookup("API_KEY")
+	assert.Equal(t, "secret of API_KEY", v)
+
+	// test that returns the error with the standard error
+	_, err = New(context.Background(), []string{"DB_PASSWORD"}, "sh", "-c", "echo 'item not found' >&2; exit 1")
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Contains(t, err.Error(), `"DB_PASSWORD"`)
+	assert.Contains(t, err.Error(), "item not found")
+}
+
+func TestNewBatch(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// test that reads the output in the dotenv syntax
+	src, err := NewBatch(context.Background(), "sh", "-c", `printf 'DB_PASSWORD=s3cret\nexport API_KEY="key"\n'`)
+	assert.NoError(t, err)
+	v, _ := src.Lookup("DB_PASSWORD")
+	assert.Equal(t, "s3cret", v)
+	v, _ = src.Lookup("API_KEY")
+	assert.Equal(t, "key", v)
+
+	// test that returns the error of the syntax
+	_, err = NewBatch(context.Background(), "sh", "-c", "echo invalid")
+	assert.True(t, errors.Is(err, getenv.ErrDotenv))
+}
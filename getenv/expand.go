@@ -0,0 +1,236 @@
+package getenv
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Expansion is the set of the syntaxes of the references to the other
+// variables that are expanded in the values before parsing.
+type Expansion int
+
+const (
+	// ExpandPercent expands the references of the form %NAME% like the
+	// ExpandEnvironmentStrings function of Windows, e.g. %SystemRoot%\Temp.
+	// The references to the undefined variables and the text between the
+	// percent signs that is not the name are left as they are, and the
+	// expanded values are not expanded again.
+	ExpandPercent Expansion = 1 << iota
+	// ExpandBraces expands the references of the form ${NAME} like the
+	// shell, e.g. https://${API_HOST}:${API_PORT}. The referenced values are
+	// expanded recursively by the enabled syntaxes, and the variable that is
+	// not defined by the Sources is expanded by the current value of the
+	// registered variable of the name. "$$" is expanded to "$". Parse fails
+	// with ErrExpand if the reference is not defined or refers to itself.
+	//
+	// The name can be followed by the operators of the parameter expansion
+	// of the shell. ${NAME:-default} and ${NAME-default} are expanded to the
+	// expanded default if NAME is not defined or empty, and if NAME is not
+	// defined, respectively. ${NAME:?message} and ${NAME?message} make Parse
+	// fail with ErrExpand and the message in the same conditions.
+	ExpandBraces
+)
+
+// ErrExpand is returned by Parse if the reference in the value cannot be
+// expanded.
+var ErrExpand = fmt.Errorf("cannot expand reference")
+
+// SetExpansion enables the expansion of the references to the other variables
+// in the values. The references are expanded by the values looked up from the
+// same Sources as the values, before the values are decrypted and parsed. No
+// reference is expanded by default.
+//
+//	getenv.SetExpansion(getenv.ExpandBraces)
+//	if runtime.GOOS == "windows" {
+//		getenv.SetExpansion(getenv.ExpandBraces | getenv.ExpandPercent)
+//	}
+func SetExpansion(e Expansion) {
+	defaultSet.SetExpansion(e)
+}
+
+// SetExpansion enables the expansion of the set like the SetExpansion
+// function. The sets returned by Sub after the call inherit the expansion.
+func (s *EnvSet) SetExpansion(e Expansion) {
+	s.expansion = e
+}
+
+// expand expands the references in the value v of the variable of name by the
+// values looked up from l.
+func (s *EnvSet) expand(ctx context.Context, l Lookuper, name, v string) (string, error) {
+	if s.expansion == 0 {
+		return v, nil
+	}
+	e := &expander{ctx: ctx, set: s, l: l, stack: []string{name}}
+	v, err := e.expand(v)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q %w", ErrEnvVar, name, err)
+	}
+	return v, nil
+}
+
+// expander expands the references in the values.
+type expander struct {
+	ctx context.Context
+	set *EnvSet
+	l   Lookuper
+	// names of the variables being expanded to detect the cycle
+	stack []string
+}
+
+// expand expands the references in v in a single pass, so that the expanded
+// values are not scanned again for the other syntax.
+func (e *expander) expand(v string) (string, error) {
+	braces := e.set.expansion&ExpandBraces != 0
+	percent := e.set.expansion&ExpandPercent != 0
+
+	var b strings.Builder
+	for i := 0; i < len(v); {
+		switch {
+		case braces && strings.HasPrefix(v[i:], "$$"):
+			b.WriteByte('$')
+			i += 2
+			continue
+
+		case braces && strings.HasPrefix(v[i:], "${"):
+			end := closingBrace(v[i:])
+			if end < 0 {
+				return "", fmt.Errorf("%w: %q is not terminated", ErrExpand, v[i:])
+			}
+			ref, err := e.resolve(v[i+2 : i+end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(ref)
+			i += end + 1
+			continue
+
+		case percent && v[i] == '%':
+			if j := strings.IndexByte(v[i+1:], '%'); j > 0 {
+				ref, err := e.resolvePercent(v[i+1 : i+1+j])
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(ref)
+				i += j + 2
+				continue
+			}
+		}
+		b.WriteByte(v[i])
+		i++
+	}
+	return b.String(), nil
+}
+
+// resolvePercent returns the value of the reference %name%, or the reference
+// as it is if the variable of name is not defined.
+func (e *expander) resolvePercent(name string) (string, error) {
+	if checkName(name) == nil {
+		if v, _, ok, err := lookupSource(e.ctx, e.l, name); err != nil || ok {
+			return v, err
+		}
+	}
+	return "%" + name + "%", nil
+}
+
+// closingBrace returns the index of the "}" that closes the "${" at the
+// beginning of v, or -1 if not found. The nested "${" are skipped.
+func closingBrace(v string) int {
+	depth := 0
+	for i := 0; i < len(v); i++ {
+		switch {
+		case strings.HasPrefix(v[i:], "$$"):
+			i++
+		case strings.HasPrefix(v[i:], "${"):
+			depth++
+			i++
+		case v[i] == '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// resolve returns the expanded value of the reference ${expr}.
+func (e *expander) resolve(expr string) (string, error) {
+	// split the operator of the parameter expansion
+	name, op, word := expr, "", ""
+	i := 0
+	for i < len(expr) && (isAlpha(expr[i]) || isDigit(expr[i]) || expr[i] == '_') {
+		i++
+	}
+	if i > 0 {
+		for _, o := range []string{":-", ":?", "-", "?"} {
+			if strings.HasPrefix(expr[i:], o) {
+				name, op, word = expr[:i], o, expr[i+len(o):]
+				break
+			}
+		}
+	}
+
+	v, ok, err := e.lookup(name)
+	if err != nil {
+		return "", err
+	} else if ok && (v != "" || !strings.HasPrefix(op, ":")) {
+		return v, nil
+	}
+
+	switch op {
+	case ":-", "-":
+		return e.expand(word)
+	case ":?", "?":
+		if word == "" && op == ":?" {
+			word = "is not defined or empty"
+		} else if word == "" {
+			word = "is not defined"
+		} else if word, err = e.expand(word); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %q %s", ErrExpand, "${"+name+"}", word)
+	}
+	return "", fmt.Errorf("%w: %q is not defined", ErrExpand, "${"+name+"}")
+}
+
+// lookup returns the expanded value of the variable of name, and false if it
+// is not defined.
+func (e *expander) lookup(name string) (string, bool, error) {
+	if err := checkName(name); err != nil {
+		return "", false, fmt.Errorf("%w: %q %w", ErrExpand, "${"+name+"}", err)
+	}
+	for i, s := range e.stack {
+		if s == name {
+			cycle := make([]string, 0, len(e.stack)-i+1)
+			for _, s := range e.stack[i:] {
+				cycle = append(cycle, strconv.Quote(s))
+			}
+			cycle = append(cycle, strconv.Quote(name))
+			return "", false, fmt.Errorf("%w: cycle %s", ErrExpand, strings.Join(cycle, " -> "))
+		}
+	}
+
+	v, _, ok, err := lookupSource(e.ctx, e.l, name)
+	if err != nil {
+		return "", false, err
+	} else if ok {
+		e.stack = append(e.stack, name)
+		defer func() {
+			e.stack = e.stack[:len(e.stack)-1]
+		}()
+		v, err = e.expand(v)
+		return v, err == nil, err
+	}
+
+	// the current value of the registered variable
+	if env, ok := e.set.name2envs[e.set.envName(name)]; ok {
+		v, ok, err := formatValue(reflect.ValueOf(env.Value).Elem(), env.separator())
+		if err != nil {
+			return "", false, fmt.Errorf("%w: %q %w", ErrExpand, "${"+name+"}", err)
+		}
+		return v, ok, nil
+	}
+	return "", false, nil
+}
@@ -0,0 +1,295 @@
+package getenv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newExpander returns the expander of the set that has the expansion e.
+func newExpander(l Lookuper, e Expansion) *expander {
+	s := NewEnvSet()
+	s.SetExpansion(e)
+	return &expander{ctx: context.Background(), set: s, l: l}
+}
+
+func TestExpandPercent(t *testing.T) {
+	l := MapLookuper{
+		"SystemRoot": `C:\Windows`,
+		"USERNAME":   "alice",
+		"PERCENT":    "%",
+		"NESTED":     "%USERNAME%",
+	}
+	for v, want := range map[string]string{
+		"":                           "",
+		"plain":                      "plain",
+		`%SystemRoot%\Temp`:          `C:\Windows\Temp`,
+		"%USERNAME%@%USERNAME%":      "alice@alice",
+		"%UNDEFINED%\\x":             "%UNDEFINED%\\x",
+		"50% off for %USERNAME%":     "50% off for %USERNAME%",
+		"100%":                       "100%",
+		"%%":                         "%%",
+		"%%USERNAME%%":               "%alice%",
+		"%PERCENT%USERNAME%":         "%USERNAME%",
+		"%NESTED%":                   "%USERNAME%",
+		"%NOT A NAME%%USERNAME%":     "%NOT A NAME%alice",
+		"%UNDEFINED%USERNAME%":       "%UNDEFINED%USERNAME%",
+		"a%USERNAME%b%SystemRoot%c%": `aaliceb` + `C:\Windows` + "c%",
+	} {
+		got, err := newExpander(l, ExpandPercent).expand(v)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, v)
+	}
+
+	// test that the error of the lookup is returned
+	errLookup := errors.New("connection refused")
+	cl := &contextLookuper{MapLookuper: l, err: errLookup}
+	_, err := newExpander(cl, ExpandPercent).expand("%USERNAME%")
+	assert.Equal(t, errLookup, err)
+}
+
+func TestSetExpansion(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	dir := ""
+	var cfg struct {
+		Servers []struct {
+			Home string
+		} `env:"TEST_SERVER"`
+	}
+	assert.NoError(t, Set("TEST_DIR", "", &dir))
+	assert.NoError(t, Bind(&cfg))
+	m := map[string]string{
+		"SystemRoot":         `C:\Windows`,
+		"TEST_DIR":           `%SystemRoot%\Temp`,
+		"TEST_SERVER_0_HOME": `%SystemRoot%\Users`,
+	}
+
+	// test that no reference is expanded by default
+	assert.NoError(t, ParseMap(m))
+	assert.Equal(t, `%SystemRoot%\Temp`, dir)
+	assert.Equal(t, `%SystemRoot%\Users`, cfg.Servers[0].Home)
+
+	// test that the references are expanded by the values of the Sources
+	SetExpansion(ExpandPercent)
+	assert.NoError(t, ParseMap(m))
+	assert.Equal(t, `C:\Windows\Temp`, dir)
+	assert.Equal(t, `C:\Windows\Users`, cfg.Servers[0].Home)
+
+	// test that ParseValue expands the references by the Sources of the set
+	SetSources(NewSource("env", MapLookuper{"SystemRoot": `D:\Windows`}))
+	assert.NoError(t, ParseValue("TEST_DIR", `%SystemRoot%\Logs`))
+	assert.Equal(t, `D:\Windows\Logs`, dir)
+
+	// test that the sub sets inherit the expansion
+	assert.Equal(t, ExpandPercent, WithPrefix("TEST_").expansion)
+
+	// test that the references are matched ignoring the case by the
+	// case-insensitive set
+	s := NewEnvSet(WithCaseInsensitive())
+	s.SetExpansion(ExpandPercent)
+	assert.NoError(t, s.Set("TEST_DIR", "", &dir))
+	assert.NoError(t, s.ParseMap(map[string]string{
+		"SYSTEMROOT": `E:\Windows`,
+		"Test_Dir":   `%SystemRoot%\Temp`,
+	}))
+	assert.Equal(t, `E:\Windows\Temp`, dir)
+
+	// test that the error of the lookup identifies the variable
+	errLookup := errors.New("connection refused")
+	s = NewEnvSet()
+	s.SetExpansion(ExpandPercent)
+	assert.NoError(t, s.Set("TEST_DIR", "", &dir))
+	l := &contextLookuper{MapLookuper: MapLookuper{"TEST_DIR": `%SystemRoot%\Temp`}}
+	s.SetSources(NewSource("remote", l))
+	assert.NoError(t, s.Parse())
+	l.err = errLookup
+	err := s.ParseValue("TEST_DIR", `%SystemRoot%\Temp`)
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.True(t, errors.Is(err, errLookup))
+	assert.Contains(t, err.Error(), `"TEST_DIR" remote: connection refused`)
+}
+
+func TestExpandBraces(t *testing.T) {
+	l := MapLookuper{
+		"API_HOST":  "api.example.com",
+		"API_PORT":  "8443",
+		"API_URL":   "https://${API_HOST}:${API_PORT}",
+		"ENDPOINT":  "${API_URL}/v1",
+		"PERCENT":   "%API_HOST%",
+		"DOLLAR":    "$${API_HOST}",
+		"EMPTY":     "",
+		"CYCLE_A":   "${CYCLE_B}",
+		"CYCLE_B":   "x${CYCLE_A}",
+		"UNDEFINED": "${MISSING}",
+	}
+	for v, want := range map[string]string{
+		"":                 "",
+		"plain":            "plain",
+		"$HOME":            "$HOME",
+		"cost $5":          "cost $5",
+		"${API_HOST}":      "api.example.com",
+		"${API_URL}":       "https://api.example.com:8443",
+		"${ENDPOINT}/ping": "https://api.example.com:8443/v1/ping",
+		"[${EMPTY}]":       "[]",
+		"$${API_HOST}":     "${API_HOST}",
+		"$$$${API_HOST}":   "$${API_HOST}",
+		"${DOLLAR}":        "${API_HOST}",
+		"${PERCENT}":       "%API_HOST%",
+		"%API_HOST%":       "%API_HOST%",
+		"${API_HOST}$":     "api.example.com$",
+	} {
+		got, err := newExpander(l, ExpandBraces).expand(v)
+		assert.NoError(t, err, v)
+		assert.Equal(t, want, got, v)
+	}
+
+	// test that the values of %NAME% are not expanded again, while the values
+	// of ${NAME} are expanded by both syntaxes
+	l["BRACES"] = "${API_HOST}"
+	got, err := newExpander(l, ExpandBraces|ExpandPercent).expand("%BRACES% ${PERCENT}")
+	assert.NoError(t, err)
+	assert.Equal(t, "${API_HOST} api.example.com", got)
+
+	// test that the invalid references are reported
+	for v, msg := range map[string]string{
+		"${MISSING}":      `cannot expand reference: "${MISSING}" is not defined`,
+		"${UNDEFINED}":    `cannot expand reference: "${MISSING}" is not defined`,
+		"${API_HOST":      `cannot expand reference: "${API_HOST" is not terminated`,
+		"${}":             `cannot expand reference: "${}" name must be`,
+		"${NOT A NAME}":   `cannot expand reference: "${NOT A NAME}" name must be`,
+		"${CYCLE_A}":      `cannot expand reference: cycle "CYCLE_A" -> "CYCLE_B" -> "CYCLE_A"`,
+		"x${ENDPOINT}${}": `cannot expand reference: "${}"`,
+	} {
+		_, err := newExpander(l, ExpandBraces).expand(v)
+		assert.True(t, errors.Is(err, ErrExpand), v)
+		assert.Contains(t, err.Error(), msg, v)
+	}
+}
+
+func TestSetExpansionBraces(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	url := ""
+	port := 8080
+	hosts := []string{"a", "b"}
+	assert.NoError(t, Set("TEST_URL", "", &url))
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	assert.NoError(t, Set("TEST_HOSTS", "", &hosts))
+	SetExpansion(ExpandBraces)
+
+	// test that the references are expanded by the Sources or the current
+	// values of the registered variables
+	assert.NoError(t, ParseMap(map[string]string{
+		"TEST_HOST": "example.com",
+		"TEST_URL":  "https://${TEST_HOST}:${TEST_PORT}/?hosts=${TEST_HOSTS}",
+	}))
+	assert.Equal(t, "https://example.com:8080/?hosts=a,b", url)
+
+	// test that the value of the Source takes precedence
+	assert.NoError(t, ParseMap(map[string]string{
+		"TEST_HOST": "example.com",
+		"TEST_PORT": "${TEST_ALT_PORT}",
+		"TEST_URL":  "https://${TEST_HOST}:${TEST_PORT}",
+
+		"TEST_ALT_PORT": "8443",
+	}))
+	assert.Equal(t, "https://example.com:8443", url)
+	assert.Equal(t, 8443, port)
+
+	// test that the variable that refers to itself is reported
+	err := ParseMap(map[string]string{"TEST_URL": "${TEST_URL}/v1"})
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.True(t, errors.Is(err, ErrExpand))
+	assert.Contains(t, err.Error(), `"TEST_URL" cannot expand reference: cycle "TEST_URL" -> "TEST_URL"`)
+
+	// test that the undefined references are reported and no value is
+	// changed
+	err = ParseMap(map[string]string{
+		"TEST_PORT": "9000",
+		"TEST_URL":  "https://${TEST_MISSING}",
+	})
+	assert.True(t, errors.Is(err, ErrExpand))
+	assert.Equal(t, `invalid environment variable: "TEST_URL" cannot expand reference: "${TEST_MISSING}" is not defined`, err.Error())
+	assert.Equal(t, 8443, port)
+	assert.Equal(t, "https://example.com:8443", url)
+
+	// test that ParseValue expands the references
+	assert.NoError(t, ParseValue("TEST_URL", "http://localhost:${TEST_PORT}"))
+	assert.Equal(t, "http://localhost:8443", url)
+}
+
+func TestExpandBracesOperators(t *testing.T) {
+	l := MapLookuper{
+		"HOST":  "example.com",
+		"EMPTY": "",
+		"PORT":  "8443",
+		"REF":   "${MISSING:-fallback}",
+	}
+	for v, want := range map[string]string{
+		"${HOST:-localhost}":              "example.com",
+		"${MISSING:-localhost}":           "localhost",
+		"${EMPTY:-localhost}":             "localhost",
+		"${MISSING-localhost}":            "localhost",
+		"${EMPTY-localhost}":              "",
+		"${MISSING:-}":                    "",
+		"${MISSING:-${HOST}:${PORT}}":     "example.com:8443",
+		"${MISSING:-${OTHER:-${PORT}}}/x": "8443/x",
+		"${MISSING:-$${HOST}}":            "${HOST}",
+		"${MISSING:-a:-b}":                "a:-b",
+		"${HOST:-${UNDEFINED}}":           "example.com",
+		"${HOST:?must be set}":            "example.com",
+		"${EMPTY?must be set}":            "",
+		"${REF}":                          "fallback",
+	} {
+		got, err := newExpander(l, ExpandBraces).expand(v)
+		assert.NoError(t, err, v)
+		assert.Equal(t, want, got, v)
+	}
+
+	// test that the operator-authored messages are reported
+	for v, msg := range map[string]string{
+		"${MISSING:?DB host must be set}": `cannot expand reference: "${MISSING}" DB host must be set`,
+		"${EMPTY:?DB host must be set}":   `cannot expand reference: "${EMPTY}" DB host must be set`,
+		"${MISSING?set ${HOST} host}":     `cannot expand reference: "${MISSING}" set example.com host`,
+		"${MISSING:?}":                    `cannot expand reference: "${MISSING}" is not defined or empty`,
+		"${MISSING?}":                     `cannot expand reference: "${MISSING}" is not defined`,
+		"${MISSING:-${UNDEFINED}}":        `cannot expand reference: "${UNDEFINED}" is not defined`,
+		"${MISSING:-${HOST}":              `cannot expand reference: "${MISSING:-${HOST}" is not terminated`,
+	} {
+		_, err := newExpander(l, ExpandBraces).expand(v)
+		assert.True(t, errors.Is(err, ErrExpand), v)
+		assert.Equal(t, msg, err.Error(), v)
+	}
+}
+
+func TestSetExpansionOperators(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	url := ""
+	assert.NoError(t, Set("TEST_URL", "", &url))
+	SetExpansion(ExpandBraces)
+
+	// test that the default is used for the undefined variable
+	assert.NoError(t, ParseMap(map[string]string{
+		"TEST_URL": "https://${TEST_HOST:-localhost}:${TEST_PORT:-8080}",
+	}))
+	assert.Equal(t, "https://localhost:8080", url)
+
+	// test that the message of the operator is reported
+	err := ParseMap(map[string]string{
+		"TEST_URL": "https://${TEST_HOST:?TEST_HOST is required to compose TEST_URL}",
+	})
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.True(t, errors.Is(err, ErrExpand))
+	assert.Equal(t, `invalid environment variable: "TEST_URL" cannot expand reference: "${TEST_HOST}" TEST_HOST is required to compose TEST_URL`, err.Error())
+	assert.Equal(t, "https://localhost:8080", url)
+}
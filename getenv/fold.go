@@ -0,0 +1,114 @@
+package getenv
+
+import (
+	"context"
+	"strings"
+)
+
+// WithCaseInsensitive makes the EnvSet match the names of the variables
+// ignoring the case like the environment of Windows, e.g. the registered
+// variable PATH is read from the variable Path. The registration of the name
+// that differs from the registered one only in the case fails with
+// ErrNameAlready, and the methods that take the name, such as Lookup and
+// ParseValue, accept the name in any case.
+func WithCaseInsensitive() SetOption {
+	return func(s *EnvSet) {
+		s.caseInsensitive = true
+	}
+}
+
+// envName returns the registered name that is equal to name, or equal to
+// name ignoring the case if the set is case-insensitive. name is returned as
+// is if not found.
+func (s *EnvSet) envName(name string) string {
+	if _, ok := s.name2envs[name]; ok || !s.caseInsensitive {
+		return name
+	} else if found, ok := s.foldName(name); ok {
+		return found
+	}
+	return name
+}
+
+// foldName returns the registered name that is equal to name ignoring the
+// case.
+func (s *EnvSet) foldName(name string) (string, bool) {
+	for k := range s.name2envs {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// collectorPrefix is like envName but returns the prefix of the collector.
+func (s *EnvSet) collectorPrefix(prefix string) string {
+	if _, ok := s.prefix2collectors[prefix]; ok || !s.caseInsensitive {
+		return prefix
+	} else if found, ok := s.foldPrefix(prefix); ok {
+		return found
+	}
+	return prefix
+}
+
+// foldPrefix returns the prefix of the collector that is equal to prefix
+// ignoring the case.
+func (s *EnvSet) foldPrefix(prefix string) (string, bool) {
+	for k := range s.prefix2collectors {
+		if strings.EqualFold(k, prefix) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// seenKey returns the key of name to detect the duplicated names, that is
+// name in upper case if the set is case-insensitive.
+func (s *EnvSet) seenKey(name string) string {
+	if s.caseInsensitive {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// caseLookuper returns l that looks up the variables ignoring the case if the
+// set is case-insensitive.
+func (s *EnvSet) caseLookuper(l Lookuper) Lookuper {
+	if s.caseInsensitive {
+		return foldLookuper{l: l}
+	}
+	return l
+}
+
+// foldLookuper looks up the variables from l ignoring the case of the names.
+type foldLookuper struct {
+	l Lookuper
+}
+
+func (f foldLookuper) Lookup(name string) (string, bool) {
+	v, _, ok, _ := f.lookupSource(context.Background(), name)
+	return v, ok
+}
+
+func (f foldLookuper) Environ() []string {
+	if el, ok := f.l.(EnvironLookuper); ok {
+		return el.Environ()
+	}
+	return nil
+}
+
+// lookupSource is like the lookupSource function but looks up the variable
+// whose name is equal to name ignoring the case from the variables listed by
+// l if the variable of name is not defined. The variables are not listed
+// unless l is the EnvironLookuper.
+func (f foldLookuper) lookupSource(ctx context.Context, name string) (v, src string, ok bool, err error) {
+	if v, src, ok, err = lookupSource(ctx, f.l, name); err != nil || ok {
+		return v, src, ok, err
+	} else if el, isEL := f.l.(EnvironLookuper); isEL {
+		for _, kv := range el.Environ() {
+			if k, _, _ := strings.Cut(kv, "="); k != name && strings.EqualFold(k, name) {
+				return lookupSource(ctx, f.l, k)
+			}
+		}
+	}
+	return "", "", false, nil
+}
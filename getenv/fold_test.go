@@ -0,0 +1,77 @@
+package getenv
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithCaseInsensitive(t *testing.T) {
+	s := NewEnvSet(WithCaseInsensitive())
+	path := ""
+	port := 80
+	headers := map[string]string{}
+	var cfg struct {
+		Servers []struct {
+			Host string
+		} `env:"SERVER"`
+	}
+	assert.NoError(t, s.Set("PATH", "", &path, WithRequired()))
+	assert.NoError(t, s.Set("APP_PORT", "", &port))
+	assert.NoError(t, s.CollectPrefix("HEADER_", "", &headers))
+	assert.NoError(t, s.Bind(&cfg))
+
+	// test that the registration collides with the name in the other case
+	var v string
+	err := s.Set("Path", "", &v)
+	assert.True(t, errors.Is(err, ErrNameAlready))
+	assert.Equal(t, `environment variable name is already registered: "Path" already registered as "PATH"`, err.Error())
+	assert.True(t, errors.Is(s.Replace("path", "", &v), ErrNameAlready))
+	assert.True(t, errors.Is(s.CollectPrefix("Header_", "", &map[string]string{}), ErrNameAlready))
+	var dup struct {
+		Path string
+	}
+	assert.True(t, errors.Is(s.BindWithNaming(&dup, func(string) string { return "path" }), ErrNameAlready))
+	assert.True(t, errors.Is(s.Sub("app_").Set("port", "", &v), ErrNameAlready))
+
+	// test that the names are matched ignoring the case
+	assert.NoError(t, s.ParseFrom(NewSource("env", MapLookuper{
+		"Path":                 `C:\Windows`,
+		"app_port":             "8080",
+		"Header_Accept":        "text/plain",
+		"header_CACHE_CONTROL": "no-cache",
+		"Server_0_Host":        "example.com",
+	})))
+	assert.Equal(t, `C:\Windows`, path)
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, map[string]string{"Accept": "text/plain", "CACHE_CONTROL": "no-cache"}, headers)
+	assert.Len(t, cfg.Servers, 1)
+	assert.Equal(t, "example.com", cfg.Servers[0].Host)
+	env, ok := s.Lookup("path")
+	assert.True(t, ok)
+	assert.Equal(t, "PATH", env.Name)
+	assert.Equal(t, "env", env.Source)
+
+	// test that the exact name takes precedence
+	assert.NoError(t, s.ParseMap(map[string]string{"Path": "folded", "PATH": "exact"}))
+	assert.Equal(t, "exact", path)
+
+	// test that the methods accept the name in any case
+	assert.True(t, s.Has("app_Port"))
+	assert.NoError(t, s.ParseValue("App_Port", "9000"))
+	assert.Equal(t, 9000, port)
+	assert.NoError(t, s.ParseMap(map[string]string{"APP_PORT": "7000"}, "app_port"))
+	assert.Equal(t, 7000, port)
+	assert.True(t, s.Unset("header_"))
+	assert.False(t, s.Has("HEADER_"))
+
+	// test that the names are matched strictly by default
+	s = NewEnvSet()
+	assert.NoError(t, s.Set("PATH", "", &path))
+	assert.NoError(t, s.Set("Path", "", &v))
+	assert.False(t, s.Has("path"))
+	path = ""
+	assert.NoError(t, s.ParseMap(map[string]string{"path": "x"}, "PATH"))
+	assert.Equal(t, "", path)
+}
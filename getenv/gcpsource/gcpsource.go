@@ -0,0 +1,143 @@
+// Package gcpsource provides the getenv.Source that resolves the registered
+// variables from Google Cloud Secret Manager by the Application Default
+// Credentials.
+//
+//	getenv.MustSet("DB_PASSWORD", "database password", &password, getenv.WithSecret())
+//	src, err := gcpsource.New(ctx, "my-project", getenv.SecretNames(),
+//		gcpsource.WithVersion("DB_PASSWORD", "5"))
+//	if err != nil {
+//		return err
+//	}
+//	getenv.SetSources(getenv.EnvSource(), src)
+package gcpsource
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mah0x211/go-getenv/getenv"
+	"golang.org/x/oauth2/google"
+)
+
+// DefaultEndpoint is the endpoint of the Secret Manager API.
+const DefaultEndpoint = "https://secretmanager.googleapis.com"
+
+const scope = "https://www.googleapis.com/auth/cloud-platform"
+
+type options struct {
+	endpoint string
+	client   *http.Client
+	prefix   string
+	versions map[string]string
+}
+
+// Option configures the Source.
+type Option func(o *options)
+
+// WithVersion pins the version of the secret of name. The "latest" version is
+// accessed by default.
+func WithVersion(name, version string) Option {
+	return func(o *options) {
+		o.versions[name] = version
+	}
+}
+
+// WithPrefix resolves the variables from the secrets whose ids are the names
+// prefixed by prefix, e.g. "myapp-DB_PASSWORD" with the prefix "myapp-".
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithHTTPClient uses c to access the API instead of the client authorized by
+// the Application Default Credentials.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.client = c
+	}
+}
+
+// WithEndpoint uses the endpoint instead of DefaultEndpoint.
+func WithEndpoint(endpoint string) Option {
+	return func(o *options) {
+		o.endpoint = strings.TrimRight(endpoint, "/")
+	}
+}
+
+// New returns the Source named "gcpsecretmanager" that supplies the payloads
+// of the secrets of names in project. The secrets or the versions that do not
+// exist are treated as undefined.
+func New(ctx context.Context, project string, names []string, opts ...Option) (getenv.Source, error) {
+	o := &options{endpoint: DefaultEndpoint, versions: map[string]string{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.client == nil {
+		c, err := google.DefaultClient(ctx, scope)
+		if err != nil {
+			return nil, fmt.Errorf("gcpsecretmanager: %w", err)
+		}
+		o.client = c
+	}
+
+	m := getenv.MapLookuper{}
+	for _, name := range names {
+		version := o.versions[name]
+		if version == "" {
+			version = "latest"
+		}
+		v, ok, err := access(ctx, o, fmt.Sprintf("projects/%s/secrets/%s/versions/%s",
+			url.PathEscape(project), url.PathEscape(o.prefix+name), url.PathEscape(version)))
+		if err != nil {
+			return nil, err
+		} else if ok {
+			m[name] = v
+		}
+	}
+	return getenv.NewSource("gcpsecretmanager", m), nil
+}
+
+// access returns the payload of the secret version of resource.
+func access(ctx context.Context, o *options, resource string) (string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.endpoint+"/v1/"+resource+":access", nil)
+	if err != nil {
+		return "", false, fmt.Errorf("gcpsecretmanager: %w", err)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("gcpsecretmanager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	} else if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return "", false, fmt.Errorf("gcpsecretmanager: %s: %s %s", resource, resp.Status, e.Error.Message)
+	}
+
+	var res struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", false, fmt.Errorf("gcpsecretmanager: %s: %w", resource, err)
+	}
+	b, err := base64.StdEncoding.DecodeString(res.Payload.Data)
+	if err != nil {
+		return "", false, fmt.Errorf("gcpsecretmanager: %s: %w", resource, err)
+	}
+	return string(b), true, nil
+}
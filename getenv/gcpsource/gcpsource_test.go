@@ -0,0 +1,58 @@
+package gcpsource
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		secrets := map[string]string{
+			"/v1/projects/proj/secrets/app-DB_PASSWORD/versions/latest:access": "s3cret",
+			"/v1/projects/proj/secrets/app-API_KEY/versions/5:access":          "key-v5",
+		}
+		if strings.Contains(r.URL.Path, "DENIED") {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]string{"message": "permission denied"},
+			})
+			return
+		} else if v, ok := secrets[r.URL.Path]; ok {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte(v))},
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	// test that resolves the latest or the pinned versions
+	src, err := New(context.Background(), "proj", []string{"DB_PASSWORD", "API_KEY", "MISSING"},
+		WithEndpoint(srv.URL+"/"), WithHTTPClient(srv.Client()), WithPrefix("app-"),
+		WithVersion("API_KEY", "5"))
+	assert.NoError(t, err)
+	assert.Equal(t, "gcpsecretmanager", src.Name())
+	v, ok := src.Lookup("DB_PASSWORD")
+	assert.True(t, ok)
+	assert.Equal(t, "s3cret", v)
+	v, _ = src.Lookup("API_KEY")
+	assert.Equal(t, "key-v5", v)
+	_, ok = src.Lookup("MISSING")
+	assert.False(t, ok)
+	assert.Len(t, paths, 3)
+
+	// test that returns the error of the API
+	_, err = New(context.Background(), "proj", []string{"DENIED"},
+		WithEndpoint(srv.URL), WithHTTPClient(srv.Client()))
+	assert.Contains(t, err.Error(), "permission denied")
+}
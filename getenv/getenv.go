@@ -1,12 +1,20 @@
 package getenv
 
 import (
+	"context"
+	"database/sql"
+	"encoding"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math/big"
 	"os"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 func isDigit(b byte) bool {
@@ -25,37 +33,43 @@ func isAlpha(b byte) bool {
 	return isUpper(b) || isLower(b)
 }
 
-func parseInt(s string, k reflect.Kind) (int64, error) {
+// ErrUnsupportedType is returned by the parser if the value is not of the type
+// that the parser can handle. It is reported at the Parse time only if the
+// value is registered with a custom ParseFunc that delegates to the default
+// parser, since the type is validated at the registration otherwise.
+var ErrUnsupportedType = fmt.Errorf("unsupported value type")
+
+func parseInt(s string, base int, k reflect.Kind) (int64, error) {
 	switch k {
 	case reflect.Int:
-		return strconv.ParseInt(s, 10, 0)
+		return strconv.ParseInt(s, base, 0)
 	case reflect.Int8:
-		return strconv.ParseInt(s, 10, 8)
+		return strconv.ParseInt(s, base, 8)
 	case reflect.Int16:
-		return strconv.ParseInt(s, 10, 16)
+		return strconv.ParseInt(s, base, 16)
 	case reflect.Int32:
-		return strconv.ParseInt(s, 10, 32)
+		return strconv.ParseInt(s, base, 32)
 	case reflect.Int64:
-		return strconv.ParseInt(s, 10, 64)
+		return strconv.ParseInt(s, base, 64)
 	default:
-		panic(fmt.Errorf("bug: unsupported integer types %v", k))
+		return 0, fmt.Errorf("%w: %v is not an integer type", ErrUnsupportedType, k)
 	}
 }
 
-func parseUint(s string, k reflect.Kind) (uint64, error) {
+func parseUint(s string, base int, k reflect.Kind) (uint64, error) {
 	switch k {
 	case reflect.Uint:
-		return strconv.ParseUint(s, 10, 0)
+		return strconv.ParseUint(s, base, 0)
 	case reflect.Uint8:
-		return strconv.ParseUint(s, 10, 8)
+		return strconv.ParseUint(s, base, 8)
 	case reflect.Uint16:
-		return strconv.ParseUint(s, 10, 16)
+		return strconv.ParseUint(s, base, 16)
 	case reflect.Uint32:
-		return strconv.ParseUint(s, 10, 32)
+		return strconv.ParseUint(s, base, 32)
 	case reflect.Uint64, reflect.Uintptr:
-		return strconv.ParseUint(s, 10, 64)
+		return strconv.ParseUint(s, base, 64)
 	default:
-		panic(fmt.Errorf("bug: unsupported unsigned integer types %v", k))
+		return 0, fmt.Errorf("%w: %v is not an unsigned integer type", ErrUnsupportedType, k)
 	}
 }
 
@@ -66,33 +80,295 @@ func parseFloat(s string, k reflect.Kind) (float64, error) {
 	case reflect.Float64:
 		return strconv.ParseFloat(s, 64)
 	default:
-		panic(fmt.Errorf("bug: unsupported float types %v", k))
+		return 0, fmt.Errorf("%w: %v is not a float type", ErrUnsupportedType, k)
+	}
+}
+
+func parseComplex(s string, k reflect.Kind) (complex128, error) {
+	switch k {
+	case reflect.Complex64:
+		return strconv.ParseComplex(s, 64)
+	case reflect.Complex128:
+		return strconv.ParseComplex(s, 128)
+	default:
+		return 0, fmt.Errorf("%w: %v is not a complex type", ErrUnsupportedType, k)
+	}
+}
+
+// ParseBool returns the boolean value represented by the string. In addition
+// to the forms accepted by strconv.ParseBool, it accepts "y", "yes", "on",
+// "enable" and "enabled" as true, and "n", "no", "off", "disable" and
+// "disabled" as false. The forms are case-insensitive.
+func ParseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "1", "t", "true", "y", "yes", "on", "enable", "enabled":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off", "disable", "disabled":
+		return false, nil
+	}
+	return false, &strconv.NumError{Func: "ParseBool", Num: s, Err: strconv.ErrSyntax}
+}
+
+var siSuffixes = map[byte]int64{
+	'k': 1e3, 'K': 1e3,
+	'M': 1e6,
+	'G': 1e9,
+	'T': 1e12,
+	'P': 1e15,
+	'E': 1e18,
+}
+
+// ParseSI parses the decimal number with an optional SI suffix k (or K), M, G,
+// T, P or E, and returns the scaled integer value. A fractional number is
+// allowed if the scaled value is an integer, such as "1.5k".
+func ParseSI(s string) (*big.Int, error) {
+	num, mul := s, int64(1)
+	if n := len(s); n > 0 {
+		if v, ok := siSuffixes[s[n-1]]; ok {
+			num, mul = s[:n-1], v
+		}
+	}
+
+	v, ok := new(big.Rat).SetString(num)
+	if !ok || num == "" || strings.ContainsAny(num, "/eE") {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	v.Mul(v, new(big.Rat).SetInt64(mul))
+	if !v.IsInt() {
+		return nil, fmt.Errorf("invalid number %q: not an integer", s)
+	}
+	return v.Num(), nil
+}
+
+// the default separator of the slice value
+const defaultSeparator = ","
+
+// splitValue splits s by the separators that are not escaped by backslash,
+// and unescapes the elements like the values of parseMap.
+func splitValue(s, sep string) []string {
+	list := splitUnescaped(s, sep)
+	for i, v := range list {
+		list[i] = unescape(unquote(strings.TrimSpace(v)))
+	}
+	return list
+}
+
+// unquote removes the double quotes enclosing s that keep the leading and
+// trailing spaces of the element from being trimmed.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && indexUnescaped(s[1:], `"`) == len(s)-2 {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func indexUnescaped(s, sep string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			// skip escaped character
+			i++
+		} else if strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+func splitUnescaped(s, sep string) []string {
+	var list []string
+	for {
+		i := indexUnescaped(s, sep)
+		if i == -1 {
+			return append(list, s)
+		}
+		list = append(list, s[:i])
+		s = s[i+len(sep):]
+	}
+}
+
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') == -1 {
+		return s
 	}
+
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// parseMap parses a list of key-value pairs separated by sep.
+// The separator and '=' can be escaped by backslash, and the keys and the
+// values enclosed in double quotes keep the leading and trailing spaces.
+func parseMap(s, sep string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, pair := range splitUnescaped(s, sep) {
+		i := indexUnescaped(pair, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid key-value pair %q", pair)
+		}
+		k := unescape(unquote(strings.TrimSpace(pair[:i])))
+		if k == "" {
+			return nil, fmt.Errorf("invalid key-value pair %q", pair)
+		}
+		m[k] = unescape(unquote(strings.TrimSpace(pair[i+1:])))
+	}
+	return m, nil
 }
 
 type ParseFunc func(iv interface{}, envName, envValue string) error
 
+// ParseContextFunc is like ParseFunc but receives the context passed to
+// ParseContext, e.g. to resolve the host name of the value by DNS.
+type ParseContextFunc func(ctx context.Context, iv interface{}, envName, envValue string) error
+
+// parser parses the environment variable value with the options.
+type parser struct {
+	// separator of the slice and map value
+	sep string
+	// use ParseBool instead of strconv.ParseBool
+	extendedBool bool
+	// base of the integer value
+	base int
+	// accept the SI suffixes for the integer value
+	siSuffix bool
+	// scale of the float value with the percent sign
+	percentScale float64
+	// use ParseDuration instead of time.ParseDuration
+	extendedDuration bool
+}
+
+var defaultParser = parser{
+	sep:  defaultSeparator,
+	base: 10,
+}
+
 func defaultParseFunc(iv interface{}, envName, envValue string) error {
+	return defaultParser.parse(iv, envName, envValue)
+}
+
+// SliceParseFunc returns the ParseFunc that splits the environment variable
+// value by sep to parse it into a slice or map value. The separator and the
+// backslash in the elements are escaped by backslash, and the elements
+// enclosed in double quotes keep the leading and trailing spaces.
+func SliceParseFunc(sep string) ParseFunc {
+	p := defaultParser
+	p.sep = sep
+	return p.parse
+}
+
+// ExtendedBoolParseFunc returns the ParseFunc that parses the bool values by
+// ParseBool that accepts the extended forms such as "yes" and "off".
+func ExtendedBoolParseFunc() ParseFunc {
+	p := defaultParser
+	p.extendedBool = true
+	return p.parse
+}
+
+// IntParseFunc returns the ParseFunc that parses the integer values in the
+// given base. If base is 0, the base is implied by the prefix of the value:
+// "0x" for 16, "0o" or "0" for 8, "0b" for 2, and 10 otherwise. In this case,
+// underscores are permitted as digit separators such as "1_000_000".
+func IntParseFunc(base int) ParseFunc {
+	p := defaultParser
+	p.base = base
+	return p.parse
+}
+
+// SIParseFunc returns the ParseFunc that parses the integer values with the
+// SI suffixes by ParseSI, such as "10k", "5M" and "1.5G".
+func SIParseFunc() ParseFunc {
+	p := defaultParser
+	p.siSuffix = true
+	return p.parse
+}
+
+// PercentParseFunc returns the ParseFunc that parses the float values with
+// the percent sign such as "75%". If ratio is true, "75%" will be parsed as
+// 0.75, otherwise as 75. The values without the percent sign are parsed as is.
+func PercentParseFunc(ratio bool) ParseFunc {
+	p := defaultParser
+	p.percentScale = 1
+	if ratio {
+		p.percentScale = 0.01
+	}
+	return p.parse
+}
+
+// ExtendedDurationParseFunc returns the ParseFunc that parses the
+// time.Duration values by ParseDuration that accepts the units "d" and "w".
+func ExtendedDurationParseFunc() ParseFunc {
+	p := defaultParser
+	p.extendedDuration = true
+	return p.parse
+}
+
+func (p parser) parse(iv interface{}, envName, envValue string) error {
 	ref := reflect.ValueOf(iv)
 	if ref.Kind() != reflect.Ptr {
-		return ErrValue
+		return fmt.Errorf("%w: %T is not a pointer", ErrValue, iv)
+	}
+	return p.setValue(reflect.Indirect(ref), envName, envValue)
+}
+
+// valueParser is implemented by the types that parse the environment variable
+// value by themselves with the parser options.
+type valueParser interface {
+	parseValue(p parser, envName, envValue string) error
+}
+
+func (p parser) setValue(ref reflect.Value, envName, envValue string) error {
+	if v, ok := ref.Addr().Interface().(valueParser); ok {
+		return v.parseValue(p, envName, envValue)
+	}
+
+	if p.extendedDuration && ref.Type() == durationType {
+		v, err := ParseDuration(envValue)
+		if err != nil {
+			return err
+		}
+		ref.SetInt(int64(v))
+		return nil
+	}
+
+	// types that need to be parsed by a dedicated parser
+	if fn, ok := typeParsers[ref.Type()]; ok {
+		return fn(ref, envValue)
+	} else if v, ok := ref.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return v.UnmarshalText([]byte(envValue))
+	} else if v, ok := ref.Addr().Interface().(flag.Value); ok {
+		return v.Set(envValue)
 	}
 
-	ref = reflect.Indirect(ref)
 	kind := ref.Kind()
 	switch kind {
 	case reflect.String:
 		ref.SetString(envValue)
 
 	case reflect.Bool:
-		v, err := strconv.ParseBool(envValue)
+		parseBool := strconv.ParseBool
+		if p.extendedBool {
+			parseBool = ParseBool
+		}
+		v, err := parseBool(envValue)
 		if err != nil {
 			return err
 		}
 		ref.SetBool(v)
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		v, err := parseInt(envValue, kind)
+		if p.siSuffix {
+			n, err := ParseSI(envValue)
+			if err != nil {
+				return err
+			}
+			envValue = n.String()
+		}
+		v, err := parseInt(envValue, p.base, kind)
 		if err != nil {
 			return err
 		}
@@ -100,21 +376,110 @@ func defaultParseFunc(iv interface{}, envName, envValue string) error {
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
 		reflect.Uint64, reflect.Uintptr:
-		v, err := parseUint(envValue, kind)
+		if p.siSuffix {
+			n, err := ParseSI(envValue)
+			if err != nil {
+				return err
+			}
+			envValue = n.String()
+		}
+		v, err := parseUint(envValue, p.base, kind)
 		if err != nil {
 			return err
 		}
 		ref.SetUint(v)
 
 	case reflect.Float32, reflect.Float64:
+		if p.percentScale != 0 && strings.HasSuffix(envValue, "%") {
+			v, err := parseFloat(strings.TrimSpace(envValue[:len(envValue)-1]), kind)
+			if err != nil {
+				return err
+			}
+			ref.SetFloat(v * p.percentScale)
+			break
+		}
 		v, err := parseFloat(envValue, kind)
 		if err != nil {
 			return err
 		}
 		ref.SetFloat(v)
 
+	case reflect.Complex64, reflect.Complex128:
+		v, err := parseComplex(envValue, kind)
+		if err != nil {
+			return err
+		}
+		ref.SetComplex(v)
+
+	case reflect.Slice:
+		t := ref.Type()
+		if envValue == "" {
+			ref.Set(reflect.MakeSlice(t, 0, 0))
+			break
+		}
+		list := splitValue(envValue, p.sep)
+		v := reflect.MakeSlice(t, len(list), len(list))
+		for i, s := range list {
+			if err := p.setValue(v.Index(i), envName, s); err != nil {
+				return fmt.Errorf("element %d of %s is not a valid %v: %w", i, envName, t.Elem(), err)
+			}
+		}
+		ref.Set(v)
+
+	case reflect.Map:
+		t := ref.Type()
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("%w: %v", ErrUnsupportedType, t)
+		} else if envValue == "" {
+			ref.Set(reflect.MakeMap(t))
+			break
+		}
+		m, err := parseMap(envValue, p.sep)
+		if err != nil {
+			return err
+		}
+		v := reflect.MakeMapWithSize(t, len(m))
+		for k, s := range m {
+			elm := reflect.New(t.Elem()).Elem()
+			if err := p.setValue(elm, envName, s); err != nil {
+				return fmt.Errorf("value of key %q in %s is not a valid %v: %w", k, envName, t.Elem(), err)
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elm)
+		}
+		ref.Set(v)
+
+	case reflect.Ptr:
+		// allocate a new value to distinguish it from the unset value
+		v := reflect.New(ref.Type().Elem())
+		if err := p.setValue(v.Elem(), envName, envValue); err != nil {
+			return err
+		}
+		ref.Set(v)
+
 	default:
-		panic(fmt.Errorf("bug: unsupported value types %v", kind))
+		// fallback to the other unmarshaling interfaces
+		switch v := ref.Addr().Interface().(type) {
+		case sql.Scanner:
+			// scan into a new value to keep the value on failure
+			nv := reflect.New(ref.Type())
+			if err := nv.Interface().(sql.Scanner).Scan(envValue); err != nil {
+				return err
+			}
+			ref.Set(nv.Elem())
+			return nil
+
+		case encoding.BinaryUnmarshaler:
+			b, err := decodeBase64(envValue)
+			if err != nil {
+				return err
+			}
+			return v.UnmarshalBinary(b)
+
+		case fmt.Scanner:
+			_, err := fmt.Sscan(envValue, v)
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrUnsupportedType, ref.Type())
 	}
 
 	return nil
@@ -122,11 +487,25 @@ func defaultParseFunc(iv interface{}, envName, envValue string) error {
 
 type CheckFunc func(iv interface{}, envName string) error
 
+// CheckContextFunc is like CheckFunc but receives the context passed to
+// ParseContext, e.g. to check that the value is reachable.
+type CheckContextFunc func(ctx context.Context, iv interface{}, envName string) error
+
 func defaultCheckFunc(iv interface{}, envName string) error {
 	// allow any value
 	return nil
 }
 
+// defaultCheck returns the CheckFunc used if the checker is not specified for
+// the variable of the default value defval. The Decimal value is checked by
+// ScaleCheckFunc with the scale of defval unless defval is the zero value.
+func defaultCheck(defval interface{}) CheckFunc {
+	if d, ok := defval.(Decimal); ok && d.unscaled != nil {
+		return ScaleCheckFunc(d.scale)
+	}
+	return defaultCheckFunc
+}
+
 var ErrName = fmt.Errorf("name must be non-empty printable ascii string and that must not contain spaces and '='")
 
 func checkName(s string) error {
@@ -153,25 +532,121 @@ func checkName(s string) error {
 	return nil
 }
 
-var ErrValue = fmt.Errorf("value must be non-nil pointer of following types: string, bool, uintptr, 8-64 bit int or uint and 32-64 bit float")
+// ErrValue is returned if the value is not a non-nil pointer to the supported
+// type: the basic kinds, the types that have the dedicated parser such as
+// time.Duration and big.Int, the types implementing encoding.TextUnmarshaler,
+// flag.Value, sql.Scanner, encoding.BinaryUnmarshaler or fmt.Scanner, the
+// pointers to them, and the slices and the map[string] of the basic kinds.
+// The unsupported type is reported in the wrapping error.
+var ErrValue = fmt.Errorf("value must be non-nil pointer of supported type")
 
-func checkValue(v interface{}) (interface{}, error) {
-	ref := reflect.ValueOf(v)
-	if ref.Kind() != reflect.Ptr {
-		return nil, ErrValue
+var (
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	scannerType           = reflect.TypeOf((*fmt.Scanner)(nil)).Elem()
+	flagValueType         = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	sqlScannerType        = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// elemTyper is implemented by the types that hold the value of the element
+// type, such as Optional, so that the element type is checked at the
+// registration.
+type elemTyper interface {
+	elemType() reflect.Type
+}
+
+// isScalarType returns true if t is a type that can be parsed from a single
+// value.
+func isScalarType(t reflect.Type) bool {
+	if _, ok := typeParsers[t]; ok {
+		return true
+	} else if et, ok := reflect.New(t).Interface().(elemTyper); ok {
+		return isValueType(et.elemType())
+	} else if pt := reflect.PtrTo(t); pt.Implements(textUnmarshalerType) ||
+		pt.Implements(flagValueType) {
+		return true
 	}
 
-	ref = reflect.Indirect(ref)
-	switch ref.Kind() {
+	switch t.Kind() {
 	case reflect.String, reflect.Bool,
 		reflect.Int, reflect.Uint, reflect.Uintptr,
 		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-		reflect.Float32, reflect.Float64:
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128:
+		return true
+
+	case reflect.Slice, reflect.Map, reflect.Ptr:
+		// parsed as the container of the values
+		return false
+	}
+
+	// fallback to the other unmarshaling interfaces
+	pt := reflect.PtrTo(t)
+	return pt.Implements(sqlScannerType) || pt.Implements(binaryUnmarshalerType) ||
+		pt.Implements(scannerType)
+}
+
+// isElemType returns true if t is a type that can be used as an element of
+// the slice value or a value of the map value.
+func isElemType(t reflect.Type) bool {
+	switch t {
+	case durationType, ipNetType, urlPtrType:
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Uint8:
+		// []byte is not a list of numbers
+		return false
+
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Uint, reflect.Uintptr,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128:
+		_, ok := typeParsers[t]
+		return !ok && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+	}
+	return false
+}
+
+// checkValue checks that v is a pointer to a supported type and returns the
+// value pointed to by v. If anyType is true, v can be a pointer to any type.
+func checkValue(v interface{}, anyType bool) (interface{}, error) {
+	ref := reflect.ValueOf(v)
+	if ref.Kind() != reflect.Ptr || ref.IsNil() {
+		return nil, fmt.Errorf("%w: %T is not a non-nil pointer", ErrValue, v)
+	}
+
+	ref = ref.Elem()
+	t := ref.Type()
+	if anyType || isValueType(t) {
 		return ref.Interface(), nil
 	}
+	return nil, fmt.Errorf("%w: %v", ErrValue, t)
+}
+
+// isValueType returns true if t is a type that can be parsed by the default
+// parser.
+func isValueType(t reflect.Type) bool {
+	if isScalarType(t) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		return isElemType(t.Elem())
+
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && isElemType(t.Elem())
 
-	return nil, ErrValue
+	case reflect.Ptr:
+		// pointer that is nil if the environment variable is not defined
+		return isScalarType(t.Elem())
+	}
+	return false
 }
 
 type Env struct {
@@ -182,75 +657,1022 @@ type Env struct {
 	Required     bool
 	Parse        ParseFunc
 	Check        CheckFunc
+	// parser and checker that receive the context, used instead of Parse
+	// and Check if set
+	ParseContext ParseContextFunc
+	CheckContext CheckContextFunc
+	// example value shown to the users
+	Example string
+	// use the value without trimming the leading and trailing spaces
+	NoTrim bool
+	// treat the empty value as set if the environment variable is defined
+	AllowEmpty bool
+	// read the value from the file specified by the variable of the name
+	// followed by FileSuffix
+	File bool
+	// the value is the secret that should be resolved from the secret store
+	Secret bool
+	// name of the Source that supplied the value by Parse, or empty if the
+	// value has not been supplied by any Source
+	Source string
+
+	// the value has been set from the environment variable by Parse
+	isSet bool
+	// separator of the slice and map value set by WithSeparator
+	sep string
+}
+
+// EnvSet is the set of the registered environment variables. The package
+// level functions such as Set and Parse operate on the default EnvSet, and
+// the libraries can use their own EnvSet to avoid the name collisions.
+type EnvSet struct {
+	// prefix of the names registered through the set
+	prefix            string
+	name2envs         map[string]*Env
+	prefix2collectors map[string]collector
+	// sources consulted by Parse in order
+	sources []Source
+	// decrypter of the encrypted values
+	decrypter Decrypter
+	// maximum number of the variables resolved concurrently by Parse
+	concurrency int
+	// Source of the process environment, or nil to look up the variables
+	// from the process environment at each Parse
+	env Source
+	// match the names ignoring the case
+	caseInsensitive bool
+	// syntaxes of the references expanded in the values
+	expansion Expansion
+	// marker of the values that are read from the files
+	fileMarker string
 }
 
-var name2envs = map[string]*Env{}
+// SetOption configures the EnvSet created by NewEnvSet.
+type SetOption func(s *EnvSet)
+
+// WithSnapshot makes the EnvSet read the snapshot of the process environment
+// captured by SnapshotEnvSource at the creation instead of the process
+// environment at each Parse, so that the repeated Parse calls produce the same
+// values even if the process environment is changed by os.Setenv.
+func WithSnapshot() SetOption {
+	return func(s *EnvSet) {
+		s.env = SnapshotEnvSource()
+	}
+}
+
+// WithLiveEnv makes the EnvSet read the process environment at each Parse,
+// which is the default.
+func WithLiveEnv() SetOption {
+	return func(s *EnvSet) {
+		s.env = nil
+	}
+}
+
+// NewEnvSet returns the empty EnvSet configured by opts.
+func NewEnvSet(opts ...SetOption) *EnvSet {
+	s := &EnvSet{
+		name2envs:         map[string]*Env{},
+		prefix2collectors: map[string]collector{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+var defaultSet = NewEnvSet()
+
+// WithPrefix returns the EnvSet that shares the variables with the default
+// EnvSet and registers the variables with the names prefixed by prefix.
+func WithPrefix(prefix string) *EnvSet {
+	return defaultSet.Sub(prefix)
+}
+
+// Sub returns the EnvSet that shares the variables with s and registers the
+// variables with the names prefixed by the prefix of s followed by prefix.
+// The Parse and Usage of the returned set operate only on the variables that
+// have the composed prefix.
+func (s *EnvSet) Sub(prefix string) *EnvSet {
+	return &EnvSet{
+		prefix:            s.prefix + prefix,
+		name2envs:         s.name2envs,
+		prefix2collectors: s.prefix2collectors,
+		sources:           s.sources,
+		decrypter:         s.decrypter,
+		concurrency:       s.concurrency,
+		env:               s.env,
+		caseInsensitive:   s.caseInsensitive,
+		expansion:         s.expansion,
+		fileMarker:        s.fileMarker,
+	}
+}
+
+// envs returns the variables that have the prefix of the set.
+func (s *EnvSet) envs() map[string]*Env {
+	if s.prefix == "" {
+		return s.name2envs
+	}
+	envs := map[string]*Env{}
+	for name, env := range s.name2envs {
+		if strings.HasPrefix(name, s.prefix) {
+			envs[name] = env
+		}
+	}
+	return envs
+}
+
+// collectors returns the collectors that have the prefix of the set.
+func (s *EnvSet) collectors() map[string]collector {
+	if s.prefix == "" {
+		return s.prefix2collectors
+	}
+	collectors := map[string]collector{}
+	for prefix, c := range s.prefix2collectors {
+		if strings.HasPrefix(prefix, s.prefix) {
+			collectors[prefix] = c
+		}
+	}
+	return collectors
+}
 
 var ErrNameAlready = fmt.Errorf("environment variable name is already registered")
 
+// Option configures the environment variable to be registered.
+type Option func(env *Env)
+
+// WithRequired makes the environment variable required.
+func WithRequired() Option {
+	return func(env *Env) {
+		env.Required = true
+	}
+}
+
+// WithParse sets the parser of the environment variable value. If parsefn is
+// specified, the value can be a pointer to any type that parsefn can handle.
+func WithParse(parsefn ParseFunc) Option {
+	return func(env *Env) {
+		env.Parse = parsefn
+	}
+}
+
+// WithCheck sets the checker of the parsed value.
+func WithCheck(checkfn CheckFunc) Option {
+	return func(env *Env) {
+		env.Check = checkfn
+	}
+}
+
+// WithParseContext is like WithParse but sets the parser that receives the
+// context passed to ParseContext. Parse passes context.Background().
+func WithParseContext(parsefn ParseContextFunc) Option {
+	return func(env *Env) {
+		env.ParseContext = parsefn
+	}
+}
+
+// WithCheckContext is like WithCheck but sets the checker that receives the
+// context passed to ParseContext. Parse passes context.Background().
+func WithCheckContext(checkfn CheckContextFunc) Option {
+	return func(env *Env) {
+		env.CheckContext = checkfn
+	}
+}
+
+// WithNoTrim makes the value used verbatim without trimming the leading and
+// trailing spaces. The value consisting only of spaces is treated as set.
+func WithNoTrim() Option {
+	return func(env *Env) {
+		env.NoTrim = true
+	}
+}
+
+// WithAllowEmpty makes the empty value valid if the environment variable is
+// defined. By default, the empty value is treated as unset.
+func WithAllowEmpty() Option {
+	return func(env *Env) {
+		env.AllowEmpty = true
+	}
+}
+
+// WithFile makes the value readable from the file specified by the variable
+// of the name followed by FileSuffix, e.g. the contents of the file
+// /run/secrets/db_password are used as the value of DB_PASSWORD if
+// DB_PASSWORD_FILE=/run/secrets/db_password is defined. The contents are
+// processed in the same way as the value of the variable. Parse returns
+// ErrFileConflict if both variables are defined.
+func WithFile() Option {
+	return func(env *Env) {
+		env.File = true
+	}
+}
+
+// WithSecret marks the value as the secret. The names of the secrets are
+// returned by SecretNames to be resolved from the secret stores.
+func WithSecret() Option {
+	return func(env *Env) {
+		env.Secret = true
+	}
+}
+
+// WithSeparator parses the slice and map value split by sep like
+// SliceParseFunc, and makes MarshalRegistered join the elements by sep so
+// that the rendered value can be parsed back.
+func WithSeparator(sep string) Option {
+	return func(env *Env) {
+		env.Parse = SliceParseFunc(sep)
+		env.sep = sep
+	}
+}
+
+// separator returns the separator of the slice and map value.
+func (env *Env) separator() string {
+	if env.sep == "" {
+		return defaultSeparator
+	}
+	return env.sep
+}
+
+// WithExample sets the example value of the environment variable.
+func WithExample(example string) Option {
+	return func(env *Env) {
+		env.Example = example
+	}
+}
+
 // Register environment variables to be read by the Parse function.
-// The parsefn and checkfn functions are used as value parser and value checker. If the function is nil, the default function will be used.
-func Set(name, desc string, value interface{}, required bool, parsefn ParseFunc, checkfn CheckFunc) error {
-	var defval interface{}
+// The opts configure the environment variable, such as WithRequired,
+// WithParse and WithCheck. If the parser or the checker is not specified, the
+// default function will be used.
+func Set(name, desc string, value interface{}, opts ...Option) error {
+	return defaultSet.Set(name, desc, value, opts...)
+}
+
+// Set registers the environment variable to the set like the Set function.
+func (s *EnvSet) Set(name, desc string, value interface{}, opts ...Option) error {
+	return s.set(s.prefix+name, desc, value, false, opts)
+}
+
+// MustSet is like Set but panics if the variable cannot be registered. It is
+// intended for the registrations in the package level var blocks and init
+// functions.
+func MustSet(name, desc string, value interface{}, opts ...Option) {
+	defaultSet.MustSet(name, desc, value, opts...)
+}
+
+// MustSet is like Set but panics if the variable cannot be registered.
+func (s *EnvSet) MustSet(name, desc string, value interface{}, opts ...Option) {
+	if err := s.Set(name, desc, value, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// Replace is like Set but overwrites the registered variable of the same name
+// instead of returning ErrNameAlready.
+func Replace(name, desc string, value interface{}, opts ...Option) error {
+	return defaultSet.Replace(name, desc, value, opts...)
+}
+
+// Replace is like Set but overwrites the registered variable of the same name.
+func (s *EnvSet) Replace(name, desc string, value interface{}, opts ...Option) error {
+	return s.set(s.prefix+name, desc, value, true, opts)
+}
+
+// set registers the environment variable with the name that is not prefixed
+// by the prefix of the set. If replace is true, the registered variable of the
+// same name is overwritten.
+func (s *EnvSet) set(name, desc string, value interface{}, replace bool, opts []Option) error {
+	env := &Env{
+		Name:        name,
+		Description: desc,
+		Value:       value,
+	}
+	for _, opt := range opts {
+		opt(env)
+	}
+
+	var err error
 	// check arguments
-	if err := checkName(name); err != nil {
+	if err = checkName(name); err != nil {
 		return err
-	} else if v, ok := name2envs[name]; ok && v != nil {
+	} else if v, ok := s.name2envs[name]; ok && v != nil && !replace {
 		return fmt.Errorf("%w: %q already registered", ErrNameAlready, name)
-	} else if defval, err = checkValue(value); err != nil {
+	} else if other := s.envName(name); other != name {
+		return fmt.Errorf("%w: %q already registered as %q", ErrNameAlready, name, other)
+	} else if env.DefaultValue, err = checkValue(value, env.Parse != nil || env.ParseContext != nil); err != nil {
 		return err
 	}
-	if parsefn == nil {
-		parsefn = defaultParseFunc
+	if env.Parse == nil {
+		env.Parse = defaultParseFunc
 	}
-	if checkfn == nil {
-		checkfn = defaultCheckFunc
+	if env.Check == nil {
+		env.Check = defaultCheck(env.DefaultValue)
 	}
 
 	// set env
-	name2envs[name] = &Env{
-		Name:         name,
-		Description:  desc,
-		DefaultValue: defval,
-		Value:        value,
-		Required:     required,
-		Parse:        parsefn,
-		Check:        checkfn,
+	s.name2envs[name] = env
+	return nil
+}
+
+// reset restores the value to the default value.
+func (env *Env) reset() {
+	ref := reflect.ValueOf(env.Value).Elem()
+	if env.DefaultValue == nil {
+		ref.Set(reflect.Zero(ref.Type()))
+		return
 	}
+	ref.Set(reflect.ValueOf(env.DefaultValue))
+}
 
-	return nil
+// Lookup returns the copy of the registered variable of name, and false if
+// it is not registered.
+func Lookup(name string) (*Env, bool) {
+	return defaultSet.Lookup(name)
 }
 
-type UsageFunc func(name, desc string, defval interface{}, required bool)
+// Lookup returns the copy of the registered variable of the set like the
+// Lookup function.
+func (s *EnvSet) Lookup(name string) (*Env, bool) {
+	env, ok := s.name2envs[s.envName(s.prefix+name)]
+	if !ok {
+		return nil, false
+	}
+	v := *env
+	return &v, true
+}
 
-func Usage(usagefn UsageFunc) {
-	names := make([]string, 0, len(name2envs))
-	for name := range name2envs {
+// Names returns the sorted names of the registered variables. The variables
+// collected by the prefix are not included.
+func Names() []string {
+	return defaultSet.Names()
+}
+
+// Names returns the sorted names of the registered variables of the set.
+func (s *EnvSet) Names() []string {
+	envs := s.envs()
+	names := make([]string, 0, len(envs))
+	for name := range envs {
 		names = append(names, name)
 	}
 	sort.Strings(names)
+	return names
+}
 
-	for _, name := range names {
-		env := name2envs[name]
-		usagefn(name, env.Description, env.DefaultValue, env.Required)
+// SecretNames returns the sorted names of the registered variables marked by
+// WithSecret.
+func SecretNames() []string {
+	return defaultSet.SecretNames()
+}
+
+// SecretNames returns the sorted names of the secrets of the set.
+func (s *EnvSet) SecretNames() []string {
+	var names []string
+	for _, name := range s.Names() {
+		if s.name2envs[name].Secret {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Len returns the number of the registered variables.
+func Len() int {
+	return defaultSet.Len()
+}
+
+// Len returns the number of the registered variables of the set.
+func (s *EnvSet) Len() int {
+	return len(s.envs())
+}
+
+// Has returns true if the variable of name is registered.
+func Has(name string) bool {
+	return defaultSet.Has(name)
+}
+
+// Has returns true if the variable of name is registered to the set.
+func (s *EnvSet) Has(name string) bool {
+	_, ok := s.name2envs[s.envName(s.prefix+name)]
+	return ok
+}
+
+// Visit calls fn for each variable that has been set from the environment
+// variable by Parse in the order of the names.
+func Visit(fn func(*Env)) {
+	defaultSet.Visit(fn)
+}
+
+// Visit calls fn for each variable of the set that has been set from the
+// environment variable by Parse in the order of the names.
+func (s *EnvSet) Visit(fn func(*Env)) {
+	for _, name := range s.Names() {
+		if env := s.name2envs[name]; env.isSet {
+			fn(env)
+		}
 	}
 }
 
+// VisitAll calls fn for each registered variable in the order of the names.
+func VisitAll(fn func(*Env)) {
+	defaultSet.VisitAll(fn)
+}
+
+// VisitAll calls fn for each variable of the set in the order of the names.
+func (s *EnvSet) VisitAll(fn func(*Env)) {
+	for _, name := range s.Names() {
+		fn(s.name2envs[name])
+	}
+}
+
+// Unset removes the registered variable or the variables collected by the
+// prefix of name, and returns true if it was registered. The value is not
+// changed.
+func Unset(name string) bool {
+	return defaultSet.Unset(name)
+}
+
+// Unset removes the registered variable of the set like the Unset function.
+func (s *EnvSet) Unset(name string) bool {
+	name = s.prefix + name
+	if envName := s.envName(name); s.name2envs[envName] != nil {
+		delete(s.name2envs, envName)
+		return true
+	} else if prefix := s.collectorPrefix(name); s.prefix2collectors[prefix] != nil {
+		delete(s.prefix2collectors, prefix)
+		return true
+	}
+	return false
+}
+
+// Reset removes all the registered variables after restoring their values to
+// the default values that were recorded at the registration.
+func Reset() {
+	defaultSet.Reset()
+}
+
+// Reset removes all the registered variables of the set like the Reset
+// function.
+func (s *EnvSet) Reset() {
+	for name, env := range s.envs() {
+		env.reset()
+		delete(s.name2envs, name)
+	}
+	for prefix, c := range s.collectors() {
+		c.reset()
+		delete(s.prefix2collectors, prefix)
+	}
+}
+
+type UsageFunc func(name, desc string, defval interface{}, required bool)
+
+func Usage(usagefn UsageFunc) {
+	defaultSet.Usage(usagefn)
+}
+
+// Usage calls usagefn for each variable of the set in the order of the names.
+func (s *EnvSet) Usage(usagefn UsageFunc) {
+	envs := s.envs()
+	entries := make([]usageEntry, 0, len(envs))
+	for _, env := range envs {
+		entries = append(entries, usageEntry{
+			name:     env.Name,
+			desc:     env.Description,
+			defval:   env.DefaultValue,
+			required: env.Required,
+		})
+	}
+	for _, c := range s.collectors() {
+		entries = append(entries, c.usage()...)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	for _, e := range entries {
+		usagefn(e.name, e.desc, e.defval, e.required)
+	}
+}
+
+// PrintUsage writes the usage of the variables to w in the format like the
+// flag.PrintDefaults function.
+func PrintUsage(w io.Writer) {
+	defaultSet.PrintUsage(w)
+}
+
+// PrintUsage writes the usage of the variables of the set to w.
+func (s *EnvSet) PrintUsage(w io.Writer) {
+	s.Usage(func(name, desc string, defval interface{}, required bool) {
+		line := "  " + name
+		if required {
+			line += " (required)"
+		}
+		line += "\n    \t" + strings.ReplaceAll(desc, "\n", "\n    \t")
+		if v := reflect.ValueOf(defval); v.IsValid() && !v.IsZero() {
+			line += fmt.Sprintf(" (default %v)", defval)
+		}
+		fmt.Fprintln(w, line)
+	})
+}
+
 var ErrEnvVar = fmt.Errorf("invalid environment variable")
 var ErrNotDefined = fmt.Errorf("required environment variable not defined")
 
-func Parse() error {
-	for name, env := range name2envs {
-		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
-			if err := env.Parse(env.Value, name, v); err != nil {
-				return fmt.Errorf("%w: %q %v", ErrEnvVar, name, err)
-			} else if err = env.Check(env.Value, name); err != nil {
-				return fmt.Errorf("%w: %q %v", ErrEnvVar, name, err)
+// notDefinedError returns the ErrNotDefined error that lists all the missing
+// variables with their descriptions.
+func notDefinedError(envs []*Env) error {
+	list := make([]string, 0, len(envs))
+	for _, env := range envs {
+		v := strconv.Quote(env.Name)
+		if env.Description != "" {
+			v += " (" + strings.Join(strings.Fields(env.Description), " ") + ")"
+		}
+		list = append(list, v)
+	}
+	return fmt.Errorf("%w: %s", ErrNotDefined, strings.Join(list, ", "))
+}
+
+// Parse reads the registered environment variables. It does not stop at the
+// first invalid variable, and returns the error that joins the errors of all
+// the invalid variables in the order of the names. The missing required
+// variables are reported at once by the first error with their descriptions.
+//
+// If names are specified, only the variables of the names, or the variables
+// collected by the prefixes of the names, are read. ErrNotRegistered is
+// returned if any of the names is not registered.
+func Parse(names ...string) error {
+	return defaultSet.Parse(names...)
+}
+
+// MustParse is like Parse but panics if an error occurs.
+func MustParse() {
+	defaultSet.MustParse()
+}
+
+// MustParse is like Parse but panics if an error occurs.
+func (s *EnvSet) MustParse() {
+	if err := s.Parse(); err != nil {
+		panic(err)
+	}
+}
+
+// exit is the function to terminate the process that can be replaced in the
+// tests.
+var exit = os.Exit
+
+// ParseExit parses the environment variables, and if an error occurs, writes
+// the error and the usage to w and exits with code like flag.ExitOnError.
+func ParseExit(w io.Writer, code int) {
+	defaultSet.ParseExit(w, code)
+}
+
+// ParseExit is like the ParseExit function but operates on the set.
+func (s *EnvSet) ParseExit(w io.Writer, code int) {
+	if err := s.Parse(); err != nil {
+		fmt.Fprintln(w, err)
+		fmt.Fprintln(w, "\nEnvironment variables:")
+		s.PrintUsage(w)
+		exit(code)
+	}
+}
+
+// stage parses v into the copy of the current value and checks it. The
+// returned function stores the parsed value in the registered value with the
+// name of the source that supplied v. The flag.Value is copied from the
+// default value instead, since its Set method may append v to the current
+// value such as the list of the values, and parsing it again would
+// accumulate the values.
+func (env *Env) stage(ctx context.Context, v, src string) (func(), error) {
+	ref := reflect.ValueOf(env.Value).Elem()
+	base := ref
+	if env.DefaultValue != nil && ref.Addr().Type().Implements(flagValueType) {
+		base = reflect.New(ref.Type()).Elem()
+		base.Set(reflect.ValueOf(env.DefaultValue))
+	}
+	staged := cloneValue(base)
+	if err := env.parse(ctx, staged.Interface(), v); err != nil {
+		return nil, fmt.Errorf("%w: %q %w", ErrEnvVar, env.Name, err)
+	} else if err = env.check(ctx, staged.Interface()); err != nil {
+		return nil, fmt.Errorf("%w: %q %w", ErrEnvVar, env.Name, err)
+	}
+	return func() {
+		ref.Set(staged.Elem())
+		env.isSet = true
+		env.Source = src
+	}, nil
+}
+
+// cloneValue returns the pointer to the copy of ref that shares no map, slice
+// or pointer with ref, so that parsing into the copy does not change ref or
+// the default value. The values of the types that have the dedicated parser
+// and the unexported fields are copied shallowly since parsing replaces them
+// as a whole. The cyclic references are kept cyclic in the copy, and the
+// pointers to ref point to the copy.
+func cloneValue(ref reflect.Value) reflect.Value {
+	p := reflect.New(ref.Type())
+	visited := map[cloneKey]reflect.Value{}
+	if ref.CanAddr() {
+		visited[cloneKey{ptr: ref.Addr().Pointer(), typ: p.Type()}] = p
+	}
+	p.Elem().Set(cloneValueOf(ref, visited))
+	return p
+}
+
+// cloneKey identifies the pointer, the map or the slice already copied.
+type cloneKey struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+func cloneValueOf(ref reflect.Value, visited map[cloneKey]reflect.Value) reflect.Value {
+	v := reflect.New(ref.Type()).Elem()
+	v.Set(ref)
+	if _, ok := typeParsers[ref.Type()]; ok {
+		return v
+	}
+
+	switch ref.Kind() {
+	case reflect.Ptr:
+		if !ref.IsNil() {
+			key := cloneKey{ptr: ref.Pointer(), typ: ref.Type()}
+			if p, ok := visited[key]; ok {
+				v.Set(p)
+				break
+			}
+			p := reflect.New(ref.Type().Elem())
+			visited[key] = p
+			p.Elem().Set(cloneValueOf(ref.Elem(), visited))
+			v.Set(p)
+		}
+
+	case reflect.Interface:
+		if !ref.IsNil() {
+			v.Set(cloneValueOf(ref.Elem(), visited))
+		}
+
+	case reflect.Slice:
+		if !ref.IsNil() {
+			key := cloneKey{ptr: ref.Pointer(), len: ref.Len(), typ: ref.Type()}
+			if list, ok := visited[key]; ok {
+				v.Set(list)
+				break
 			}
+			list := reflect.MakeSlice(ref.Type(), ref.Len(), ref.Cap())
+			visited[key] = list
+			for i := 0; i < ref.Len(); i++ {
+				list.Index(i).Set(cloneValueOf(ref.Index(i), visited))
+			}
+			v.Set(list)
+		}
+
+	case reflect.Array:
+		for i := 0; i < ref.Len(); i++ {
+			v.Index(i).Set(cloneValueOf(ref.Index(i), visited))
+		}
+
+	case reflect.Map:
+		if !ref.IsNil() {
+			key := cloneKey{ptr: ref.Pointer(), typ: ref.Type()}
+			if m, ok := visited[key]; ok {
+				v.Set(m)
+				break
+			}
+			m := reflect.MakeMapWithSize(ref.Type(), ref.Len())
+			visited[key] = m
+			iter := ref.MapRange()
+			for iter.Next() {
+				m.SetMapIndex(iter.Key(), cloneValueOf(iter.Value(), visited))
+			}
+			v.Set(m)
+		}
+
+	case reflect.Struct:
+		for i := 0; i < ref.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				f.Set(cloneValueOf(ref.Field(i), visited))
+			}
+		}
+	}
+	return v
+}
+
+// parse parses v into iv by ParseContext if set, or by Parse.
+func (env *Env) parse(ctx context.Context, iv interface{}, v string) error {
+	if env.ParseContext != nil {
+		return env.ParseContext(ctx, iv, env.Name, v)
+	}
+	return env.Parse(iv, env.Name, v)
+}
+
+// check checks iv by CheckContext if set, or by Check.
+func (env *Env) check(ctx context.Context, iv interface{}) error {
+	if env.CheckContext != nil {
+		return env.CheckContext(ctx, iv, env.Name)
+	}
+	return env.Check(iv, env.Name)
+}
+
+// ParseValue parses value as the value of the environment variable of the
+// registered name, without reading the process environment. The value is
+// processed in the same way as Parse, and is stored in the registered value
+// only if it is valid. ErrNotRegistered is returned if the name is not
+// registered.
+func ParseValue(name, value string) error {
+	return defaultSet.ParseValue(name, value)
+}
+
+// ParseValue parses value as the value of the variable of the set like the
+// ParseValue function.
+func (s *EnvSet) ParseValue(name, value string) error {
+	name = s.envName(s.prefix + name)
+	env, ok := s.name2envs[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNotRegistered, name)
+	}
+
+	if !env.NoTrim {
+		value = strings.TrimSpace(value)
+	}
+	if value == "" && !env.AllowEmpty {
+		// the empty value is treated as undefined
+		if env.Required {
+			return notDefinedError([]*Env{env})
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+	value, err := s.expand(ctx, s.caseLookuper(s.lookuper()), name, value)
+	if err != nil {
+		return err
+	} else if value, ok, err = s.indirect(name, value, env.NoTrim, env.AllowEmpty); err != nil {
+		return err
+	} else if !ok {
+		if env.Required {
+			return notDefinedError([]*Env{env})
+		}
+		return nil
+	} else if value, err = s.decrypt(name, value); err != nil {
+		return err
+	}
+	commit, err := env.stage(ctx, value, "")
+	if err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// ErrNotRegistered is returned by Parse if the specified variable is not
+// registered.
+var ErrNotRegistered = fmt.Errorf("environment variable not registered")
+
+// Parse reads the environment variables of the set like the Parse function.
+func (s *EnvSet) Parse(names ...string) error {
+	return s.ParseContext(context.Background(), names...)
+}
+
+// ParseContext is like Parse but passes ctx to the Sources that are the
+// ContextLookuper and to the parsers and the checkers set by
+// WithParseContext and WithCheckContext. It stops reading the variables and
+// returns the error of ctx if ctx is done, and no value is changed.
+func ParseContext(ctx context.Context, names ...string) error {
+	return defaultSet.ParseContext(ctx, names...)
+}
+
+// ParseContext reads the environment variables of the set like the
+// ParseContext function.
+func (s *EnvSet) ParseContext(ctx context.Context, names ...string) error {
+	return s.ParseFromContext(ctx, s.lookuper(), names...)
+}
+
+// ParseFrom is like Parse but reads the values from l instead of the process
+// environment. The variables collected by the prefix are read only if l is
+// the EnvironLookuper.
+func ParseFrom(l Lookuper, names ...string) error {
+	return defaultSet.ParseFrom(l, names...)
+}
+
+// ParseFrom reads the variables of the set from l like the ParseFrom
+// function.
+func (s *EnvSet) ParseFrom(l Lookuper, names ...string) error {
+	return s.ParseFromContext(context.Background(), l, names...)
+}
+
+// ParseFromContext is like ParseFrom but passes ctx like ParseContext.
+func ParseFromContext(ctx context.Context, l Lookuper, names ...string) error {
+	return defaultSet.ParseFromContext(ctx, l, names...)
+}
+
+// ParseFromContext reads the variables of the set from l like the
+// ParseFromContext function.
+func (s *EnvSet) ParseFromContext(ctx context.Context, l Lookuper, names ...string) error {
+	if len(names) == 0 {
+		return s.parse(ctx, l, s.Names(), s.collectorPrefixes())
+	}
+
+	var envNames, prefixes []string
+	var errs []error
+	for _, name := range names {
+		name = s.prefix + name
+		if envName := s.envName(name); s.name2envs[envName] != nil {
+			envNames = append(envNames, envName)
+		} else if prefix := s.collectorPrefix(name); s.prefix2collectors[prefix] != nil {
+			prefixes = append(prefixes, prefix)
+		} else {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrNotRegistered, name))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return s.parse(ctx, l, envNames, prefixes)
+}
+
+// ParseFiltered is like Parse but reads only the variables for which fn
+// returns true. The variables collected by the prefix are passed to fn as
+// the Env named the prefix.
+func ParseFiltered(fn func(*Env) bool) error {
+	return defaultSet.ParseFiltered(fn)
+}
+
+// ParseFiltered reads the variables of the set for which fn returns true.
+func (s *EnvSet) ParseFiltered(fn func(*Env) bool) error {
+	var names, prefixes []string
+	for _, name := range s.Names() {
+		if fn(s.name2envs[name]) {
+			names = append(names, name)
+		}
+	}
+	for _, prefix := range s.collectorPrefixes() {
+		if fn(s.prefix2collectors[prefix].env()) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return s.parse(context.Background(), s.lookuper(), names, prefixes)
+}
+
+// OnlyPrefix returns the filter for ParseFiltered that accepts the variables
+// whose names have any of the prefixes.
+func OnlyPrefix(prefixes ...string) func(*Env) bool {
+	return func(env *Env) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(env.Name, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SkipRequired returns the filter for ParseFiltered that accepts only the
+// optional variables.
+func SkipRequired() func(*Env) bool {
+	return func(env *Env) bool {
+		return !env.Required
+	}
+}
+
+// collectorPrefixes returns the sorted prefixes of the collectors of the set.
+func (s *EnvSet) collectorPrefixes() []string {
+	collectors := s.collectors()
+	prefixes := make([]string, 0, len(collectors))
+	for prefix := range collectors {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// SetConcurrency sets the maximum number of the variables resolved
+// concurrently by Parse, e.g. to look up dozens of the secrets from the remote
+// stores at once. The variables are resolved serially if n is less than 2,
+// which is the default. The Sources, the Decrypter, and the parsers and the
+// checkers of the variables must be safe for the concurrent use if n is
+// greater than 1. The lookups in progress are canceled as soon as any variable
+// fails to resolve like errgroup, and the errors that occurred until then are
+// reported in the same order as the serial resolution.
+func SetConcurrency(n int) {
+	defaultSet.SetConcurrency(n)
+}
+
+// SetConcurrency sets the concurrency of the set like the SetConcurrency
+// function. The sets returned by Sub after the call inherit the concurrency.
+func (s *EnvSet) SetConcurrency(n int) {
+	s.concurrency = n
+}
+
+// forEach calls fn for each index less than n by up to limit goroutines, and
+// waits for all the calls to return. fn is called serially in order if limit
+// is less than 2.
+func forEach(n, limit int, fn func(i int)) {
+	if limit < 2 || n < 2 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// resolved is the result of resolving the variable.
+type resolved struct {
+	commit  func()
+	missing bool
+	err     error
+}
+
+// resolve looks up the value of the variable from l and stages it.
+func (s *EnvSet) resolve(ctx context.Context, l Lookuper, env *Env) resolved {
+	if err := ctx.Err(); err != nil {
+		return resolved{err: err}
+	}
+	v, src, ok, err := lookupFileValue(ctx, l, env.Name, env.NoTrim, env.AllowEmpty, env.File)
+	if err != nil {
+		return resolved{err: err}
+	} else if !ok {
+		return resolved{missing: env.Required}
+	} else if v, err = s.expand(ctx, l, env.Name, v); err != nil {
+		return resolved{err: err}
+	} else if v, ok, err = s.indirect(env.Name, v, env.NoTrim, env.AllowEmpty); err != nil {
+		return resolved{err: err}
+	} else if !ok {
+		return resolved{missing: env.Required}
+	} else if v, err = s.decrypt(env.Name, v); err != nil {
+		return resolved{err: err}
+	}
+	commit, err := env.stage(ctx, v, src)
+	return resolved{commit: commit, err: err}
+}
+
+// parse reads the variables of names and the variables collected by the
+// prefixes from l. The parsed values are staged and stored in the registered values
+// only if all the variables are parsed successfully. It returns the error of
+// ctx as soon as ctx is done.
+func (s *EnvSet) parse(ctx context.Context, l Lookuper, names, prefixes []string) error {
+	l = s.caseLookuper(l)
+	results := make([]resolved, len(names))
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	forEach(len(names), s.concurrency, func(i int) {
+		if s.concurrency < 2 {
+			results[i] = s.resolve(ctx, l, s.name2envs[names[i]])
+		} else if results[i] = s.resolve(rctx, l, s.name2envs[names[i]]); results[i].err != nil {
+			// cancel the other lookups since the parse fails anyway
+			cancel()
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	var missing []*Env
+	var commits []func()
+	for i, r := range results {
+		if r.err != nil && rctx.Err() != nil && errors.Is(r.err, context.Canceled) {
+			// canceled by the failure of the other variable
 			continue
-		} else if env.Required {
-			return fmt.Errorf("%w: %q", ErrNotDefined, name)
+		} else if r.err != nil {
+			errs = append(errs, r.err)
+		} else if r.missing {
+			missing = append(missing, s.name2envs[names[i]])
+		} else if r.commit != nil {
+			commits = append(commits, r.commit)
 		}
 	}
+	if len(missing) > 0 {
+		errs = append([]error{notDefinedError(missing)}, errs...)
+	}
+
+	for _, prefix := range prefixes {
+		if commit, err := s.prefix2collectors[prefix].parse(ctx, l); err != nil {
+			errs = append(errs, err)
+		} else {
+			commits = append(commits, commit)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	} else if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	for _, commit := range commits {
+		commit()
+	}
 	return nil
 }
@@ -1,13 +1,18 @@
 package getenv
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math/big"
+	"net"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -34,9 +39,71 @@ func equalFuncs(t *testing.T, a interface{}, b interface{}) bool {
 	return assert.Equal(t, ap, bp)
 }
 
+type testText struct {
+	v string
+}
+
+func (t *testText) UnmarshalText(b []byte) error {
+	if len(b) == 0 || b[0] != '#' {
+		return fmt.Errorf("text must start with '#'")
+	}
+	t.v = string(b[1:])
+	return nil
+}
+
+type testBinary struct {
+	b []byte
+}
+
+func (t *testBinary) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 {
+		return fmt.Errorf("empty binary")
+	}
+	t.b = append([]byte{}, b...)
+	return nil
+}
+
+type testScanner struct {
+	x, y int
+}
+
+func (t *testScanner) Scan(state fmt.ScanState, verb rune) error {
+	_, err := fmt.Fscanf(state, "%d:%d", &t.x, &t.y)
+	return err
+}
+
+type testLevel int
+
+func (l *testLevel) String() string {
+	return strconv.Itoa(int(*l))
+}
+
+func (l *testLevel) Set(s string) error {
+	switch s {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", s)
+	}
+	return nil
+}
+
+type testList []string
+
+func (l *testList) String() string {
+	return strings.Join(*l, "|")
+}
+
+func (l *testList) Set(s string) error {
+	*l = append(*l, strings.Split(s, "|")...)
+	return nil
+}
+
 func TestSet(t *testing.T) {
 	defer func() {
-		name2envs = map[string]*Env{}
+		defaultSet = NewEnvSet()
 	}()
 
 	parsefn := func(iv interface{}, k, v string) error {
@@ -55,25 +122,43 @@ func TestSet(t *testing.T) {
 	uintv := uint(789)
 	f32v := float32(10.1)
 	f64v := float64(11.2)
+	c128v := complex128(1 + 2i)
+	durv := 3 * time.Second
+	txtv := testText{v: "text"}
+	ipnv := net.IPNet{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)}
+	intsv := []int{1, 2, 3}
+	binv := testBinary{}
+	scnv := testScanner{1, 2}
+	lvlv := testLevel(1)
+	lstv := testList{"x"}
 	for name, v := range map[string][]interface{}{
-		"STR":     {strv, &strv, nil, nil},
-		"BOL":     {bolv, &bolv, parsefn, checkfn},
-		"UINTPTR": {uipv, &uipv, nil, nil},
-		"INT":     {intv, &intv, parsefn, checkfn},
-		"UINT":    {uintv, &uintv, nil, nil},
-		"FLOAT32": {f32v, &f32v, parsefn, checkfn},
-		"FLOAT64": {f64v, &f64v, nil, nil},
+		"STR":      {strv, &strv, nil, nil},
+		"BOL":      {bolv, &bolv, parsefn, checkfn},
+		"UINTPTR":  {uipv, &uipv, nil, nil},
+		"INT":      {intv, &intv, parsefn, checkfn},
+		"UINT":     {uintv, &uintv, nil, nil},
+		"FLOAT32":  {f32v, &f32v, parsefn, checkfn},
+		"FLOAT64":  {f64v, &f64v, nil, nil},
+		"COMPLEX":  {c128v, &c128v, nil, nil},
+		"DURATION": {durv, &durv, parsefn, checkfn},
+		"TEXT":     {txtv, &txtv, nil, nil},
+		"IPNET":    {ipnv, &ipnv, nil, nil},
+		"INTS":     {intsv, &intsv, nil, nil},
+		"BINARY":   {binv, &binv, nil, nil},
+		"SCANNER":  {scnv, &scnv, nil, nil},
+		"LEVEL":    {lvlv, &lvlv, nil, nil},
+		"LIST":     {lstv, &lstv, nil, nil},
 	} {
 		desc := fmt.Sprintf("test %T env", v[0])
 		if fn, ok := v[2].(ParseFunc); ok {
-			assert.NoError(t, Set(name, desc, v[1], false, fn, v[3].(CheckFunc)))
+			assert.NoError(t, Set(name, desc, v[1], WithParse(fn), WithCheck(v[3].(CheckFunc))))
 		} else {
 			// use defaultParseFunc
-			assert.NoError(t, Set(name, desc, v[1], false, nil, nil))
+			assert.NoError(t, Set(name, desc, v[1]))
 			v[2] = defaultParseFunc
 		}
 		// confirm
-		env, ok := name2envs[name]
+		env, ok := defaultSet.name2envs[name]
 		assert.True(t, ok)
 		assert.Equal(t, name, env.Name)
 		assert.Equal(t, desc, env.Description)
@@ -86,43 +171,69 @@ func TestSet(t *testing.T) {
 	for _, name := range []string{
 		"", "0BAR", " BAR", "BAR ", "BAR-BAZ",
 	} {
-		assert.Equal(t, ErrName, Set(name, "", nil, false, nil, nil))
+		assert.Equal(t, ErrName, Set(name, "", nil))
 	}
 
 	// test that returns ErrValue
 	for _, v := range []interface{}{
-		strv, bolv, uipv, intv, uintv, f32v, f64v,
+		strv, bolv, uipv, intv, uintv, f32v, f64v, durv, txtv,
 		nil,
+		(*testText)(nil),
 		[]string{},
 		map[string]string{},
 		struct{}{},
-		&[]string{},
-		&map[string]string{},
+		&[]struct{}{},
+		&[][]string{},
+		new(*[]string),
+		new(**bool),
+		new(*struct{}),
+		&map[int]string{},
+		&map[string]struct{}{},
+		&map[string][]string{},
 		&struct{}{},
 	} {
-		assert.Equal(t, ErrValue, Set("BAR", "", v, false, nil, nil))
+		err := Set("BAR", "", v)
+		assert.True(t, errors.Is(err, ErrValue), "%T", v)
+		assert.Contains(t, err.Error(), strings.TrimPrefix(fmt.Sprintf("%T", v), "*"))
 	}
 
 	// test that returns error
 	for name, v := range map[string]interface{}{
-		"STR":     &strv,
-		"BOL":     &bolv,
-		"UINTPTR": &uipv,
-		"INT":     &intv,
-		"UINT":    &uintv,
-		"FLOAT32": &f32v,
-		"FLOAT64": &f64v,
+		"STR":      &strv,
+		"BOL":      &bolv,
+		"UINTPTR":  &uipv,
+		"INT":      &intv,
+		"UINT":     &uintv,
+		"FLOAT32":  &f32v,
+		"FLOAT64":  &f64v,
+		"DURATION": &durv,
 	} {
-		err := Set(name, "", v, false, nil, nil)
+		err := Set(name, "", v)
 		assert.Error(t, err)
 		assert.True(t, errors.Is(err, ErrNameAlready))
 		fmt.Printf("%v\n", err)
 	}
+
+	// test that the options configure the variable
+	assert.NoError(t, Set("OPTIONS", "", &strv, WithRequired(), WithParse(parsefn),
+		WithCheck(checkfn), WithExample("foo")))
+	env := defaultSet.name2envs["OPTIONS"]
+	assert.True(t, env.Required)
+	equalFuncs(t, parsefn, env.Parse)
+	equalFuncs(t, checkfn, env.Check)
+	assert.Equal(t, "foo", env.Example)
+
+	// test that the default functions are used if nil is specified
+	assert.NoError(t, Set("NIL_OPTIONS", "", &strv, WithParse(nil), WithCheck(nil)))
+	env = defaultSet.name2envs["NIL_OPTIONS"]
+	assert.False(t, env.Required)
+	equalFuncs(t, defaultParseFunc, env.Parse)
+	equalFuncs(t, defaultCheckFunc, env.Check)
 }
 
 func TestUsage(t *testing.T) {
 	defer func() {
-		name2envs = map[string]*Env{}
+		defaultSet = NewEnvSet()
 	}()
 
 	// setup
@@ -133,19 +244,21 @@ func TestUsage(t *testing.T) {
 	uintv := uint(789)
 	f32v := float32(10.1)
 	f64v := float64(11.2)
+	durv := 3 * time.Second
 	vals := map[string][]interface{}{
-		"STR":     {strv, &strv},
-		"BOL":     {bolv, &bolv},
-		"UINTPTR": {uipv, &uipv},
-		"INT":     {intv, &intv},
-		"UINT":    {uintv, &uintv},
-		"FLOAT32": {f32v, &f32v},
-		"FLOAT64": {f64v, &f64v},
+		"STR":      {strv, &strv},
+		"BOL":      {bolv, &bolv},
+		"UINTPTR":  {uipv, &uipv},
+		"INT":      {intv, &intv},
+		"UINT":     {uintv, &uintv},
+		"FLOAT32":  {f32v, &f32v},
+		"FLOAT64":  {f64v, &f64v},
+		"DURATION": {durv, &durv},
 	}
 	names := make([]string, 0, len(vals))
 	for name, v := range vals {
 		desc := fmt.Sprintf("test %q env", name)
-		assert.NoError(t, Set(name, desc, v[1], true, nil, nil))
+		assert.NoError(t, Set(name, desc, v[1], WithRequired()))
 		names = append(names, name)
 	}
 	sort.Strings(names)
@@ -162,7 +275,7 @@ func TestUsage(t *testing.T) {
 
 func TestParse(t *testing.T) {
 	defer func() {
-		name2envs = map[string]*Env{}
+		defaultSet = NewEnvSet()
 	}()
 
 	// setup
@@ -188,19 +301,23 @@ func TestParse(t *testing.T) {
 	uintv := uint(789)
 	f32v := float32(10.1)
 	f64v := float64(11.2)
+	c128v := complex128(1 + 2i)
+	durv := 3 * time.Second
 	vals := map[string][]interface{}{
-		"STR" + suffix:     {strv, &strv, "env string"},
-		"BOL" + suffix:     {bolv, &bolv, false},
-		"UINTPTR" + suffix: {uipv, &uipv, uintptr(321)},
-		"INT" + suffix:     {intv, &intv, int(654)},
-		"UINT" + suffix:    {uintv, &uintv, uint(987)},
-		"FLOAT32" + suffix: {f32v, &f32v, float32(1.01)},
-		"FLOAT64" + suffix: {f64v, &f64v, float64(2.11)},
+		"STR" + suffix:      {strv, &strv, "env string"},
+		"BOL" + suffix:      {bolv, &bolv, false},
+		"UINTPTR" + suffix:  {uipv, &uipv, uintptr(321)},
+		"INT" + suffix:      {intv, &intv, int(654)},
+		"UINT" + suffix:     {uintv, &uintv, uint(987)},
+		"FLOAT32" + suffix:  {f32v, &f32v, float32(1.01)},
+		"FLOAT64" + suffix:  {f64v, &f64v, float64(2.11)},
+		"COMPLEX" + suffix:  {c128v, &c128v, complex128(-3.5 + 4i)},
+		"DURATION" + suffix: {durv, &durv, 90 * time.Minute},
 	}
 	envnames := make([]string, 0, len(vals))
 	for name, v := range vals {
 		envnames = append(envnames, name)
-		assert.NoError(t, Set(name, "", v[1], false, parsefn, checkfn))
+		assert.NoError(t, Set(name, "", v[1], WithParse(parsefn), WithCheck(checkfn)))
 	}
 	defer func() {
 		for _, name := range envnames {
@@ -224,48 +341,53 @@ func TestParse(t *testing.T) {
 		nCallParseFn = 0
 		assert.Equal(t, n, nCallCheckFn)
 		nCallCheckFn = 0
-		env := name2envs[name]
+		env := defaultSet.name2envs[name]
 		assert.Equal(t, env.DefaultValue, v[0])
 	}
 
-	// test that stops parsing and returns error when the parser returns an error
+	// test that parses all variables and returns the joined errors when the
+	// parser returns an error
 	parseErr = fmt.Errorf("custom parse error")
 	err := Parse()
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "custom parse error")
-	assert.Equal(t, 1, nCallParseFn)
+	assert.Equal(t, len(vals), strings.Count(err.Error(), "custom parse error"))
+	for _, name := range envnames {
+		assert.Contains(t, err.Error(), fmt.Sprintf("%q", name))
+	}
+	assert.Equal(t, len(vals), nCallParseFn)
 	assert.Equal(t, 0, nCallCheckFn)
 
-	// test that stops parsing and returns error when the checker returns an error
+	// test that checks all variables and returns the joined errors when the
+	// checker returns an error
 	parseErr = nil
 	nCallParseFn = 0
 	checkErr = fmt.Errorf("custom check error")
 	err = Parse()
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "custom check error")
-	assert.Equal(t, 1, nCallParseFn)
-	assert.Equal(t, 1, nCallCheckFn)
+	assert.Equal(t, len(vals), strings.Count(err.Error(), "custom check error"))
+	assert.Equal(t, len(vals), nCallParseFn)
+	assert.Equal(t, len(vals), nCallCheckFn)
 
 	// test that use defaultParseFunc if parser is not defined
-	name2envs = map[string]*Env{}
+	defaultSet = NewEnvSet()
 	nCallParseFn = 0
 	nCallCheckFn = 0
 	checkErr = nil
 	for name, v := range vals {
 		envnames = append(envnames, name)
-		assert.NoError(t, Set(name, "", v[1], false, nil, checkfn))
+		assert.NoError(t, Set(name, "", v[1], WithCheck(checkfn)))
 	}
 	assert.NoError(t, Parse())
 	assert.Equal(t, 0, nCallParseFn)
 	assert.Equal(t, len(vals), nCallCheckFn)
 
 	// test that use defaultCheckFunc if checker is not defined
-	name2envs = map[string]*Env{}
+	defaultSet = NewEnvSet()
 	nCallParseFn = 0
 	nCallCheckFn = 0
 	for name, v := range vals {
 		envnames = append(envnames, name)
-		assert.NoError(t, Set(name, "", v[1], false, parsefn, nil))
+		assert.NoError(t, Set(name, "", v[1], WithParse(parsefn)))
 	}
 	assert.NoError(t, Parse())
 	assert.Equal(t, len(vals), nCallParseFn)
@@ -277,14 +399,14 @@ func TestParse(t *testing.T) {
 	}
 
 	// test that returns ErrEnvVar if cannot convert environment variable to actual value
-	name2envs = map[string]*Env{}
+	defaultSet = NewEnvSet()
 	envname := ""
 	envval := "{{unparsable env value}}"
 	for name, v := range vals {
 		if !strings.HasPrefix(name, "STR") {
 			envname = name
 			os.Setenv(name, envval)
-			assert.NoError(t, Set(name, "", v[1], false, nil, nil))
+			assert.NoError(t, Set(name, "", v[1]))
 			break
 		}
 	}
@@ -295,11 +417,11 @@ func TestParse(t *testing.T) {
 	assert.Contains(t, err.Error(), envval)
 
 	// test that returns ErrNotDefined if environment variable is not defined
-	name2envs = map[string]*Env{}
+	defaultSet = NewEnvSet()
 	for name, v := range vals {
 		if !strings.HasPrefix(name, "STR") {
 			os.Unsetenv(name)
-			assert.NoError(t, Set(name, "", v[1], true, nil, nil))
+			assert.NoError(t, Set(name, "", v[1], WithRequired()))
 			break
 		}
 	}
@@ -307,3 +429,1367 @@ func TestParse(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, ErrNotDefined))
 }
+
+func TestDefaultParseFunc(t *testing.T) {
+	// test that parse complex number
+	var c64v complex64
+	assert.NoError(t, defaultParseFunc(&c64v, "COMPLEX64", "1.5+2i"))
+	assert.Equal(t, complex64(1.5+2i), c64v)
+	var c128v complex128
+	assert.NoError(t, defaultParseFunc(&c128v, "COMPLEX128", "(-3e2-0.5i)"))
+	assert.Equal(t, complex128(-3e2-0.5i), c128v)
+	assert.Error(t, defaultParseFunc(&c128v, "COMPLEX128", "1+2j"))
+	assert.Equal(t, complex128(-3e2-0.5i), c128v)
+
+	// test that use UnmarshalText method if the value implements encoding.TextUnmarshaler
+	var txtv testText
+	assert.NoError(t, defaultParseFunc(&txtv, "TEXT", "#hello"))
+	assert.Equal(t, "hello", txtv.v)
+	assert.Error(t, defaultParseFunc(&txtv, "TEXT", "hello"))
+
+	// test that use Set method if the value implements flag.Value
+	var lvlv testLevel
+	assert.NoError(t, defaultParseFunc(&lvlv, "LEVEL", "high"))
+	assert.Equal(t, testLevel(2), lvlv)
+	assert.Error(t, defaultParseFunc(&lvlv, "LEVEL", "2"))
+	assert.Equal(t, testLevel(2), lvlv)
+	var lstv testList
+	assert.NoError(t, defaultParseFunc(&lstv, "LIST", "a|b,c"))
+	assert.Equal(t, testList{"a", "b,c"}, lstv)
+
+	// test that use UnmarshalBinary method with base64 decoded value
+	var binv testBinary
+	assert.NoError(t, defaultParseFunc(&binv, "BINARY", "AQID"))
+	assert.Equal(t, []byte{1, 2, 3}, binv.b)
+	assert.Error(t, defaultParseFunc(&binv, "BINARY", "!!"))
+	assert.Equal(t, []byte{1, 2, 3}, binv.b)
+
+	// test that use Scan method if the value implements fmt.Scanner
+	var scnv testScanner
+	assert.NoError(t, defaultParseFunc(&scnv, "SCANNER", "12:34"))
+	assert.Equal(t, testScanner{12, 34}, scnv)
+	assert.Error(t, defaultParseFunc(&scnv, "SCANNER", "12-34"))
+
+	// test that split string by comma and trim spaces of each element
+	var strs []string
+	assert.NoError(t, defaultParseFunc(&strs, "STRS", "foo, bar ,,baz"))
+	assert.Equal(t, []string{"foo", "bar", "", "baz"}, strs)
+
+	// test that separator and backslash can be escaped by backslash
+	assert.NoError(t, defaultParseFunc(&strs, "STRS", `a\,b,c\\d`))
+	assert.Equal(t, []string{"a,b", `c\d`}, strs)
+
+	// test that the elements enclosed in double quotes keep the spaces
+	assert.NoError(t, defaultParseFunc(&strs, "STRS", `" a ", " b\"c\, ",\"d","e`))
+	assert.Equal(t, []string{" a ", ` b"c, `, `"d"`, `"e`}, strs)
+
+	// test that parse each element of numeric slice
+	var ints []int
+	assert.NoError(t, defaultParseFunc(&ints, "INTS", "1, -2,3"))
+	assert.Equal(t, []int{1, -2, 3}, ints)
+	var i64s []int64
+	assert.NoError(t, defaultParseFunc(&i64s, "INT64S", "9223372036854775807"))
+	assert.Equal(t, []int64{9223372036854775807}, i64s)
+	var u16s []uint16
+	assert.NoError(t, defaultParseFunc(&u16s, "PORTS", "80,443,8080"))
+	assert.Equal(t, []uint16{80, 443, 8080}, u16s)
+	var f64s []float64
+	assert.NoError(t, defaultParseFunc(&f64s, "FLOAT64S", "0.5, 0.9,0.99"))
+	assert.Equal(t, []float64{0.5, 0.9, 0.99}, f64s)
+
+	// test that returns error with the index of the invalid element
+	err := defaultParseFunc(&ints, "INTS", "1,2,3,x")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "element 3 of INTS is not a valid int")
+	assert.Equal(t, []int{1, -2, 3}, ints)
+	err = defaultParseFunc(&u16s, "PORTS", "80,65536")
+	assert.Contains(t, err.Error(), "element 1 of PORTS is not a valid uint16")
+	err = defaultParseFunc(&f64s, "FLOAT64S", "0.5,,0.99")
+	assert.Contains(t, err.Error(), "element 1 of FLOAT64S is not a valid float64")
+
+	// test that parse key-value pairs separated by comma
+	var m map[string]string
+	assert.NoError(t, defaultParseFunc(&m, "MAP", `a=1, b = 2 ,c=,d=x=y`))
+	assert.Equal(t, map[string]string{"a": "1", "b": "2", "c": "", "d": "x=y"}, m)
+
+	// test that separator and equal sign can be escaped by backslash
+	assert.NoError(t, defaultParseFunc(&m, "MAP", `a\=b=1\,2,c\\=3`))
+	assert.Equal(t, map[string]string{"a=b": "1,2", `c\`: "3"}, m)
+
+	// test that parse each value of typed map
+	var durs map[string]time.Duration
+	assert.NoError(t, defaultParseFunc(&durs, "TIMEOUTS", "search=2s,auth=500ms"))
+	assert.Equal(t, map[string]time.Duration{"search": 2 * time.Second, "auth": 500 * time.Millisecond}, durs)
+	var weights map[string]int
+	assert.NoError(t, defaultParseFunc(&weights, "WEIGHTS", "a=1,b=-2"))
+	assert.Equal(t, map[string]int{"a": 1, "b": -2}, weights)
+	var bools map[string]bool
+	assert.NoError(t, defaultParseFunc(&bools, "FEATURES", "x=true,y=0"))
+	assert.Equal(t, map[string]bool{"x": true, "y": false}, bools)
+
+	// test that returns error with the key of the invalid value
+	err = defaultParseFunc(&durs, "TIMEOUTS", "search=2s,auth=500")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `value of key "auth" in TIMEOUTS is not a valid time.Duration`)
+	assert.Equal(t, map[string]time.Duration{"search": 2 * time.Second, "auth": 500 * time.Millisecond}, durs)
+
+	// test that returns error if the pair is invalid
+	for _, v := range []string{"a", "a=1,b", "=1", "a=1,"} {
+		assert.Error(t, defaultParseFunc(&m, "MAP", v))
+	}
+	assert.Equal(t, map[string]string{"a=b": "1,2", `c\`: "3"}, m)
+}
+
+func TestSliceParseFunc(t *testing.T) {
+	// test that split string by specified separator
+	strs := []string{"default"}
+	parsefn := SliceParseFunc(";")
+	assert.NoError(t, parsefn(&strs, "STRS", "foo; bar,baz"))
+	assert.Equal(t, []string{"foo", "bar,baz"}, strs)
+
+	// test that split key-value pairs by specified separator
+	var m map[string]string
+	assert.NoError(t, parsefn(&m, "MAP", "a=1;b=2,3"))
+	assert.Equal(t, map[string]string{"a": "1", "b": "2,3"}, m)
+}
+
+func TestParseBool(t *testing.T) {
+	// test that parse extended forms case-insensitively
+	for _, s := range []string{
+		"1", "t", "T", "true", "TRUE", "y", "Yes", "on", "ON", "enable", "Enabled",
+	} {
+		v, err := ParseBool(s)
+		assert.NoError(t, err, s)
+		assert.True(t, v, s)
+	}
+	for _, s := range []string{
+		"0", "f", "F", "false", "False", "n", "NO", "off", "Off", "disable", "DISABLED",
+	} {
+		v, err := ParseBool(s)
+		assert.NoError(t, err, s)
+		assert.False(t, v, s)
+	}
+
+	// test that returns error if the value is unknown
+	for _, s := range []string{"", "2", "yep", "nope", "o"} {
+		_, err := ParseBool(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestExtendedBoolParseFunc(t *testing.T) {
+	parsefn := ExtendedBoolParseFunc()
+
+	// test that parse extended bool forms
+	var v bool
+	assert.NoError(t, parsefn(&v, "BOOL", "yes"))
+	assert.True(t, v)
+	assert.NoError(t, parsefn(&v, "BOOL", "disabled"))
+	assert.False(t, v)
+	var m map[string]bool
+	assert.NoError(t, parsefn(&m, "FEATURES", "x=on,y=off"))
+	assert.Equal(t, map[string]bool{"x": true, "y": false}, m)
+
+	// test that defaultParseFunc keeps strict mode
+	assert.Error(t, defaultParseFunc(&v, "BOOL", "yes"))
+	assert.False(t, v)
+}
+
+func TestTriStateBool(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_FEATURE_X")
+	}()
+
+	// test that the value keeps nil if the environment variable is not defined
+	var v *bool
+	assert.NoError(t, Set("TEST_FEATURE_X", "", &v))
+	assert.NoError(t, Parse())
+	assert.Nil(t, v)
+
+	// test that allocate the value if the environment variable is defined
+	os.Setenv("TEST_FEATURE_X", "false")
+	assert.NoError(t, Parse())
+	if assert.NotNil(t, v) {
+		assert.False(t, *v)
+	}
+	os.Setenv("TEST_FEATURE_X", "true")
+	assert.NoError(t, Parse())
+	if assert.NotNil(t, v) {
+		assert.True(t, *v)
+	}
+
+	// test that returns error if the value is not a bool
+	prev := v
+	os.Setenv("TEST_FEATURE_X", "maybe")
+	assert.True(t, errors.Is(Parse(), ErrEnvVar))
+	assert.Equal(t, prev, v)
+}
+
+func TestOptionalPointer(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_OPT_STR")
+		os.Unsetenv("TEST_OPT_INT")
+		os.Unsetenv("TEST_OPT_DURATION")
+	}()
+
+	// test that the values keep nil if the environment variables are not defined
+	var strv *string
+	var intv *int
+	var durv *time.Duration
+	assert.NoError(t, Set("TEST_OPT_STR", "", &strv))
+	assert.NoError(t, Set("TEST_OPT_INT", "", &intv))
+	assert.NoError(t, Set("TEST_OPT_DURATION", "", &durv))
+	assert.NoError(t, Parse())
+	assert.Nil(t, strv)
+	assert.Nil(t, intv)
+	assert.Nil(t, durv)
+
+	// test that allocate the values if the environment variables are defined
+	os.Setenv("TEST_OPT_STR", "foo")
+	os.Setenv("TEST_OPT_INT", "0")
+	os.Setenv("TEST_OPT_DURATION", "1s")
+	assert.NoError(t, Parse())
+	if assert.NotNil(t, strv) {
+		assert.Equal(t, "foo", *strv)
+	}
+	if assert.NotNil(t, intv) {
+		assert.Equal(t, 0, *intv)
+	}
+	if assert.NotNil(t, durv) {
+		assert.Equal(t, time.Second, *durv)
+	}
+
+	// test that keep the value if the value cannot be parsed
+	intv = nil
+	assert.Error(t, defaultParseFunc(&intv, "TEST_OPT_INT", "foo"))
+	assert.Nil(t, intv)
+}
+
+func TestIntParseFunc(t *testing.T) {
+	// test that parse integers with base prefixes and underscores
+	parsefn := IntParseFunc(0)
+	var i int
+	for s, exp := range map[string]int{
+		"0xFF":      255,
+		"0o750":     0750,
+		"0750":      0750,
+		"0b1010":    10,
+		"1_000_000": 1000000,
+		"-0x10":     -16,
+		"42":        42,
+	} {
+		assert.NoError(t, parsefn(&i, "INT", s), s)
+		assert.Equal(t, exp, i, s)
+	}
+	var u8 uint8
+	assert.NoError(t, parsefn(&u8, "UINT8", "0xff"))
+	assert.Equal(t, uint8(255), u8)
+	assert.Error(t, parsefn(&u8, "UINT8", "0x100"))
+	var masks []uint32
+	assert.NoError(t, parsefn(&masks, "MASKS", "0xff00, 0b11"))
+	assert.Equal(t, []uint32{0xff00, 3}, masks)
+
+	// test that parse integers in the specified base
+	parsefn = IntParseFunc(16)
+	assert.NoError(t, parsefn(&i, "INT", "ff"))
+	assert.Equal(t, 255, i)
+	assert.Error(t, parsefn(&i, "INT", "0xff"))
+
+	// test that defaultParseFunc parses integers in base 10
+	assert.NoError(t, defaultParseFunc(&i, "INT", "0750"))
+	assert.Equal(t, 750, i)
+	assert.Error(t, defaultParseFunc(&i, "INT", "0xff"))
+	assert.Error(t, defaultParseFunc(&i, "INT", "1_000"))
+}
+
+func TestParseSI(t *testing.T) {
+	// test that parse numbers with SI suffixes
+	for s, exp := range map[string]string{
+		"100":   "100",
+		"10k":   "10000",
+		"10K":   "10000",
+		"5M":    "5000000",
+		"3G":    "3000000000",
+		"2T":    "2000000000000",
+		"1P":    "1000000000000000",
+		"9E":    "9000000000000000000",
+		"1.5k":  "1500",
+		"-2.5M": "-2500000",
+	} {
+		v, err := ParseSI(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, exp, v.String(), s)
+	}
+
+	// test that returns error if the value is invalid
+	for _, s := range []string{"", "k", "1.5", "1.0001k", "10m", "1/2k", "1e3", "10 k"} {
+		_, err := ParseSI(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSIParseFunc(t *testing.T) {
+	parsefn := SIParseFunc()
+
+	// test that scale the integer values
+	var i64 int64
+	assert.NoError(t, parsefn(&i64, "MAX_EVENTS", "5M"))
+	assert.Equal(t, int64(5000000), i64)
+	var u32s []uint32
+	assert.NoError(t, parsefn(&u32s, "LIMITS", "10k,1.5k,100"))
+	assert.Equal(t, []uint32{10000, 1500, 100}, u32s)
+
+	// test that returns error if the value overflows
+	var i16 int16
+	assert.Error(t, parsefn(&i16, "DEPTH", "1M"))
+	var u8 uint8
+	assert.Error(t, parsefn(&u8, "DEPTH", "-1k"))
+	assert.Error(t, parsefn(&i64, "MAX_EVENTS", "10E"))
+	assert.Equal(t, int64(5000000), i64)
+}
+
+func TestPercentParseFunc(t *testing.T) {
+	// test that parse percentage into ratio
+	parsefn := PercentParseFunc(true)
+	var f64 float64
+	assert.NoError(t, parsefn(&f64, "SAMPLING_RATE", "75%"))
+	assert.Equal(t, 0.75, f64)
+	assert.NoError(t, parsefn(&f64, "SAMPLING_RATE", "0.5"))
+	assert.Equal(t, 0.5, f64)
+	var f32s []float32
+	assert.NoError(t, parsefn(&f32s, "PERCENTILES", "50%, 99 %"))
+	assert.Equal(t, []float32{0.5, 0.99}, f32s)
+
+	// test that parse percentage into percent value
+	parsefn = PercentParseFunc(false)
+	assert.NoError(t, parsefn(&f64, "CPU_THRESHOLD", "80%"))
+	assert.Equal(t, float64(80), f64)
+
+	// test that returns error if the value is invalid
+	assert.Error(t, parsefn(&f64, "CPU_THRESHOLD", "%"))
+	assert.Error(t, parsefn(&f64, "CPU_THRESHOLD", "80%%"))
+	assert.Equal(t, float64(80), f64)
+
+	// test that defaultParseFunc does not accept the percent sign
+	assert.Error(t, defaultParseFunc(&f64, "CPU_THRESHOLD", "80%"))
+}
+
+func TestEnvSet(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_SET_PORT")
+	}()
+
+	// test that the sets are isolated from each other
+	set1 := NewEnvSet()
+	set2 := NewEnvSet()
+	port1 := 1
+	port2 := 2
+	port3 := 3
+	assert.NoError(t, set1.Set("TEST_SET_PORT", "set1", &port1))
+	assert.NoError(t, set2.Set("TEST_SET_PORT", "set2", &port2, WithRequired()))
+	assert.NoError(t, Set("TEST_SET_PORT", "default", &port3))
+	assert.True(t, errors.Is(set1.Set("TEST_SET_PORT", "", &port1), ErrNameAlready))
+	set1.Usage(func(name, desc string, defval interface{}, required bool) {
+		assert.Equal(t, "set1", desc)
+		assert.False(t, required)
+	})
+	set2.Usage(func(name, desc string, defval interface{}, required bool) {
+		assert.Equal(t, "set2", desc)
+		assert.True(t, required)
+	})
+
+	// test that Parse reads only the variables of the set
+	os.Setenv("TEST_SET_PORT", "8080")
+	assert.NoError(t, set1.Parse())
+	assert.Equal(t, 8080, port1)
+	assert.Equal(t, 2, port2)
+	assert.Equal(t, 3, port3)
+	assert.NoError(t, Parse())
+	assert.Equal(t, 8080, port3)
+	os.Unsetenv("TEST_SET_PORT")
+	assert.True(t, errors.Is(set2.Parse(), ErrNotDefined))
+	assert.NoError(t, set1.Parse())
+
+	// test that the other registration functions operate on the set
+	var cfg struct {
+		Host string `env:"TEST_SET_HOST"`
+	}
+	var mode string
+	var level int
+	headers := map[string]string{}
+	assert.NoError(t, set1.Bind(&cfg))
+	assert.NoError(t, set1.SetEnum("TEST_SET_MODE", "", &mode, []string{"a", "b"}))
+	assert.NoError(t, set1.CollectPrefix("TEST_SET_HEADER_", "", &headers))
+	assert.NoError(t, set1.Var("TEST_SET_LEVEL").BindInt(&level))
+	assert.NotNil(t, RegisterTo(set1, "TEST_SET_NAME", "", "foo"))
+	names := []string{}
+	set1.Usage(func(name, desc string, defval interface{}, required bool) {
+		names = append(names, name)
+	})
+	assert.Equal(t, []string{
+		"TEST_SET_HEADER_*", "TEST_SET_HOST", "TEST_SET_LEVEL",
+		"TEST_SET_MODE", "TEST_SET_NAME", "TEST_SET_PORT",
+	}, names)
+	assert.Len(t, defaultSet.name2envs, 1)
+	m, err := set1.MarshalRegistered()
+	assert.NoError(t, err)
+	assert.Equal(t, "8080", m["TEST_SET_PORT"])
+}
+
+func TestEnvSetSub(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("MYAPP_PORT")
+		os.Unsetenv("MYAPP_DB_HOST")
+		os.Unsetenv("MYAPP_DB_OPT_SSL")
+	}()
+
+	// test that the registrations compose the prefixes
+	app := WithPrefix("MYAPP_")
+	db := app.Sub("DB_")
+	var port int
+	var host string
+	var cfg struct {
+		User string
+	}
+	opts := map[string]string{}
+	assert.NoError(t, app.Set("PORT", "", &port))
+	assert.NoError(t, db.Set("HOST", "", &host, WithRequired()))
+	assert.NoError(t, db.Bind(&cfg))
+	assert.NoError(t, db.CollectPrefix("OPT_", "", &opts))
+	assert.True(t, errors.Is(Set("MYAPP_DB_HOST", "", &host), ErrNameAlready))
+
+	// test that Usage of the sub set shows only the variables of the prefix
+	names := []string{}
+	Usage(func(name, desc string, defval interface{}, required bool) {
+		names = append(names, name)
+	})
+	assert.Equal(t, []string{"MYAPP_DB_HOST", "MYAPP_DB_OPT_*", "MYAPP_DB_USER", "MYAPP_PORT"}, names)
+	names = []string{}
+	db.Usage(func(name, desc string, defval interface{}, required bool) {
+		names = append(names, name)
+	})
+	assert.Equal(t, []string{"MYAPP_DB_HOST", "MYAPP_DB_OPT_*", "MYAPP_DB_USER"}, names)
+
+	// test that Parse of the sub set reads only the variables of the prefix
+	err := app.Parse()
+	assert.True(t, errors.Is(err, ErrNotDefined))
+	assert.Contains(t, err.Error(), `"MYAPP_DB_HOST"`)
+	os.Setenv("MYAPP_PORT", "8080")
+	os.Setenv("MYAPP_DB_HOST", "localhost")
+	os.Setenv("MYAPP_DB_OPT_SSL", "on")
+	assert.NoError(t, db.Parse())
+	assert.Equal(t, 0, port)
+	assert.Equal(t, "localhost", host)
+	assert.Equal(t, map[string]string{"SSL": "on"}, opts)
+	assert.NoError(t, Parse())
+	assert.Equal(t, 8080, port)
+}
+
+func TestWithSnapshot(t *testing.T) {
+	defer func() {
+		os.Unsetenv("TEST_PORT")
+		os.Unsetenv("TEST_HEADER_ACCEPT")
+	}()
+
+	os.Setenv("TEST_PORT", "8080")
+	os.Setenv("TEST_HEADER_ACCEPT", "text/plain")
+	snapshot := NewEnvSet(WithSnapshot())
+	live := NewEnvSet(WithSnapshot(), WithLiveEnv())
+	var snapshotPort, livePort int
+	headers := map[string]string{}
+	assert.NoError(t, snapshot.Set("TEST_PORT", "", &snapshotPort))
+	assert.NoError(t, snapshot.CollectPrefix("TEST_HEADER_", "", &headers))
+	assert.NoError(t, live.Set("TEST_PORT", "", &livePort))
+
+	// test that the snapshot ignores the changes after the creation
+	os.Setenv("TEST_PORT", "9000")
+	os.Setenv("TEST_HEADER_ACCEPT", "application/json")
+	assert.NoError(t, snapshot.Parse())
+	assert.Equal(t, 8080, snapshotPort)
+	assert.Equal(t, map[string]string{"ACCEPT": "text/plain"}, headers)
+	env, _ := snapshot.Lookup("TEST_PORT")
+	assert.Equal(t, "env", env.Source)
+	assert.NoError(t, live.Parse())
+	assert.Equal(t, 9000, livePort)
+
+	// test that the snapshot is kept after the variable is unset
+	os.Unsetenv("TEST_PORT")
+	snapshotPort = 0
+	assert.NoError(t, snapshot.Parse())
+	assert.Equal(t, 8080, snapshotPort)
+
+	// test that the sub sets share the snapshot
+	var sub int
+	assert.NoError(t, snapshot.Sub("TEST_").Set("PORT2", "", &sub))
+	os.Setenv("TEST_PORT2", "1")
+	defer os.Unsetenv("TEST_PORT2")
+	assert.NoError(t, snapshot.Sub("TEST_").Parse())
+	assert.Equal(t, 0, sub)
+
+	// test that the loaders consult the snapshot before the file
+	path := filepath.Join(t.TempDir(), ".env")
+	assert.NoError(t, os.WriteFile(path, []byte("TEST_PORT=7000\nTEST_PORT2=2\n"), 0o600))
+	assert.NoError(t, snapshot.LoadDotenv(path))
+	assert.Equal(t, 8080, snapshotPort)
+	assert.Equal(t, 2, sub)
+}
+
+func TestMustSet(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	// test that register the variable
+	var port int
+	assert.NotPanics(t, func() {
+		MustSet("TEST_PORT", "listen port", &port, WithRequired())
+	})
+	assert.True(t, defaultSet.name2envs["TEST_PORT"].Required)
+
+	// test that panics if the variable cannot be registered
+	assert.PanicsWithError(t, `environment variable name is already registered: "TEST_PORT" already registered`, func() {
+		MustSet("TEST_PORT", "", &port)
+	})
+	assert.PanicsWithValue(t, ErrName, func() {
+		MustSet("0PORT", "", &port)
+	})
+	assert.PanicsWithError(t, ErrValue.Error()+": int is not a non-nil pointer", func() {
+		NewEnvSet().MustSet("TEST_PORT", "", port)
+	})
+}
+
+func TestParseExit(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		exit = os.Exit
+		os.Unsetenv("TEST_HOST")
+	}()
+
+	code := -1
+	exit = func(c int) {
+		code = c
+	}
+	port := 8080
+	var host string
+	assert.NoError(t, Set("TEST_PORT", "listen port", &port))
+	assert.NoError(t, Set("TEST_HOST", "listen host\nor address", &host, WithRequired()))
+
+	// test that print the error and usage, and exit with the code
+	buf := &strings.Builder{}
+	ParseExit(buf, 2)
+	assert.Equal(t, 2, code)
+	assert.Equal(t, `required environment variable not defined: "TEST_HOST" (listen host or address)
+
+Environment variables:
+  TEST_HOST (required)
+    	listen host
+    	or address
+  TEST_PORT
+    	listen port (default 8080)
+`, buf.String())
+
+	// test that MustParse panics
+	assert.Panics(t, MustParse)
+
+	// test that do nothing if no error occurs
+	os.Setenv("TEST_HOST", "localhost")
+	code = -1
+	buf.Reset()
+	ParseExit(buf, 2)
+	assert.Equal(t, -1, code)
+	assert.Empty(t, buf.String())
+	assert.NotPanics(t, MustParse)
+	assert.Equal(t, "localhost", host)
+}
+
+func TestReplace(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+	}()
+
+	// test that register the variable if not registered
+	port := 8080
+	assert.NoError(t, Replace("TEST_PORT", "library port", &port))
+	assert.Equal(t, "library port", defaultSet.name2envs["TEST_PORT"].Description)
+
+	// test that overwrite the registered variable
+	appPort := 9090
+	assert.NoError(t, Replace("TEST_PORT", "app port", &appPort, WithRequired()))
+	env := defaultSet.name2envs["TEST_PORT"]
+	assert.Equal(t, "app port", env.Description)
+	assert.Equal(t, 9090, env.DefaultValue)
+	assert.True(t, env.Required)
+	os.Setenv("TEST_PORT", "1234")
+	assert.NoError(t, Parse())
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, 1234, appPort)
+
+	// test that the registered variable is kept if the arguments are invalid
+	assert.True(t, errors.Is(Replace("TEST_PORT", "", appPort), ErrValue))
+	assert.Equal(t, env, defaultSet.name2envs["TEST_PORT"])
+}
+
+func TestUnsetReset(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+		os.Unsetenv("TEST_HOSTS")
+		os.Unsetenv("TEST_PTR")
+		os.Unsetenv("TEST_HEADER_A")
+		os.Unsetenv("TEST_UPSTREAM_0_HOST")
+	}()
+
+	port := 8080
+	hosts := []string{"a"}
+	var ptr *int
+	headers := map[string]string{"B": "b"}
+	var cfg struct {
+		Upstreams []struct{ Host string } `env:"TEST_UPSTREAM"`
+	}
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	assert.NoError(t, Set("TEST_HOSTS", "", &hosts))
+	assert.NoError(t, Set("TEST_PTR", "", &ptr))
+	assert.NoError(t, CollectPrefix("TEST_HEADER_", "", &headers))
+	assert.NoError(t, Bind(&cfg))
+
+	// test that Unset removes the registration without changing the value
+	os.Setenv("TEST_PORT", "9090")
+	os.Setenv("TEST_HOSTS", "x,y")
+	os.Setenv("TEST_PTR", "1")
+	os.Setenv("TEST_HEADER_A", "a")
+	os.Setenv("TEST_UPSTREAM_0_HOST", "host0")
+	assert.NoError(t, Parse())
+	assert.True(t, Unset("TEST_PORT"))
+	assert.False(t, Unset("TEST_PORT"))
+	assert.Equal(t, 9090, port)
+	assert.NotContains(t, defaultSet.name2envs, "TEST_PORT")
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	assert.Equal(t, 9090, defaultSet.name2envs["TEST_PORT"].DefaultValue)
+
+	// test that Reset restores the default values and clears the registry
+	Reset()
+	assert.Equal(t, 9090, port)
+	assert.Equal(t, []string{"a"}, hosts)
+	assert.Nil(t, ptr)
+	assert.Equal(t, map[string]string{"B": "b"}, headers)
+	assert.Nil(t, cfg.Upstreams)
+	assert.Empty(t, defaultSet.name2envs)
+	assert.Empty(t, defaultSet.prefix2collectors)
+
+	// test that Reset of the sub set affects only the variables of the prefix
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	sub := WithPrefix("TEST_SUB_")
+	assert.NoError(t, sub.Set("HOSTS", "", &hosts))
+	assert.True(t, sub.Unset("HOSTS"))
+	assert.NoError(t, sub.Set("HOSTS", "", &hosts))
+	sub.Reset()
+	assert.Len(t, defaultSet.name2envs, 1)
+	assert.Contains(t, defaultSet.name2envs, "TEST_PORT")
+}
+
+func TestLookup(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+	}()
+
+	port := 8080
+	assert.NoError(t, Set("TEST_PORT", "listen port", &port, WithRequired(), WithExample("80")))
+
+	// test that returns the metadata of the registered variable
+	env, ok := Lookup("TEST_PORT")
+	assert.True(t, ok)
+	assert.Equal(t, "TEST_PORT", env.Name)
+	assert.Equal(t, "listen port", env.Description)
+	assert.Equal(t, 8080, env.DefaultValue)
+	assert.True(t, env.Required)
+	assert.Equal(t, "80", env.Example)
+
+	// test that the current value can be read through the Value
+	os.Setenv("TEST_PORT", "9090")
+	assert.NoError(t, Parse())
+	assert.Equal(t, 9090, *env.Value.(*int))
+
+	// test that the returned Env is a copy
+	env.Required = false
+	assert.True(t, defaultSet.name2envs["TEST_PORT"].Required)
+
+	// test that the name is prefixed by the set
+	env, ok = WithPrefix("TEST_").Lookup("PORT")
+	assert.True(t, ok)
+	assert.Equal(t, "TEST_PORT", env.Name)
+
+	// test that returns false if not registered
+	env, ok = Lookup("TEST_UNKNOWN")
+	assert.False(t, ok)
+	assert.Nil(t, env)
+}
+
+func TestNamesLenHas(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	// test that returns the empty list if no variables are registered
+	assert.Equal(t, []string{}, Names())
+	assert.Equal(t, 0, Len())
+	assert.False(t, Has("TEST_PORT"))
+
+	var port int
+	var host string
+	headers := map[string]string{}
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	assert.NoError(t, Set("TEST_DB_HOST", "", &host))
+	assert.NoError(t, CollectPrefix("TEST_HEADER_", "", &headers))
+
+	// test that returns the sorted names of the registered variables
+	assert.Equal(t, []string{"TEST_DB_HOST", "TEST_PORT"}, Names())
+	assert.Equal(t, 2, Len())
+	assert.True(t, Has("TEST_PORT"))
+	assert.False(t, Has("TEST_HEADER_"))
+
+	// test that the sub set returns the variables of the prefix
+	db := WithPrefix("TEST_DB_")
+	assert.Equal(t, []string{"TEST_DB_HOST"}, db.Names())
+	assert.Equal(t, 1, db.Len())
+	assert.True(t, db.Has("HOST"))
+	assert.False(t, db.Has("TEST_DB_HOST"))
+}
+
+func TestVisit(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+	}()
+
+	var port int
+	var host string
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+	visit := func(fn func(func(*Env))) []string {
+		names := []string{}
+		fn(func(env *Env) {
+			names = append(names, env.Name)
+		})
+		return names
+	}
+
+	// test that Visit calls nothing before Parse
+	assert.Equal(t, []string{}, visit(Visit))
+	assert.Equal(t, []string{"TEST_HOST", "TEST_PORT"}, visit(VisitAll))
+
+	// test that Visit calls fn only for the variables set from the environment
+	os.Setenv("TEST_PORT", "8080")
+	assert.NoError(t, Parse())
+	assert.Equal(t, []string{"TEST_PORT"}, visit(Visit))
+	assert.Equal(t, []string{"TEST_HOST", "TEST_PORT"}, visit(VisitAll))
+}
+
+func TestParseJoinErrors(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_B_PORT")
+		os.Unsetenv("TEST_D_LIMIT_X")
+	}()
+
+	var a string
+	var port, c int
+	limits := map[string]int{}
+	assert.NoError(t, Set("TEST_A_HOST", "listen host", &a, WithRequired()))
+	assert.NoError(t, Set("TEST_B_PORT", "", &port))
+	assert.NoError(t, Set("TEST_C_COUNT", "", &c, WithRequired()))
+	assert.NoError(t, CollectPrefix("TEST_D_LIMIT_", "", &limits))
+	os.Setenv("TEST_B_PORT", "foo")
+	os.Setenv("TEST_D_LIMIT_X", "bar")
+
+	// test that returns all errors in the order of the names, and the missing
+	// variables are reported at once
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrNotDefined))
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	lines := strings.Split(err.Error(), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, `required environment variable not defined: "TEST_A_HOST" (listen host), "TEST_C_COUNT"`, lines[0])
+	assert.Contains(t, lines[1], `"TEST_B_PORT"`)
+	assert.Contains(t, lines[2], `"TEST_D_LIMIT_X"`)
+}
+
+func TestParseNames(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_DB_HOST")
+		os.Unsetenv("TEST_DB_PORT")
+		os.Unsetenv("TEST_PORT")
+		os.Unsetenv("TEST_HEADER_A")
+	}()
+
+	var host, apiKey string
+	var dbPort, port int
+	headers := map[string]string{}
+	assert.NoError(t, Set("TEST_DB_HOST", "", &host))
+	assert.NoError(t, Set("TEST_DB_PORT", "", &dbPort))
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	assert.NoError(t, Set("TEST_API_KEY", "", &apiKey, WithRequired()))
+	assert.NoError(t, CollectPrefix("TEST_HEADER_", "", &headers))
+	os.Setenv("TEST_DB_HOST", "localhost")
+	os.Setenv("TEST_DB_PORT", "5432")
+	os.Setenv("TEST_PORT", "8080")
+	os.Setenv("TEST_HEADER_A", "a")
+
+	// test that parse only the specified variables
+	assert.NoError(t, Parse("TEST_DB_HOST", "TEST_DB_PORT"))
+	assert.Equal(t, "localhost", host)
+	assert.Equal(t, 5432, dbPort)
+	assert.Equal(t, 0, port)
+	assert.Empty(t, headers)
+
+	// test that parse the variables collected by the prefix
+	assert.NoError(t, Parse("TEST_HEADER_"))
+	assert.Equal(t, map[string]string{"A": "a"}, headers)
+
+	// test that the names are prefixed by the set
+	assert.NoError(t, WithPrefix("TEST_").Parse("PORT"))
+	assert.Equal(t, 8080, port)
+
+	// test that returns ErrNotDefined if the specified variable is missing
+	assert.True(t, errors.Is(Parse("TEST_DB_HOST", "TEST_API_KEY"), ErrNotDefined))
+
+	// test that returns ErrNotRegistered without parsing any variables
+	os.Setenv("TEST_DB_HOST", "db.example.com")
+	err := Parse("TEST_DB_HOST", "TEST_UNKNOWN", "TEST_DB_USER")
+	assert.True(t, errors.Is(err, ErrNotRegistered))
+	assert.Equal(t, "environment variable not registered: \"TEST_UNKNOWN\"\n"+
+		"environment variable not registered: \"TEST_DB_USER\"", err.Error())
+	assert.Equal(t, "localhost", host)
+}
+
+func TestParseFiltered(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_DB_HOST")
+		os.Unsetenv("TEST_PORT")
+		os.Unsetenv("TEST_DB_OPT_SSL")
+	}()
+
+	var host, secret string
+	var port int
+	opts := map[string]string{}
+	assert.NoError(t, Set("TEST_DB_HOST", "", &host))
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	assert.NoError(t, Set("TEST_SECRET", "", &secret, WithRequired()))
+	assert.NoError(t, CollectPrefix("TEST_DB_OPT_", "", &opts, WithRequired()))
+	os.Setenv("TEST_DB_HOST", "localhost")
+	os.Setenv("TEST_PORT", "8080")
+	os.Setenv("TEST_DB_OPT_SSL", "on")
+
+	// test that parse only the variables accepted by the filter
+	assert.NoError(t, ParseFiltered(OnlyPrefix("TEST_DB_")))
+	assert.Equal(t, "localhost", host)
+	assert.Equal(t, 0, port)
+	assert.Equal(t, map[string]string{"SSL": "on"}, opts)
+
+	// test that skip the required variables
+	host = ""
+	opts = map[string]string{}
+	assert.NoError(t, ParseFiltered(SkipRequired()))
+	assert.Equal(t, "localhost", host)
+	assert.Equal(t, 8080, port)
+	assert.Empty(t, opts)
+
+	// test that the filter receives the metadata of the variables
+	names := []string{}
+	assert.NoError(t, ParseFiltered(func(env *Env) bool {
+		names = append(names, env.Name)
+		return false
+	}))
+	assert.Equal(t, []string{"TEST_DB_HOST", "TEST_PORT", "TEST_SECRET", "TEST_DB_OPT_"}, names)
+	assert.True(t, errors.Is(ParseFiltered(OnlyPrefix("TEST_SECRET")), ErrNotDefined))
+}
+
+func TestParseTransactional(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		for _, name := range []string{
+			"TEST_A_HOST", "TEST_B_HOSTS", "TEST_C_PORT", "TEST_D_LIMIT_X", "TEST_E_UPSTREAM_0_HOST",
+		} {
+			os.Unsetenv(name)
+		}
+	}()
+
+	host := "default"
+	hosts := []string{"a"}
+	port := 8080
+	limits := map[string]int{"Y": 1}
+	var cfg struct {
+		Upstreams []struct{ Host string } `env:"TEST_E_UPSTREAM"`
+	}
+	assert.NoError(t, Set("TEST_A_HOST", "", &host))
+	assert.NoError(t, Set("TEST_B_HOSTS", "", &hosts))
+	assert.NoError(t, Set("TEST_C_PORT", "", &port, WithCheck(RangeCheckFunc(1, 65535))))
+	assert.NoError(t, CollectPrefix("TEST_D_LIMIT_", "", &limits))
+	assert.NoError(t, Bind(&cfg))
+	os.Setenv("TEST_A_HOST", "localhost")
+	os.Setenv("TEST_B_HOSTS", "x,y")
+	os.Setenv("TEST_C_PORT", "0")
+	os.Setenv("TEST_D_LIMIT_X", "10")
+	os.Setenv("TEST_E_UPSTREAM_0_HOST", "upstream")
+
+	// test that the values are not changed if any variable is invalid
+	assert.True(t, errors.Is(Parse(), ErrEnvVar))
+	assert.Equal(t, "default", host)
+	assert.Equal(t, []string{"a"}, hosts)
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, map[string]int{"Y": 1}, limits)
+	assert.Nil(t, cfg.Upstreams)
+	assert.Equal(t, []string{}, func() []string {
+		names := []string{}
+		Visit(func(env *Env) {
+			names = append(names, env.Name)
+		})
+		return names
+	}())
+
+	// test that the values are stored if all variables are valid
+	os.Setenv("TEST_C_PORT", "9090")
+	assert.NoError(t, Parse())
+	assert.Equal(t, "localhost", host)
+	assert.Equal(t, []string{"x", "y"}, hosts)
+	assert.Equal(t, 9090, port)
+	assert.Equal(t, map[string]int{"X": 10}, limits)
+	assert.Len(t, cfg.Upstreams, 1)
+}
+
+func TestParseTransactionalShared(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	var n int
+	var num big.Int
+	num.SetInt64(1)
+	var cfg struct {
+		Limits map[string]int
+		Hosts  []string
+		Next   *struct{ Port int }
+	}
+	cfg.Limits = map[string]int{"a": 1}
+	cfg.Hosts = []string{"x"}
+	cfg.Next = &struct{ Port int }{Port: 80}
+	assert.NoError(t, Set("TEST_N", "", &n))
+	assert.NoError(t, Set("TEST_BIG", "", &num))
+	assert.NoError(t, Set("TEST_CFG", "", &cfg, WithParse(JSONParseFunc())))
+
+	// test that neither the values nor the default values are changed
+	// through the storage shared with the staged values
+	err := ParseMap(map[string]string{
+		"TEST_N":   "bad",
+		"TEST_BIG": "123456789012345678901234567890",
+		"TEST_CFG": `{"Limits":{"a":2},"Hosts":["y"],"Next":{"Port":8080}}`,
+	})
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Equal(t, "1", num.String())
+	assert.Equal(t, map[string]int{"a": 1}, cfg.Limits)
+	assert.Equal(t, []string{"x"}, cfg.Hosts)
+	assert.Equal(t, 80, cfg.Next.Port)
+	envs := defaultSet.name2envs
+	defbig := envs["TEST_BIG"].DefaultValue.(big.Int)
+	assert.Equal(t, "1", defbig.String())
+	defcfg := envs["TEST_CFG"].DefaultValue.(struct {
+		Limits map[string]int
+		Hosts  []string
+		Next   *struct{ Port int }
+	})
+	assert.Equal(t, map[string]int{"a": 1}, defcfg.Limits)
+	assert.Equal(t, []string{"x"}, defcfg.Hosts)
+	assert.Equal(t, 80, defcfg.Next.Port)
+}
+
+func TestParseTransactionalCyclic(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	type node struct {
+		Name  string
+		Next  *node
+		Attrs map[string]interface{}
+	}
+	var n int
+	v := &node{Name: "a", Attrs: map[string]interface{}{}}
+	v.Next = v
+	v.Attrs["self"] = v.Attrs
+	assert.NoError(t, Set("TEST_N", "", &n))
+	assert.NoError(t, Set("TEST_NODE", "", v, WithParse(func(iv interface{}, _, s string) error {
+		iv.(*node).Next.Name = s
+		return nil
+	})))
+
+	// test that the cyclic references are cloned without recursing infinitely
+	err := ParseMap(map[string]string{"TEST_N": "bad", "TEST_NODE": "b"})
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Equal(t, "a", v.Name)
+	assert.Same(t, v, v.Next)
+
+	// test that the cyclic references are kept cyclic in the staged value
+	assert.NoError(t, ParseMap(map[string]string{"TEST_N": "1", "TEST_NODE": "b"}))
+	assert.Equal(t, "b", v.Name)
+	assert.Same(t, v.Next, v.Next.Next)
+	assert.Equal(t, "b", v.Next.Name)
+}
+
+func TestParseFlagValue(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	lstv := testList{"x"}
+	assert.NoError(t, Set("TEST_LIST", "", &lstv))
+
+	// test that the flag.Value is parsed from the default value every time
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, ParseMap(map[string]string{"TEST_LIST": "a|b"}))
+		assert.Equal(t, testList{"x", "a", "b"}, lstv)
+	}
+	assert.Equal(t, testList{"x"}, defaultSet.name2envs["TEST_LIST"].DefaultValue)
+}
+
+func TestNoTrim(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_DELIM")
+		os.Unsetenv("TEST_PASSWORD")
+		os.Unsetenv("TEST_TRIMMED")
+	}()
+
+	var delim, trimmed string
+	var cfg struct {
+		Password string `env:"TEST_PASSWORD,notrim"`
+	}
+	assert.NoError(t, Set("TEST_DELIM", "", &delim, WithNoTrim()))
+	assert.NoError(t, Set("TEST_TRIMMED", "", &trimmed))
+	assert.NoError(t, Bind(&cfg))
+	assert.True(t, defaultSet.name2envs["TEST_PASSWORD"].NoTrim)
+
+	// test that the value is used verbatim
+	os.Setenv("TEST_DELIM", " ")
+	os.Setenv("TEST_PASSWORD", " secret\t")
+	os.Setenv("TEST_TRIMMED", " value ")
+	assert.NoError(t, Parse())
+	assert.Equal(t, " ", delim)
+	assert.Equal(t, " secret\t", cfg.Password)
+	assert.Equal(t, "value", trimmed)
+
+	// test that the notrim option cannot be used for struct
+	err := Bind(&struct {
+		DB struct{ Host string } `env:"DB,notrim"`
+	}{})
+	assert.Equal(t, "field DB: notrim and allowempty options cannot be used for struct", err.Error())
+}
+
+func TestAllowEmpty(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		for _, name := range []string{"TEST_PREFIX", "TEST_HOSTS", "TEST_LABELS", "TEST_NAME", "TEST_TAG"} {
+			os.Unsetenv(name)
+		}
+	}()
+
+	prefix := "app_"
+	hosts := []string{"a"}
+	labels := map[string]string{"k": "v"}
+	name := "default"
+	var cfg struct {
+		Tag string `env:"TEST_TAG,allowempty" default:"latest"`
+	}
+	assert.NoError(t, Set("TEST_PREFIX", "", &prefix, WithAllowEmpty(), WithRequired()))
+	assert.NoError(t, Set("TEST_HOSTS", "", &hosts, WithAllowEmpty()))
+	assert.NoError(t, Set("TEST_LABELS", "", &labels, WithAllowEmpty()))
+	assert.NoError(t, Set("TEST_NAME", "", &name))
+	assert.NoError(t, Bind(&cfg))
+
+	// test that the undefined variable is still treated as unset
+	assert.True(t, errors.Is(Parse(), ErrNotDefined))
+
+	// test that the empty value is valid if the variable is defined
+	for _, name := range []string{"TEST_PREFIX", "TEST_HOSTS", "TEST_LABELS", "TEST_NAME", "TEST_TAG"} {
+		os.Setenv(name, " ")
+	}
+	assert.NoError(t, Parse())
+	assert.Equal(t, "", prefix)
+	assert.Equal(t, []string{}, hosts)
+	assert.Equal(t, map[string]string{}, labels)
+	assert.Equal(t, "default", name)
+	assert.Equal(t, "", cfg.Tag)
+}
+
+func TestUnsupportedType(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_CHAN")
+		os.Unsetenv("TEST_IDS")
+	}()
+
+	// test that returns ErrValue at the registration
+	ch := make(chan int)
+	err := Set("TEST_CHAN", "", &ch)
+	assert.True(t, errors.Is(err, ErrValue))
+	assert.Equal(t, ErrValue.Error()+": chan int", err.Error())
+
+	// test that returns ErrUnsupportedType instead of panicking if the
+	// custom ParseFunc delegates the unsupported type to the default parser
+	ids := map[int]string{}
+	assert.NoError(t, Set("TEST_CHAN", "", &ch, WithParse(defaultParseFunc)))
+	assert.NoError(t, Set("TEST_IDS", "", &ids, WithParse(SliceParseFunc(";"))))
+	os.Setenv("TEST_CHAN", "1")
+	os.Setenv("TEST_IDS", "1=a")
+	assert.NotPanics(t, func() {
+		err = Parse()
+	})
+	assert.True(t, errors.Is(err, ErrUnsupportedType))
+	assert.Contains(t, err.Error(), "chan int")
+	assert.Contains(t, err.Error(), "map[int]string")
+	assert.Equal(t, map[int]string{}, ids)
+}
+
+func TestParseValue(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+	}()
+
+	port := 80
+	host := "localhost"
+	assert.NoError(t, Set("TEST_PORT", "", &port, WithRequired(), WithCheck(func(v interface{}, name string) error {
+		if *v.(*int) < 1024 {
+			return fmt.Errorf("privileged port")
+		}
+		return nil
+	})))
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+
+	// test that parses the value without the environment variable
+	os.Setenv("TEST_PORT", "9000")
+	assert.NoError(t, ParseValue("TEST_PORT", " 8080 "))
+	assert.Equal(t, 8080, port)
+	var visited []string
+	Visit(func(env *Env) { visited = append(visited, env.Name) })
+	assert.Equal(t, []string{"TEST_PORT"}, visited)
+
+	// test that keeps the value if the value is invalid
+	for _, v := range []string{"foo", "443"} {
+		err := ParseValue("TEST_PORT", v)
+		assert.True(t, errors.Is(err, ErrEnvVar))
+		assert.Equal(t, 8080, port)
+	}
+
+	// test that the empty value is treated as undefined
+	assert.True(t, errors.Is(ParseValue("TEST_PORT", " "), ErrNotDefined))
+	assert.NoError(t, ParseValue("TEST_HOST", ""))
+	assert.Equal(t, "localhost", host)
+
+	// test that returns ErrNotRegistered
+	assert.True(t, errors.Is(ParseValue("TEST_UNKNOWN", "1"), ErrNotRegistered))
+
+	// test that the name is prefixed by the set
+	app := WithPrefix("TEST_")
+	assert.NoError(t, app.ParseValue("PORT", "8443"))
+	assert.Equal(t, 8443, port)
+}
+
+func TestWithFile(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		for _, name := range []string{
+			"TEST_PASSWORD", "TEST_PASSWORD_FILE", "TEST_TOKEN_FILE",
+			"TEST_KEYS_0_SECRET_FILE", "TEST_DB_USER_FILE",
+		} {
+			os.Unsetenv(name)
+		}
+	}()
+
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	assert.NoError(t, os.WriteFile(secret, []byte("s3cret\n"), 0o600))
+
+	password := ""
+	token := ""
+	var cfg struct {
+		DB struct {
+			User string `env:",file"`
+		} `prefix:"TEST_DB_"`
+		Keys []struct {
+			Secret string `env:",file"`
+		} `env:"TEST_KEYS"`
+	}
+	assert.NoError(t, Set("TEST_PASSWORD", "", &password, WithFile(), WithRequired()))
+	assert.NoError(t, Set("TEST_TOKEN", "", &token))
+	assert.NoError(t, Bind(&cfg))
+
+	// test that reads the value from the file
+	os.Setenv("TEST_PASSWORD_FILE", secret)
+	os.Setenv("TEST_TOKEN_FILE", secret)
+	os.Setenv("TEST_DB_USER_FILE", secret)
+	os.Setenv("TEST_KEYS_0_SECRET_FILE", secret)
+	assert.NoError(t, Parse())
+	assert.Equal(t, "s3cret", password)
+	assert.Equal(t, "s3cret", cfg.DB.User)
+	assert.Len(t, cfg.Keys, 1)
+	assert.Equal(t, "s3cret", cfg.Keys[0].Secret)
+	// the file is not read without WithFile
+	assert.Equal(t, "", token)
+
+	// test that returns ErrFileConflict if both are defined
+	os.Setenv("TEST_PASSWORD", "plain")
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrFileConflict))
+	assert.Contains(t, err.Error(), `"TEST_PASSWORD" and "TEST_PASSWORD_FILE"`)
+	os.Unsetenv("TEST_PASSWORD")
+
+	// test that returns ErrEnvVar if the file cannot be read
+	os.Setenv("TEST_PASSWORD_FILE", filepath.Join(dir, "none"))
+	err = Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	// test that the empty file is treated as undefined
+	assert.NoError(t, os.WriteFile(secret, []byte("\n"), 0o600))
+	os.Setenv("TEST_PASSWORD_FILE", secret)
+	assert.True(t, errors.Is(Parse(), ErrNotDefined))
+
+	// test that the file option cannot be used for struct
+	var invalid struct {
+		DB struct{ User string } `env:",file"`
+	}
+	assert.Error(t, Bind(&invalid))
+}
+
+func TestWithSecret(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	var host, password, token string
+	var cfg struct {
+		APIKey string `env:"TEST_API_KEY,secret"`
+	}
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+	assert.NoError(t, Set("TEST_PASSWORD", "", &password, WithSecret()))
+	assert.NoError(t, WithPrefix("TEST_").Set("TOKEN", "", &token, WithSecret()))
+	assert.NoError(t, Bind(&cfg))
+
+	// test that returns the names of the secrets
+	assert.Equal(t, []string{"TEST_API_KEY", "TEST_PASSWORD", "TEST_TOKEN"}, SecretNames())
+	env, _ := Lookup("TEST_PASSWORD")
+	assert.True(t, env.Secret)
+
+	// test that the secret option cannot be used for struct
+	var invalid struct {
+		DB struct{ User string } `env:",secret"`
+	}
+	assert.Error(t, Bind(&invalid))
+}
+
+// slowLookuper looks up the values from the map after the delay, and records
+// the maximum number of the concurrent lookups. The lookups of the names in
+// errs fail without the delay.
+type slowLookuper struct {
+	MapLookuper
+	delay    time.Duration
+	errs     map[string]error
+	mu       sync.Mutex
+	inflight int
+	max      int
+}
+
+func (l *slowLookuper) LookupContext(ctx context.Context, name string) (string, bool, error) {
+	l.mu.Lock()
+	if l.inflight++; l.inflight > l.max {
+		l.max = l.inflight
+	}
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.inflight--
+		l.mu.Unlock()
+	}()
+
+	if err := l.errs[name]; err != nil {
+		return "", false, err
+	}
+	select {
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	case <-time.After(l.delay):
+	}
+	v, ok := l.Lookup(name)
+	return v, ok, nil
+}
+
+func TestSetConcurrency(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	values := make([]int, 8)
+	m := MapLookuper{}
+	for i := range values {
+		name := fmt.Sprintf("TEST_VALUE_%d", i)
+		assert.NoError(t, Set(name, "", &values[i], WithRequired()))
+		m[name] = strconv.Itoa(i + 1)
+	}
+
+	// test that the variables are resolved serially by default
+	l := &slowLookuper{MapLookuper: m, delay: time.Millisecond}
+	assert.NoError(t, ParseFrom(l))
+	assert.Equal(t, 1, l.max)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, values)
+
+	// test that the variables are resolved by up to the concurrency
+	SetConcurrency(4)
+	l = &slowLookuper{MapLookuper: MapLookuper{}, delay: 20 * time.Millisecond}
+	for name, v := range m {
+		l.MapLookuper[name] = v + "0"
+	}
+	assert.NoError(t, ParseFrom(l))
+	assert.Equal(t, 4, l.max)
+	assert.Equal(t, []int{10, 20, 30, 40, 50, 60, 70, 80}, values)
+
+	// test that the sets returned by Sub inherit the concurrency
+	assert.Equal(t, 4, WithPrefix("TEST_").concurrency)
+
+	// test that the errors are reported in the order of the names and no
+	// value is changed
+	l = &slowLookuper{MapLookuper: MapLookuper{}, delay: time.Millisecond}
+	err := ParseFrom(l)
+	assert.True(t, errors.Is(err, ErrNotDefined))
+	msg := err.Error()
+	assert.Less(t, strings.Index(msg, `"TEST_VALUE_0"`), strings.Index(msg, `"TEST_VALUE_2"`))
+	assert.Less(t, strings.Index(msg, `"TEST_VALUE_2"`), strings.Index(msg, `"TEST_VALUE_7"`))
+	assert.Equal(t, []int{10, 20, 30, 40, 50, 60, 70, 80}, values)
+
+	// test that the other lookups are canceled by the failure and are not
+	// reported
+	errLookup := errors.New("connection refused")
+	l = &slowLookuper{MapLookuper: m, delay: time.Hour, errs: map[string]error{
+		"TEST_VALUE_2": errLookup,
+	}}
+	start := time.Now()
+	err = ParseFrom(l)
+	assert.True(t, time.Since(start) < time.Minute)
+	assert.True(t, errors.Is(err, errLookup))
+	assert.False(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, []int{10, 20, 30, 40, 50, 60, 70, 80}, values)
+
+	// test that the resolution is stopped if the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	l = &slowLookuper{MapLookuper: m, delay: time.Hour}
+	err = ParseFromContext(ctx, l)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, []int{10, 20, 30, 40, 50, 60, 70, 80}, values)
+}
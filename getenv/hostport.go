@@ -0,0 +1,54 @@
+package getenv
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// HostPort represents a network address in the form of "host:port" such as
+// "localhost:8080", ":8080" and "[::1]:443".
+type HostPort struct {
+	host string
+	port int
+}
+
+// ParseHostPort parses the address by net.SplitHostPort. The port must be
+// a number between 0 and 65535, and the IPv6 address must be enclosed in
+// square brackets. The host can be empty.
+func ParseHostPort(s string) (HostPort, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return HostPort{}, err
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return HostPort{}, fmt.Errorf("address %s: invalid port %q", s, port)
+	}
+	return HostPort{host: host, port: int(n)}, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (v *HostPort) UnmarshalText(b []byte) error {
+	hp, err := ParseHostPort(string(b))
+	if err != nil {
+		return err
+	}
+	*v = hp
+	return nil
+}
+
+// Host returns the host part of the address.
+func (v HostPort) Host() string {
+	return v.host
+}
+
+// Port returns the port number of the address.
+func (v HostPort) Port() int {
+	return v.port
+}
+
+// String returns the address in the form of "host:port".
+func (v HostPort) String() string {
+	return net.JoinHostPort(v.host, strconv.Itoa(v.port))
+}
@@ -0,0 +1,49 @@
+package getenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHostPort(t *testing.T) {
+	// test that parse host and port
+	for s, exp := range map[string]struct {
+		host string
+		port int
+		str  string
+	}{
+		"localhost:8080":   {"localhost", 8080, "localhost:8080"},
+		":8080":            {"", 8080, ":8080"},
+		"10.0.0.1:0":       {"10.0.0.1", 0, "10.0.0.1:0"},
+		"[::1]:443":        {"::1", 443, "[::1]:443"},
+		"example.com:0443": {"example.com", 443, "example.com:443"},
+	} {
+		v, err := ParseHostPort(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, exp.host, v.Host(), s)
+		assert.Equal(t, exp.port, v.Port(), s)
+		assert.Equal(t, exp.str, v.String(), s)
+	}
+
+	// test that returns error if the address is invalid
+	for _, s := range []string{
+		"", "localhost", "localhost:", "localhost:http", "localhost:65536",
+		"localhost:-1", "::1:443", "[::1:443", "[::1]", "a:b:c",
+	} {
+		_, err := ParseHostPort(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestHostPort(t *testing.T) {
+	// test that parse address as encoding.TextUnmarshaler
+	var v HostPort
+	assert.NoError(t, defaultParseFunc(&v, "LISTEN_ADDR", "0.0.0.0:8080"))
+	assert.Equal(t, "0.0.0.0", v.Host())
+	assert.Equal(t, 8080, v.Port())
+
+	// test that returns error if the port is missing
+	assert.Error(t, defaultParseFunc(&v, "LISTEN_ADDR", "0.0.0.0"))
+	assert.Equal(t, "0.0.0.0:8080", v.String())
+}
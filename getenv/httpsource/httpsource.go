@@ -0,0 +1,194 @@
+// Package httpsource provides the getenv.Source that resolves the variables
+// from the JSON or dotenv document served by the central configuration
+// service over HTTPS.
+//
+//	src, err := httpsource.New(ctx, httpsource.Config{
+//		URL:    "https://config.example.com/myapp/prod.env",
+//		Header: http.Header{"Authorization": {"Bearer " + token}},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	getenv.SetSources(getenv.EnvSource(), src)
+//
+// The document is fetched again by Refresh with the conditional request, and
+// the last fetched values are kept if the server responds 304 Not Modified
+// or the request fails.
+package httpsource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/mah0x211/go-getenv/getenv"
+)
+
+// Format is the format of the document.
+type Format int
+
+const (
+	// FormatAuto detects the format by the Content-Type of the response. The
+	// document is parsed as JSON if the media type is "application/json",
+	// otherwise as dotenv.
+	FormatAuto Format = iota
+	// FormatJSON parses the document by getenv.ReadJSON.
+	FormatJSON
+	// FormatDotenv parses the document by getenv.ReadDotenv.
+	FormatDotenv
+)
+
+// Config configures the Source.
+type Config struct {
+	// URL of the document that must be https
+	URL string
+	// header of the requests such as Authorization
+	Header http.Header
+	// format of the document
+	Format Format
+	// duration to keep the values after the last successful fetch if the
+	// following fetches fail, or forever if 0
+	MaxStale time.Duration
+	// http.DefaultClient is used if nil
+	HTTPClient *http.Client
+}
+
+// Source is the getenv.Source named "http" that supplies the values of the
+// document.
+type Source struct {
+	cfg Config
+	now func() time.Time
+
+	// serializes Refresh
+	refreshMu    sync.Mutex
+	etag         string
+	lastModified string
+
+	mu      sync.RWMutex
+	values  getenv.MapLookuper
+	fetched time.Time
+}
+
+var (
+	_ getenv.Source          = (*Source)(nil)
+	_ getenv.EnvironLookuper = (*Source)(nil)
+)
+
+// New fetches the document and returns the Source that has its values.
+func New(ctx context.Context, cfg Config) (*Source, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("http: %w", err)
+	} else if u.Scheme != "https" {
+		return nil, fmt.Errorf("http: %q is not https", cfg.URL)
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	s := &Source{cfg: cfg, now: time.Now}
+	if err = s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Name returns "http".
+func (s *Source) Name() string {
+	return "http"
+}
+
+// Lookup returns the value of name in the last fetched document.
+func (s *Source) Lookup(name string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[name]
+	return v, ok
+}
+
+// Environ lists the variables of the last fetched document.
+func (s *Source) Environ() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values.Environ()
+}
+
+// Refresh fetches the document again if it has been modified. If the fetch
+// fails, the error is returned and the last fetched values are kept until
+// MaxStale has passed since the last successful fetch.
+func (s *Source) Refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	err := s.fetch(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.fetched = s.now()
+	} else if s.cfg.MaxStale > 0 && s.now().Sub(s.fetched) > s.cfg.MaxStale {
+		s.values = nil
+	}
+	return err
+}
+
+// fetch sends the conditional request and updates the values if modified.
+func (s *Source) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL, nil)
+	if err != nil {
+		return fmt.Errorf("http: %w", err)
+	}
+	for k, v := range s.cfg.Header {
+		req.Header[k] = v
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	if s.lastModified != "" {
+		req.Header.Set("If-Modified-Since", s.lastModified)
+	}
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("http: GET %s: %s", s.cfg.URL, resp.Status)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("http: GET %s: %w", s.cfg.URL, err)
+	}
+	read := getenv.ReadDotenv
+	switch s.cfg.Format {
+	case FormatJSON:
+		read = getenv.ReadJSON
+	case FormatAuto:
+		if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt == "application/json" {
+			read = getenv.ReadJSON
+		}
+	}
+	m, err := read(bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("http: GET %s: %w", s.cfg.URL, err)
+	}
+
+	s.etag = resp.Header.Get("ETag")
+	s.lastModified = resp.Header.Get("Last-Modified")
+	s.mu.Lock()
+	s.values = m
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,93 @@
+package httpsource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSource(t *testing.T) {
+	body := "HOST=example.com\nPORT=8080\n"
+	contentType := "text/plain"
+	status := 0
+	requests := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		} else if status != 0 {
+			w.WriteHeader(status)
+			return
+		} else if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	cfg := Config{
+		URL:        srv.URL + "/myapp.env",
+		Header:     http.Header{"Authorization": {"Bearer token"}},
+		MaxStale:   time.Minute,
+		HTTPClient: srv.Client(),
+	}
+
+	// test that fetches the dotenv document
+	src, err := New(context.Background(), cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "http", src.Name())
+	v, _ := src.Lookup("HOST")
+	assert.Equal(t, "example.com", v)
+	assert.Equal(t, []string{"HOST=example.com", "PORT=8080"}, src.Environ())
+
+	// test that keeps the values if not modified
+	body = "HOST=changed\n"
+	assert.NoError(t, src.Refresh(context.Background()))
+	v, _ = src.Lookup("HOST")
+	assert.Equal(t, "example.com", v)
+	assert.Equal(t, 2, requests)
+
+	// test that keeps the stale values on error until MaxStale has passed
+	now := time.Now()
+	src.now = func() time.Time { return now }
+	assert.NoError(t, src.Refresh(context.Background()))
+	status = http.StatusBadGateway
+	now = now.Add(30 * time.Second)
+	assert.Contains(t, src.Refresh(context.Background()).Error(), "502")
+	v, ok := src.Lookup("HOST")
+	assert.True(t, ok)
+	assert.Equal(t, "example.com", v)
+	now = now.Add(time.Minute)
+	assert.Error(t, src.Refresh(context.Background()))
+	_, ok = src.Lookup("HOST")
+	assert.False(t, ok)
+
+	// test that parses the JSON document by the Content-Type
+	status = 0
+	body = `{"HOST": "json.example.com"}`
+	contentType = "application/json; charset=utf-8"
+	src, err = New(context.Background(), cfg)
+	assert.NoError(t, err)
+	v, _ = src.Lookup("HOST")
+	assert.Equal(t, "json.example.com", v)
+
+	// test that returns the error
+	_, err = New(context.Background(), Config{URL: "http://example.com/myapp.env"})
+	assert.Contains(t, err.Error(), "not https")
+	cfg.Header = nil
+	_, err = New(context.Background(), cfg)
+	assert.Contains(t, err.Error(), "401")
+	cfg.Header = http.Header{"Authorization": {"Bearer token"}}
+	cfg.Format = FormatDotenv
+	_, err = New(context.Background(), cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package getenv
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SetFileMarker enables the indirection of the values that start with
+// marker, e.g. the contents of the file /run/secrets/db_password are used as
+// the value of DB_PASSWORD=@/run/secrets/db_password with the marker "@".
+// It complements WithFile for the platforms that can set only the variable of
+// the name. The contents are processed in the same way as the value of the
+// variable, and the value that starts with the doubled marker is used as is
+// without the first marker, e.g. "@@admin" is used as "@admin". The path is
+// read after the references in it are expanded. No value is read from the
+// file if marker is empty, which is the default.
+func SetFileMarker(marker string) {
+	defaultSet.SetFileMarker(marker)
+}
+
+// SetFileMarker sets the marker of the indirection of the set like the
+// SetFileMarker function. The sets returned by Sub after the call inherit the
+// marker.
+func (s *EnvSet) SetFileMarker(marker string) {
+	s.fileMarker = marker
+}
+
+// indirect returns the contents of the file if the value v of the variable of
+// name starts with the marker of the set, and false if the contents are empty
+// like lookupValue.
+func (s *EnvSet) indirect(name, v string, noTrim, allowEmpty bool) (string, bool, error) {
+	marker := s.fileMarker
+	if marker == "" || !strings.HasPrefix(v, marker) {
+		return v, true, nil
+	} else if strings.HasPrefix(v[len(marker):], marker) {
+		return v[len(marker):], true, nil
+	}
+
+	b, err := os.ReadFile(v[len(marker):])
+	if err != nil {
+		return "", false, fmt.Errorf("%w: %q %w", ErrEnvVar, name, err)
+	}
+	v = string(b)
+	if !noTrim {
+		v = strings.TrimSpace(v)
+	}
+	return v, v != "" || allowEmpty, nil
+}
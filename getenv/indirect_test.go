@@ -0,0 +1,82 @@
+package getenv
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFileMarker(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	dir := t.TempDir()
+	pathname := filepath.Join(dir, "password")
+	assert.NoError(t, os.WriteFile(pathname, []byte(" secret\n"), 0600))
+	empty := filepath.Join(dir, "empty")
+	assert.NoError(t, os.WriteFile(empty, []byte("\n"), 0600))
+
+	password := ""
+	var cfg struct {
+		Servers []struct {
+			Token string
+		} `env:"TEST_SERVER"`
+	}
+	assert.NoError(t, Set("TEST_PASSWORD", "", &password))
+	assert.NoError(t, Bind(&cfg))
+	m := map[string]string{
+		"TEST_PASSWORD":       "@" + pathname,
+		"TEST_SERVER_0_TOKEN": "@" + pathname,
+	}
+
+	// test that no file is read by default
+	assert.NoError(t, ParseMap(m))
+	assert.Equal(t, "@"+pathname, password)
+	assert.Equal(t, "@"+pathname, cfg.Servers[0].Token)
+
+	// test that the values are read from the files
+	SetFileMarker("@")
+	assert.NoError(t, ParseMap(m))
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, "secret", cfg.Servers[0].Token)
+	assert.NoError(t, ParseValue("TEST_PASSWORD", "@"+pathname))
+	assert.Equal(t, "secret", password)
+
+	// test that the doubled marker escapes the marker
+	assert.NoError(t, ParseMap(map[string]string{"TEST_PASSWORD": "@@admin"}))
+	assert.Equal(t, "@admin", password)
+
+	// test that the sub sets inherit the marker
+	assert.Equal(t, "@", WithPrefix("TEST_").fileMarker)
+
+	// test that the path is read after the references are expanded
+	s := NewEnvSet()
+	s.SetFileMarker("file:")
+	s.SetExpansion(ExpandBraces)
+	assert.NoError(t, s.Set("TEST_PASSWORD", "", &password, WithNoTrim()))
+	assert.NoError(t, s.ParseMap(map[string]string{
+		"SECRETS_DIR":   dir,
+		"TEST_PASSWORD": "file:${SECRETS_DIR}/password",
+	}))
+	assert.Equal(t, " secret\n", password)
+
+	// test that the empty contents are treated as undefined
+	s = NewEnvSet()
+	s.SetFileMarker("@")
+	assert.NoError(t, s.Set("TEST_PASSWORD", "", &password, WithRequired()))
+	err := s.ParseMap(map[string]string{"TEST_PASSWORD": "@" + empty})
+	assert.True(t, errors.Is(err, ErrNotDefined))
+	err = s.ParseValue("TEST_PASSWORD", "@"+empty)
+	assert.True(t, errors.Is(err, ErrNotDefined))
+
+	// test that the error of reading the file identifies the variable
+	err = s.ParseMap(map[string]string{"TEST_PASSWORD": "@" + filepath.Join(dir, "missing")})
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Contains(t, err.Error(), `"TEST_PASSWORD"`)
+}
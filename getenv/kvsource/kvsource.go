@@ -0,0 +1,163 @@
+// Package kvsource provides the getenv.Source that resolves the variables
+// from the key/value store of Consul or etcd under the prefix, so that the
+// configuration can be shared by the fleet while each host overrides it by
+// the environment variables.
+//
+//	src, err := kvsource.NewConsul(ctx, kvsource.Config{
+//		Address: "http://127.0.0.1:8500",
+//		Prefix:  "config/myapp/",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	getenv.SetSources(getenv.EnvSource(), src)
+//
+// The keys under the prefix are converted to the variable names by
+// converting to upper case and replacing "/", "-" and "." with "_", e.g.
+// "config/myapp/db/host" is resolved as DB_HOST with the prefix
+// "config/myapp/".
+package kvsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mah0x211/go-getenv/getenv"
+)
+
+// Config configures the Source.
+type Config struct {
+	// address of the HTTP API such as "http://127.0.0.1:8500" for Consul and
+	// "http://127.0.0.1:2379" for etcd
+	Address string
+	// prefix of the keys
+	Prefix string
+	// ACL token of Consul or the auth token of etcd
+	Token string
+	// http.DefaultClient is used if nil
+	HTTPClient *http.Client
+}
+
+func (c *Config) init() {
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	c.Address = strings.TrimRight(c.Address, "/")
+}
+
+// keyName converts the key without the prefix to the variable name.
+func keyName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, strings.ToUpper(strings.Trim(key, "/")))
+}
+
+// do sends the request and decodes the JSON response into res. The ok is
+// false if the response is 404 Not Found.
+func do(c *http.Client, req *http.Request, res interface{}) (ok bool, err error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	} else if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("%s %s: %s %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(b))
+	}
+	return true, json.NewDecoder(resp.Body).Decode(res)
+}
+
+// NewConsul returns the Source named "consul" that supplies the values of
+// the keys under the prefix fetched from the KV store of Consul.
+func NewConsul(ctx context.Context, cfg Config) (getenv.Source, error) {
+	cfg.init()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		cfg.Address+"/v1/kv/"+(&url.URL{Path: cfg.Prefix}).EscapedPath()+"?recurse=true", nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul: %w", err)
+	} else if cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", cfg.Token)
+	}
+
+	var pairs []struct {
+		Key   string
+		Value []byte
+	}
+	if _, err = do(cfg.HTTPClient, req, &pairs); err != nil {
+		return nil, fmt.Errorf("consul: %w", err)
+	}
+
+	m := getenv.MapLookuper{}
+	for _, p := range pairs {
+		// the folders have no value
+		if name := keyName(strings.TrimPrefix(p.Key, cfg.Prefix)); name != "" && p.Value != nil {
+			m[name] = string(p.Value)
+		}
+	}
+	return getenv.NewSource("consul", m), nil
+}
+
+// NewEtcd returns the Source named "etcd" that supplies the values of the
+// keys under the prefix fetched by the JSON gateway of etcd v3.
+func NewEtcd(ctx context.Context, cfg Config) (getenv.Source, error) {
+	cfg.init()
+	b, err := json.Marshal(map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(cfg.Prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(rangeEnd(cfg.Prefix)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("etcd: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Address+"/v3/kv/range", bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("etcd: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", cfg.Token)
+	}
+
+	var res struct {
+		Kvs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	if _, err = do(cfg.HTTPClient, req, &res); err != nil {
+		return nil, fmt.Errorf("etcd: %w", err)
+	}
+
+	m := getenv.MapLookuper{}
+	for _, kv := range res.Kvs {
+		if name := keyName(strings.TrimPrefix(string(kv.Key), cfg.Prefix)); name != "" {
+			m[name] = string(kv.Value)
+		}
+	}
+	return getenv.NewSource("etcd", m), nil
+}
+
+// rangeEnd returns the end of the range of the keys that have the prefix.
+func rangeEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// all the keys
+	return []byte{0}
+}
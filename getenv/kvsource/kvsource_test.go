@@ -0,0 +1,88 @@
+package kvsource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConsul(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("ACL not found"))
+			return
+		} else if r.URL.Path != "/v1/kv/config/my app/" || r.URL.Query().Get("recurse") != "true" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"Key": "config/my app/", "Value": nil},
+			{"Key": "config/my app/db/host", "Value": []byte("db.example.com")},
+			{"Key": "config/my app/log-level", "Value": []byte("info")},
+		})
+	}))
+	defer srv.Close()
+
+	// test that converts the keys under the prefix to the names
+	src, err := NewConsul(context.Background(), Config{Address: srv.URL + "/", Prefix: "config/my app/", Token: "token"})
+	assert.NoError(t, err)
+	assert.Equal(t, "consul", src.Name())
+	v, _ := src.Lookup("DB_HOST")
+	assert.Equal(t, "db.example.com", v)
+	v, _ = src.Lookup("LOG_LEVEL")
+	assert.Equal(t, "info", v)
+
+	// test that no key is found
+	src, err = NewConsul(context.Background(), Config{Address: srv.URL, Prefix: "none/", Token: "token"})
+	assert.NoError(t, err)
+	_, ok := src.Lookup("DB_HOST")
+	assert.False(t, ok)
+
+	// test that returns the error of the API
+	_, err = NewConsul(context.Background(), Config{Address: srv.URL, Prefix: "config/my app/"})
+	assert.Contains(t, err.Error(), "ACL not found")
+}
+
+func TestNewEtcd(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Key      []byte `json:"key"`
+			RangeEnd []byte `json:"range_end"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v3/kv/range" || string(req.Key) != "/myapp/" || string(req.RangeEnd) != "/myapp0" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"kvs": []map[string][]byte{
+				{"key": []byte("/myapp/db.host"), "value": []byte("db.example.com")},
+				{"key": []byte("/myapp/PORT"), "value": []byte("8080")},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	// test that converts the keys under the prefix to the names
+	src, err := NewEtcd(context.Background(), Config{Address: srv.URL, Prefix: "/myapp/"})
+	assert.NoError(t, err)
+	assert.Equal(t, "etcd", src.Name())
+	v, _ := src.Lookup("DB_HOST")
+	assert.Equal(t, "db.example.com", v)
+	v, _ = src.Lookup("PORT")
+	assert.Equal(t, "8080", v)
+
+	// test that returns the error of the API
+	_, err = NewEtcd(context.Background(), Config{Address: srv.URL, Prefix: "/other/"})
+	assert.Contains(t, err.Error(), "400")
+
+	// test that returns the end of the range
+	assert.Equal(t, []byte("b"), rangeEnd("a"))
+	assert.Equal(t, []byte("b"), rangeEnd("a\xff"))
+	assert.Equal(t, []byte{0}, rangeEnd(""))
+}
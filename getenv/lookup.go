@@ -0,0 +1,215 @@
+package getenv
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Lookuper looks up the value of the environment variable of name. The ok is
+// false if the variable is not defined.
+type Lookuper interface {
+	Lookup(name string) (v string, ok bool)
+}
+
+// EnvironLookuper is the Lookuper that also lists all the defined variables
+// in the form of "NAME=VALUE" like os.Environ. The variables collected by the
+// prefix are read only from the EnvironLookuper.
+type EnvironLookuper interface {
+	Lookuper
+	Environ() []string
+}
+
+// ContextLookuper is the Lookuper that looks up the value with the context,
+// e.g. from the remote store on demand. ParseContext calls LookupContext
+// instead of Lookup to respect the deadline and the cancellation of the
+// context, and fails if err is not nil.
+type ContextLookuper interface {
+	Lookuper
+	LookupContext(ctx context.Context, name string) (v string, ok bool, err error)
+}
+
+// LookupFunc is the adapter to use the function as the Lookuper.
+type LookupFunc func(name string) (string, bool)
+
+// Lookup calls fn(name).
+func (fn LookupFunc) Lookup(name string) (string, bool) {
+	return fn(name)
+}
+
+// OSLookuper looks up the variables from the process environment. It is used
+// by Parse by default.
+type OSLookuper struct{}
+
+// Lookup calls os.LookupEnv.
+func (OSLookuper) Lookup(name string) (string, bool) {
+	return os.LookupEnv(name)
+}
+
+// Environ calls os.Environ.
+func (OSLookuper) Environ() []string {
+	return os.Environ()
+}
+
+// MapLookuper looks up the variables from the map of the names and the
+// values.
+type MapLookuper map[string]string
+
+// Lookup returns the value of name in the map.
+func (m MapLookuper) Lookup(name string) (string, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+// Environ returns the entries of the map sorted by the names.
+func (m MapLookuper) Environ() []string {
+	list := make([]string, 0, len(m))
+	for name, v := range m {
+		list = append(list, name+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// MultiLookuper returns the Lookuper that looks up the variables from ls in
+// order and returns the first defined value, so that the earlier Lookuper
+// takes precedence. The variables are listed from ls that are the
+// EnvironLookuper.
+func MultiLookuper(ls ...Lookuper) EnvironLookuper {
+	return multiLookuper(ls)
+}
+
+type multiLookuper []Lookuper
+
+func (ml multiLookuper) Lookup(name string) (string, bool) {
+	for _, l := range ml {
+		if v, ok := l.Lookup(name); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (ml multiLookuper) Environ() []string {
+	m := MapLookuper{}
+	for i := len(ml) - 1; i >= 0; i-- {
+		if el, ok := ml[i].(EnvironLookuper); ok {
+			for name, v := range environMap(el.Environ()) {
+				m[name] = v
+			}
+		}
+	}
+	return m.Environ()
+}
+
+// ParseMap is like Parse but reads the values from m instead of the process
+// environment.
+func ParseMap(m map[string]string, names ...string) error {
+	return defaultSet.ParseMap(m, names...)
+}
+
+// ParseMap reads the variables of the set from m like the ParseMap function.
+func (s *EnvSet) ParseMap(m map[string]string, names ...string) error {
+	return s.ParseFrom(MapLookuper(m), names...)
+}
+
+// ParseEnviron is like Parse but reads the values from environ, the list of
+// the "NAME=VALUE" entries such as os.Environ returns. The entries without
+// "=" or the name are ignored, and the last one wins if the name appears more
+// than once.
+func ParseEnviron(environ []string, names ...string) error {
+	return defaultSet.ParseEnviron(environ, names...)
+}
+
+// ParseEnviron reads the variables of the set from environ like the
+// ParseEnviron function.
+func (s *EnvSet) ParseEnviron(environ []string, names ...string) error {
+	return s.ParseFrom(environMap(environ), names...)
+}
+
+// environMap converts the list of the "NAME=VALUE" entries into the map.
+func environMap(environ []string) MapLookuper {
+	m := MapLookuper{}
+	for _, kv := range environ {
+		if name, v, ok := strings.Cut(kv, "="); ok && name != "" {
+			m[name] = v
+		}
+	}
+	return m
+}
+
+// FileSuffix is the suffix of the name of the variable that specifies the
+// file to read the value from, e.g. DB_PASSWORD_FILE for DB_PASSWORD.
+const FileSuffix = "_FILE"
+
+// ErrFileConflict is returned by Parse if both the variable and the variable
+// of the file are defined.
+var ErrFileConflict = fmt.Errorf("both the environment variable and the file variable are defined")
+
+// lookupFileValue is like lookupValue but reads the value from the file
+// specified by the variable of name+FileSuffix if file is true and the
+// variable of name is not defined. ErrFileConflict is returned if both are
+// defined.
+func lookupFileValue(ctx context.Context, l Lookuper, name string, noTrim, allowEmpty, file bool) (v, src string, ok bool, err error) {
+	if v, src, ok, err = lookupValue(ctx, l, name, noTrim, allowEmpty); err != nil || !file {
+		return v, src, ok, err
+	}
+
+	fileName := name + FileSuffix
+	path, fileSrc, found, err := lookupValue(ctx, l, fileName, false, false)
+	if err != nil {
+		return "", "", false, err
+	} else if !found {
+		return v, src, ok, nil
+	} else if ok {
+		return "", "", false, fmt.Errorf("%w: %q and %q", ErrFileConflict, name, fileName)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false, fmt.Errorf("%w: %q %w", ErrEnvVar, fileName, err)
+	}
+	v = string(b)
+	if !noTrim {
+		v = strings.TrimSpace(v)
+	}
+	return v, fileSrc, v != "" || allowEmpty, nil
+}
+
+// lookupValue returns the value of the variable of name and the name of the
+// Source that supplied it, and false if it is not defined or the value is
+// empty. The value is trimmed unless noTrim is true, and the empty value is
+// returned with true if allowEmpty is true.
+func lookupValue(ctx context.Context, l Lookuper, name string, noTrim, allowEmpty bool) (v, src string, ok bool, err error) {
+	if v, src, ok, err = lookupSource(ctx, l, name); err != nil {
+		return "", "", false, fmt.Errorf("%w: %q %w", ErrEnvVar, name, err)
+	} else if !ok {
+		return "", "", false, nil
+	} else if !noTrim {
+		v = strings.TrimSpace(v)
+	}
+	return v, src, v != "" || allowEmpty, nil
+}
+
+// lookupPrefix returns the variables that have the prefix with the prefix
+// stripped names. The variables with the empty value are ignored, and nothing
+// is found unless l is the EnvironLookuper.
+func lookupPrefix(l Lookuper, prefix string) map[string]string {
+	found := map[string]string{}
+	el, ok := l.(EnvironLookuper)
+	if !ok {
+		return found
+	}
+	_, fold := l.(foldLookuper)
+	for _, kv := range el.Environ() {
+		name, v, _ := strings.Cut(kv, "=")
+		if len(name) > len(prefix) && (strings.HasPrefix(name, prefix) || fold && strings.EqualFold(name[:len(prefix)], prefix)) {
+			if v = strings.TrimSpace(v); v != "" {
+				found[name[len(prefix):]] = v
+			}
+		}
+	}
+	return found
+}
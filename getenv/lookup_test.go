@@ -0,0 +1,234 @@
+package getenv
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFrom(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+	}()
+
+	port := 80
+	headers := map[string]string{}
+	var cfg struct {
+		Servers []struct {
+			Host string
+		} `env:"TEST_SERVER"`
+	}
+	assert.NoError(t, Set("TEST_PORT", "", &port, WithRequired()))
+	assert.NoError(t, CollectPrefix("TEST_HEADER_", "", &headers))
+	assert.NoError(t, Bind(&cfg))
+
+	// test that reads the values from the Lookuper instead of the environment
+	os.Setenv("TEST_PORT", "9000")
+	assert.NoError(t, ParseFrom(MapLookuper{
+		"TEST_PORT":          " 8080 ",
+		"TEST_HEADER_ACCEPT": "text/plain",
+		"TEST_SERVER_0_HOST": "example.com",
+	}))
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, map[string]string{"ACCEPT": "text/plain"}, headers)
+	assert.Len(t, cfg.Servers, 1)
+	assert.Equal(t, "example.com", cfg.Servers[0].Host)
+
+	// test that the prefixes are not collected unless the Lookuper lists the
+	// variables
+	fn := LookupFunc(func(name string) (string, bool) {
+		if name == "TEST_PORT" {
+			return "8443", true
+		}
+		return "", false
+	})
+	assert.NoError(t, ParseFrom(fn))
+	assert.Equal(t, 8443, port)
+	assert.Equal(t, map[string]string{"ACCEPT": "text/plain"}, headers)
+
+	// test that returns ErrNotDefined if the required variable is not found
+	assert.True(t, errors.Is(ParseFrom(MapLookuper{}), ErrNotDefined))
+
+	// test that reads only the specified names
+	assert.NoError(t, ParseFrom(MapLookuper{"TEST_PORT": "1"}, "TEST_PORT"))
+	assert.Equal(t, 1, port)
+	assert.True(t, errors.Is(ParseFrom(MapLookuper{}, "TEST_UNKNOWN"), ErrNotRegistered))
+
+	// test that OSLookuper reads the process environment
+	assert.NoError(t, ParseFrom(OSLookuper{}, "TEST_PORT"))
+	assert.Equal(t, 9000, port)
+}
+
+// contextLookuper looks up the values from the map by LookupContext, and
+// records the contexts passed to it.
+type contextLookuper struct {
+	MapLookuper
+	ctxs []context.Context
+	err  error
+}
+
+func (l *contextLookuper) LookupContext(ctx context.Context, name string) (string, bool, error) {
+	l.ctxs = append(l.ctxs, ctx)
+	if l.err != nil {
+		return "", false, l.err
+	} else if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	v, ok := l.Lookup(name)
+	return v, ok, nil
+}
+
+func TestParseContext(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	type ctxKey struct{}
+	var parsed, checked []interface{}
+	host := "localhost"
+	port := 80
+	headers := map[string]string{}
+	assert.NoError(t, Set("TEST_HOST", "", &host, WithParseContext(func(ctx context.Context, iv interface{}, envName, envValue string) error {
+		parsed = append(parsed, ctx.Value(ctxKey{}))
+		*iv.(*string) = envValue
+		return nil
+	})))
+	assert.NoError(t, Set("TEST_PORT", "", &port, WithCheckContext(func(ctx context.Context, iv interface{}, envName string) error {
+		checked = append(checked, ctx.Value(ctxKey{}))
+		return nil
+	})))
+	assert.NoError(t, CollectPrefix("TEST_HEADER_", "", &headers))
+	l := &contextLookuper{MapLookuper: MapLookuper{
+		"TEST_HOST":          "example.com",
+		"TEST_PORT":          "8080",
+		"TEST_HEADER_ACCEPT": "text/plain",
+	}}
+
+	// test that the context is passed to the ContextLookuper, the parser and
+	// the checker
+	ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+	assert.NoError(t, ParseFromContext(ctx, NewSource("remote", l)))
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, map[string]string{"ACCEPT": "text/plain"}, headers)
+	assert.Equal(t, []interface{}{"v"}, parsed)
+	assert.Equal(t, []interface{}{"v"}, checked)
+	assert.NotEmpty(t, l.ctxs)
+	for _, c := range l.ctxs {
+		assert.Equal(t, "v", c.Value(ctxKey{}))
+	}
+
+	// test that Parse passes the background context to the parser
+	parsed = nil
+	assert.NoError(t, ParseMap(map[string]string{"TEST_HOST": "example.net"}))
+	assert.Equal(t, "example.net", host)
+	assert.Equal(t, []interface{}{nil}, parsed)
+
+	// test that nothing is changed if the context is done
+	cctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.MapLookuper = MapLookuper{"TEST_HOST": "example.org", "TEST_PORT": "9000"}
+	err := ParseFromContext(cctx, l)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, "example.net", host)
+	assert.Equal(t, 8080, port)
+
+	// test that the context is also respected for the Lookuper that is not
+	// the ContextLookuper
+	err = ParseFromContext(cctx, MapLookuper{"TEST_HOST": "example.org"})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, "example.net", host)
+
+	// test that the error of the ContextLookuper is returned with the name
+	// of the variable and the Source
+	l.err = errors.New("connection refused")
+	err = ParseFromContext(context.Background(), Chain{NewSource("remote", l)})
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.True(t, errors.Is(err, l.err))
+	assert.Contains(t, err.Error(), `"TEST_HOST" remote: connection refused`)
+	assert.Equal(t, "example.net", host)
+
+	// test that ParseContext reads the Sources of the set
+	l.err = nil
+	SetSources(NewSource("remote", l))
+	assert.NoError(t, ParseContext(ctx, "TEST_HOST"))
+	assert.Equal(t, "example.org", host)
+}
+
+func TestParseMap(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	// test that Environ lists the entries in order
+	assert.Equal(t, []string{"A=1", "B=2"}, MapLookuper{"B": "2", "A": "1"}.Environ())
+
+	host := "localhost"
+	headers := map[string]string{}
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+	assert.NoError(t, CollectPrefix("TEST_HEADER_", "", &headers))
+
+	// test that reads the values from the map without the environment
+	assert.NoError(t, ParseMap(map[string]string{
+		"TEST_HOST":          "example.com",
+		"TEST_HEADER_ACCEPT": "*/*",
+	}))
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, map[string]string{"ACCEPT": "*/*"}, headers)
+	_, ok := os.LookupEnv("TEST_HOST")
+	assert.False(t, ok)
+
+	// test that the name is prefixed by the set
+	assert.NoError(t, WithPrefix("TEST_").ParseMap(map[string]string{"TEST_HOST": "example.org"}, "HOST"))
+	assert.Equal(t, "example.org", host)
+}
+
+func TestParseEnviron(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	host := "localhost"
+	opts := ""
+	port := 80
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+	assert.NoError(t, Set("TEST_OPTS", "", &opts, WithNoTrim()))
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+
+	// test that the last entry wins and the invalid entries are ignored
+	assert.NoError(t, ParseEnviron([]string{
+		"TEST_HOST=example.com",
+		"TEST_HOST=example.org",
+		"TEST_OPTS= a=b ",
+		"TEST_PORT",
+		"=C:=C:\\",
+	}))
+	assert.Equal(t, "example.org", host)
+	assert.Equal(t, " a=b ", opts)
+	assert.Equal(t, 80, port)
+
+	// test that returns ErrEnvVar if the value is invalid
+	assert.True(t, errors.Is(ParseEnviron([]string{"TEST_PORT=http"}), ErrEnvVar))
+}
+
+func TestMultiLookuper(t *testing.T) {
+	l := MultiLookuper(MapLookuper{"A": "1", "B": ""}, LookupFunc(func(name string) (string, bool) {
+		return "fn", name == "C"
+	}), MapLookuper{"A": "2", "B": "2", "D": "2"})
+
+	// test that the earlier Lookuper takes precedence
+	for name, want := range map[string]string{"A": "1", "B": "", "C": "fn", "D": "2"} {
+		v, ok := l.Lookup(name)
+		assert.True(t, ok)
+		assert.Equal(t, want, v)
+	}
+	_, ok := l.Lookup("E")
+	assert.False(t, ok)
+
+	// test that lists the variables of the EnvironLookuper only
+	assert.Equal(t, []string{"A=1", "B=", "D=2"}, l.Environ())
+}
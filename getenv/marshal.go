@@ -0,0 +1,279 @@
+package getenv
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// typeFormatter formats the value of ref into the string that can be parsed
+// by the corresponding typeParser.
+type typeFormatter func(ref reflect.Value) string
+
+var typeFormatters = map[reflect.Type]typeFormatter{
+	durationType: func(ref reflect.Value) string {
+		return time.Duration(ref.Int()).String()
+	},
+	ipNetType: func(ref reflect.Value) string {
+		v := ref.Interface().(net.IPNet)
+		return v.String()
+	},
+	reflect.TypeOf(url.URL{}): func(ref reflect.Value) string {
+		v := ref.Interface().(url.URL)
+		return v.String()
+	},
+	reflect.TypeOf(time.Time{}): func(ref reflect.Value) string {
+		return ref.Interface().(time.Time).Format(time.RFC3339Nano)
+	},
+	reflect.TypeOf(&regexp.Regexp{}): func(ref reflect.Value) string {
+		return ref.Interface().(*regexp.Regexp).String()
+	},
+	reflect.TypeOf(big.Int{}): func(ref reflect.Value) string {
+		return addrOf(ref).Interface().(*big.Int).String()
+	},
+	reflect.TypeOf(big.Float{}): func(ref reflect.Value) string {
+		return addrOf(ref).Interface().(*big.Float).Text('g', -1)
+	},
+	reflect.TypeOf(big.Rat{}): func(ref reflect.Value) string {
+		return addrOf(ref).Interface().(*big.Rat).RatString()
+	},
+	reflect.TypeOf([]byte(nil)): func(ref reflect.Value) string {
+		return base64.StdEncoding.EncodeToString(ref.Bytes())
+	},
+	reflect.TypeOf(&time.Location{}): func(ref reflect.Value) string {
+		return ref.Interface().(*time.Location).String()
+	},
+	reflect.TypeOf(os.FileMode(0)): func(ref reflect.Value) string {
+		mode := os.FileMode(ref.Uint())
+		v := uint32(mode & os.ModePerm)
+		if mode&os.ModeSetuid != 0 {
+			v |= 04000
+		}
+		if mode&os.ModeSetgid != 0 {
+			v |= 02000
+		}
+		if mode&os.ModeSticky != 0 {
+			v |= 01000
+		}
+		return fmt.Sprintf("%04o", v)
+	},
+	reflect.TypeOf(mail.Address{}): func(ref reflect.Value) string {
+		v := ref.Interface().(mail.Address)
+		return v.String()
+	},
+}
+
+// addrOf returns the pointer to the value of ref. If ref is not addressable,
+// the pointer to the copy of the value is returned.
+func addrOf(ref reflect.Value) reflect.Value {
+	if ref.CanAddr() {
+		return ref.Addr()
+	}
+	v := reflect.New(ref.Type())
+	v.Elem().Set(ref)
+	return v
+}
+
+// valueFormatter is implemented by the types that format their value by
+// themselves. The ok is false if the value is not set.
+type valueFormatter interface {
+	formatValue(sep string) (s string, ok bool, err error)
+}
+
+// escapeValue escapes the backslash and the separators in s with backslash.
+// The value that has the leading or trailing spaces is enclosed in double
+// quotes since the parser trims the elements, and the leading double quote is
+// escaped otherwise.
+func escapeValue(s string, seps ...string) string {
+	quote := strings.TrimSpace(s) != s
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	for _, sep := range seps {
+		s = strings.ReplaceAll(s, sep, `\`+sep)
+	}
+	if quote {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	} else if strings.HasPrefix(s, `"`) {
+		return `\` + s
+	}
+	return s
+}
+
+// formatValue formats the value of ref into the string that can be parsed by
+// the default parser with the separator sep. The ok is false if the value is
+// a nil pointer or is not set.
+func formatValue(ref reflect.Value, sep string) (s string, ok bool, err error) {
+	t := ref.Type()
+	if t.Kind() == reflect.Ptr && ref.IsNil() {
+		return "", false, nil
+	} else if f, found := typeFormatters[t]; found {
+		return f(ref), true, nil
+	}
+
+	// the types implementing the interfaces with the value receiver or the
+	// pointer receiver
+	v := ref
+	if t.Kind() != reflect.Ptr {
+		v = addrOf(ref)
+	}
+	switch vi := v.Interface().(type) {
+	case valueFormatter:
+		return vi.formatValue(sep)
+	case encoding.TextMarshaler:
+		b, err := vi.MarshalText()
+		if err != nil {
+			return "", false, err
+		}
+		return string(b), true, nil
+	case driver.Valuer:
+		dv, err := vi.Value()
+		if err != nil {
+			return "", false, err
+		} else if dv == nil {
+			// NULL value
+			return "", false, nil
+		} else if b, ok := dv.([]byte); ok {
+			return string(b), true, nil
+		} else if tv, ok := dv.(time.Time); ok {
+			return tv.Format(time.RFC3339Nano), true, nil
+		}
+		return fmt.Sprint(dv), true, nil
+	case flag.Value:
+		return vi.String(), true, nil
+	case fmt.Stringer:
+		// the String method of the basic kinds may not be parsable unless
+		// the type implements encoding.TextUnmarshaler
+		if t.Kind() == reflect.Struct || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+			return vi.String(), true, nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return ref.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(ref.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(ref.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(ref.Uint(), 10), true, nil
+	case reflect.Float32:
+		return strconv.FormatFloat(ref.Float(), 'g', -1, 32), true, nil
+	case reflect.Float64:
+		return strconv.FormatFloat(ref.Float(), 'g', -1, 64), true, nil
+	case reflect.Complex64:
+		return strconv.FormatComplex(ref.Complex(), 'g', -1, 64), true, nil
+	case reflect.Complex128:
+		return strconv.FormatComplex(ref.Complex(), 'g', -1, 128), true, nil
+
+	case reflect.Slice:
+		list := make([]string, 0, ref.Len())
+		for i := 0; i < ref.Len(); i++ {
+			v, ok, err := formatValue(ref.Index(i), sep)
+			if err != nil {
+				return "", false, fmt.Errorf("element %d: %w", i, err)
+			} else if ok {
+				list = append(list, escapeValue(v, sep))
+			}
+		}
+		return strings.Join(list, sep), true, nil
+
+	case reflect.Map:
+		keys := make([]string, 0, ref.Len())
+		key2val := map[string]string{}
+		iter := ref.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			v, ok, err := formatValue(iter.Value(), sep)
+			if err != nil {
+				return "", false, fmt.Errorf("value of key %q: %w", k, err)
+			} else if ok {
+				keys = append(keys, k)
+				key2val[k] = v
+			}
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			keys[i] = escapeValue(k, sep, "=") + "=" + escapeValue(key2val[k], sep)
+		}
+		return strings.Join(keys, sep), true, nil
+
+	case reflect.Ptr:
+		return formatValue(ref.Elem(), sep)
+	}
+
+	return "", false, fmt.Errorf("%w: cannot format %v", ErrValue, t)
+}
+
+// Marshal renders the current values of the fields of the struct pointed to
+// by v into the map of the environment variable names and the values, which
+// can be parsed back by Bind and Parse. The names are derived in the same way
+// as Bind, and the indexed variables are rendered for each element of the
+// slice of struct. The nil pointers and the unset Optional values are
+// omitted.
+func Marshal(v interface{}) (map[string]string, error) {
+	return MarshalWithNaming(v, nil)
+}
+
+// MarshalWithNaming is like Marshal but derives the names of the fields by
+// namefn. If namefn is nil, ScreamingSnakeCase is used.
+func MarshalWithNaming(v interface{}, namefn NamingFunc) (map[string]string, error) {
+	if namefn == nil {
+		namefn = ScreamingSnakeCase
+	}
+
+	ref := reflect.ValueOf(v)
+	if ref.Kind() == reflect.Ptr && !ref.IsNil() {
+		ref = ref.Elem()
+	}
+	if ref.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T is not a struct or a pointer to struct", ErrValue, v)
+	}
+
+	m := map[string]string{}
+	b := &binder{namefn: namefn, readonly: true}
+	if err := b.collect(addrOf(ref).Elem(), ""); err != nil {
+		return nil, err
+	} else if err = b.render(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MarshalRegistered renders the current values of the registered variables
+// into the map of the environment variable names and the values.
+func MarshalRegistered() (map[string]string, error) {
+	return defaultSet.MarshalRegistered()
+}
+
+// MarshalRegistered renders the current values of the variables of the set
+// like the MarshalRegistered function.
+func (s *EnvSet) MarshalRegistered() (map[string]string, error) {
+	m := map[string]string{}
+	for name, env := range s.envs() {
+		v, ok, err := formatValue(reflect.ValueOf(env.Value).Elem(), env.separator())
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", name, err)
+		} else if ok {
+			m[name] = v
+		}
+	}
+	for _, c := range s.collectors() {
+		if err := c.render(m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
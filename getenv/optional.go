@@ -0,0 +1,75 @@
+package getenv
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Optional holds the value of T and whether it has been set from the
+// environment variable. It can be registered as the value to distinguish
+// the unset variable from the variable set to the zero value.
+type Optional[T any] struct {
+	value T
+	set   bool
+}
+
+// Some returns the Optional that is set to v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, set: true}
+}
+
+// IsSet returns true if the value has been set.
+func (o Optional[T]) IsSet() bool {
+	return o.set
+}
+
+// Get returns the value and whether it has been set. If it has not been set,
+// the zero value of T will be returned.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.set
+}
+
+// GetOr returns the value if it has been set, otherwise returns def.
+func (o Optional[T]) GetOr(def T) T {
+	if o.set {
+		return o.value
+	}
+	return def
+}
+
+// String returns the string representation of the value, or an empty string
+// if it has not been set.
+func (o Optional[T]) String() string {
+	if !o.set {
+		return ""
+	}
+	return fmt.Sprint(o.value)
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The text
+// is parsed in the same way as the default parser.
+func (o *Optional[T]) UnmarshalText(b []byte) error {
+	return o.parseValue(defaultParser, "", string(b))
+}
+
+func (o *Optional[T]) elemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (o *Optional[T]) parseValue(p parser, envName, envValue string) error {
+	var v T
+	if _, err := checkValue(&v, false); err != nil {
+		return err
+	} else if err := p.parse(&v, envName, envValue); err != nil {
+		return err
+	}
+	o.value, o.set = v, true
+	return nil
+}
+
+func (o *Optional[T]) formatValue(sep string) (string, bool, error) {
+	if !o.set {
+		return "", false, nil
+	}
+	return formatValue(reflect.ValueOf(&o.value).Elem(), sep)
+}
@@ -0,0 +1,85 @@
+package getenv
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptional(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_OPTIONAL_INT")
+		os.Unsetenv("TEST_OPTIONAL_STRS")
+	}()
+
+	// test that the value is not set if the environment variable is not defined
+	var intv Optional[int]
+	var strsv Optional[[]string]
+	assert.NoError(t, Set("TEST_OPTIONAL_INT", "", &intv))
+	assert.NoError(t, Set("TEST_OPTIONAL_STRS", "", &strsv, WithParse(SliceParseFunc(";"))))
+	assert.NoError(t, Parse())
+	assert.False(t, intv.IsSet())
+	v, ok := intv.Get()
+	assert.Equal(t, 0, v)
+	assert.False(t, ok)
+	assert.Equal(t, 8080, intv.GetOr(8080))
+	assert.Equal(t, "", intv.String())
+
+	// test that the value is set even if it is the zero value
+	os.Setenv("TEST_OPTIONAL_INT", "0")
+	os.Setenv("TEST_OPTIONAL_STRS", "a;b")
+	assert.NoError(t, Parse())
+	assert.True(t, intv.IsSet())
+	v, ok = intv.Get()
+	assert.Equal(t, 0, v)
+	assert.True(t, ok)
+	assert.Equal(t, 0, intv.GetOr(8080))
+	assert.Equal(t, "0", intv.String())
+	strs, ok := strsv.Get()
+	assert.Equal(t, []string{"a", "b"}, strs)
+	assert.True(t, ok)
+
+	// test that keep the value if the value cannot be parsed
+	os.Setenv("TEST_OPTIONAL_INT", "foo")
+	assert.True(t, errors.Is(Parse(), ErrEnvVar))
+	assert.Equal(t, Some(0), intv)
+}
+
+func TestOptionalUnmarshalText(t *testing.T) {
+	// test that parse the text with the default parser
+	var v Optional[time.Duration]
+	assert.NoError(t, v.UnmarshalText([]byte("1m")))
+	assert.Equal(t, Some(time.Minute), v)
+	assert.Error(t, v.UnmarshalText([]byte("1")))
+	assert.Equal(t, Some(time.Minute), v)
+
+	// test that returns ErrValue if the type is not supported
+	var s Optional[struct{}]
+	err := s.UnmarshalText([]byte("{}"))
+	assert.True(t, errors.Is(err, ErrValue))
+	assert.Equal(t, ErrValue.Error()+": struct {}", err.Error())
+	assert.False(t, s.IsSet())
+}
+
+func TestSetOptional(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	// test that the element type is checked at the registration
+	var ok Optional[[]int]
+	var ptr *Optional[int]
+	assert.NoError(t, Set("TEST_OK", "", &ok))
+	assert.NoError(t, Set("TEST_PTR", "", &ptr))
+	for _, v := range []interface{}{
+		&Optional[struct{ A chan int }]{},
+		new(*Optional[chan int]),
+		new([]Optional[chan int]),
+	} {
+		assert.True(t, errors.Is(Set("TEST_NG", "", v), ErrValue), "%T", v)
+	}
+}
@@ -0,0 +1,95 @@
+package getenv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate represents the number of events per interval. It can be parsed from a
+// string such as "100/s", "5000/m", "1/h" or "10/500ms".
+type Rate struct {
+	Count    int64
+	Interval time.Duration
+}
+
+var rateUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+}
+
+// ParseRate parses the rate string in the form of "<count>/<interval>". The
+// interval is a unit such as "s", "m", "h" and "d", or a duration string such
+// as "10s" and "500ms".
+func ParseRate(s string) (Rate, error) {
+	i := strings.IndexByte(s, '/')
+	if i == -1 {
+		return Rate{}, fmt.Errorf("invalid rate %q: must be <count>/<interval>", s)
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(s[:i]), 10, 64)
+	if err != nil || count < 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: count must be a non-negative integer", s)
+	}
+
+	unit := strings.TrimSpace(s[i+1:])
+	interval, ok := rateUnits[unit]
+	if !ok {
+		if interval, err = time.ParseDuration(unit); err != nil {
+			return Rate{}, fmt.Errorf("invalid rate %q: %w", s, err)
+		}
+	}
+	if interval <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: interval must be positive", s)
+	}
+
+	return Rate{Count: count, Interval: interval}, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (r *Rate) UnmarshalText(b []byte) error {
+	v, err := ParseRate(string(b))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// String returns the rate in the form of "<count>/<interval>".
+func (r Rate) String() string {
+	for _, unit := range []string{"d", "h", "m", "s", "ms", "us", "ns"} {
+		if r.Interval == rateUnits[unit] {
+			return strconv.FormatInt(r.Count, 10) + "/" + unit
+		}
+	}
+	return strconv.FormatInt(r.Count, 10) + "/" + r.Interval.String()
+}
+
+// Per returns the number of events per d.
+func (r Rate) Per(d time.Duration) float64 {
+	if r.Interval <= 0 {
+		return 0
+	}
+	return float64(r.Count) * float64(d) / float64(r.Interval)
+}
+
+// PerSecond returns the number of events per second.
+func (r Rate) PerSecond() float64 {
+	return r.Per(time.Second)
+}
+
+// Every returns the interval between the events, or 0 if the count is 0.
+func (r Rate) Every() time.Duration {
+	if r.Count <= 0 {
+		return 0
+	}
+	return r.Interval / time.Duration(r.Count)
+}
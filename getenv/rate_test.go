@@ -0,0 +1,54 @@
+package getenv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	// test that parse rate strings
+	for s, exp := range map[string]Rate{
+		"100/s":    {100, time.Second},
+		"5000/m":   {5000, time.Minute},
+		"1/h":      {1, time.Hour},
+		"10/d":     {10, 24 * time.Hour},
+		"10/500ms": {10, 500 * time.Millisecond},
+		" 3 / 2s ": {3, 2 * time.Second},
+		"0/s":      {0, time.Second},
+	} {
+		v, err := ParseRate(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, exp, v, s)
+	}
+
+	// test that returns error if the value is invalid
+	for _, s := range []string{
+		"", "100", "/s", "-1/s", "1.5/s", "10/x", "10/0s", "10/-1s",
+	} {
+		_, err := ParseRate(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestRate(t *testing.T) {
+	// test that parse rate as encoding.TextUnmarshaler
+	var v Rate
+	assert.NoError(t, defaultParseFunc(&v, "RATE_LIMIT", "5000/m"))
+	assert.Equal(t, Rate{5000, time.Minute}, v)
+	assert.Error(t, defaultParseFunc(&v, "RATE_LIMIT", "5000"))
+	assert.Equal(t, Rate{5000, time.Minute}, v)
+
+	// test that normalize the rate
+	assert.InDelta(t, 83.333, v.PerSecond(), 0.001)
+	assert.Equal(t, float64(300000), v.Per(time.Hour))
+	assert.Equal(t, 12*time.Millisecond, v.Every())
+	assert.Equal(t, float64(0), Rate{}.PerSecond())
+	assert.Equal(t, time.Duration(0), Rate{}.Every())
+
+	// test that format the rate
+	assert.Equal(t, "5000/m", v.String())
+	assert.Equal(t, "10/500ms", Rate{10, 500 * time.Millisecond}.String())
+	assert.Equal(t, "1/d", Rate{1, 24 * time.Hour}.String())
+}
@@ -0,0 +1,17 @@
+package getenv
+
+// Register registers the environment variable of type T with the default
+// value def, and returns the pointer to the value that is updated by Parse.
+// It panics if the variable cannot be registered, e.g. the name is already
+// registered or T is not supported.
+func Register[T any](name, desc string, def T, opts ...Option) *T {
+	return RegisterTo(defaultSet, name, desc, def, opts...)
+}
+
+// RegisterTo registers the environment variable to the set like Register.
+func RegisterTo[T any](set *EnvSet, name, desc string, def T, opts ...Option) *T {
+	v := new(T)
+	*v = def
+	set.MustSet(name, desc, v, opts...)
+	return v
+}
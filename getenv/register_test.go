@@ -0,0 +1,57 @@
+package getenv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+		os.Unsetenv("TEST_TIMEOUT")
+		os.Unsetenv("TEST_LEVEL")
+	}()
+
+	// test that register the variables with the default values
+	port := Register("TEST_PORT", "listen port", 8080, WithRequired())
+	timeout := Register("TEST_TIMEOUT", "", 5*time.Second)
+	level := Register("TEST_LEVEL", "", 1, WithParse(IntParseFunc(16)),
+		WithCheck(func(iv interface{}, envName string) error {
+			if v := *iv.(*int); v > 0xff {
+				return fmt.Errorf("must be less than 0x100")
+			}
+			return nil
+		}))
+	assert.Equal(t, 8080, *port)
+	assert.Equal(t, 5*time.Second, *timeout)
+	assert.Equal(t, 1, *level)
+	env := defaultSet.name2envs["TEST_PORT"]
+	assert.Equal(t, "listen port", env.Description)
+	assert.Equal(t, 8080, env.DefaultValue)
+	assert.True(t, env.Required)
+
+	// test that Parse updates the values
+	os.Setenv("TEST_PORT", "9090")
+	os.Setenv("TEST_TIMEOUT", "1m")
+	os.Setenv("TEST_LEVEL", "ff")
+	assert.NoError(t, Parse())
+	assert.Equal(t, 9090, *port)
+	assert.Equal(t, time.Minute, *timeout)
+	assert.Equal(t, 0xff, *level)
+	os.Setenv("TEST_LEVEL", "100")
+	assert.True(t, errors.Is(Parse(), ErrEnvVar))
+
+	// test that panics if the variable cannot be registered
+	assert.PanicsWithError(t, `environment variable name is already registered: "TEST_PORT" already registered`, func() {
+		Register("TEST_PORT", "", 0)
+	})
+	assert.Panics(t, func() {
+		Register("TEST_CHAN", "", make(chan int))
+	})
+}
@@ -0,0 +1,94 @@
+package getenv
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy is the policy to retry the failed lookups of the Source with
+// the exponential backoff.
+type RetryPolicy struct {
+	// maximum number of the attempts including the first one, and no retry
+	// is made if it is less than 2
+	Attempts int
+	// delay before the first retry that is doubled for each retry
+	Backoff time.Duration
+	// upper limit of the delay, or no limit if zero
+	MaxBackoff time.Duration
+	// fraction of the delay to be randomized in the range of [0, 1], e.g.
+	// 0.2 makes the delay of 1s random between 0.8s and 1.2s
+	Jitter float64
+}
+
+// delay returns the delay before the n-th retry.
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < n && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Do calls fn until it succeeds or the attempts are exhausted, and returns
+// the last error. It stops waiting for the next attempt and returns the error
+// of ctx if ctx is done.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	for n := 1; ; n++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		} else if ctx.Err() != nil || n >= p.Attempts {
+			break
+		}
+
+		timer := time.NewTimer(p.delay(n))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if n := p.Attempts; n > 1 && ctx.Err() == nil {
+		return fmt.Errorf("%w (%d attempts)", err, n)
+	}
+	return err
+}
+
+// RetryPolicer is implemented by the Sources that declare the RetryPolicy.
+// ParseContext retries the failed lookups of the Source that is the
+// ContextLookuper by the policy.
+type RetryPolicer interface {
+	RetryPolicy() RetryPolicy
+}
+
+// retrySource is the Source that declares the RetryPolicy.
+type retrySource struct {
+	namedSource
+	policy RetryPolicy
+}
+
+func (s retrySource) RetryPolicy() RetryPolicy {
+	return s.policy
+}
+
+// WithRetry returns the Source that looks up the variables from src and
+// declares the RetryPolicy p, e.g. to tolerate the transient network
+// failures of the remote store during the startup.
+func WithRetry(src Source, p RetryPolicy) Source {
+	return retrySource{
+		namedSource: namedSource{name: src.Name(), l: src},
+		policy:      p,
+	}
+}
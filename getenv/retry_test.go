@@ -0,0 +1,170 @@
+package getenv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	// test that the delay is doubled for each retry up to MaxBackoff
+	p := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(1))
+	assert.Equal(t, 2*time.Second, p.delay(2))
+	assert.Equal(t, 4*time.Second, p.delay(3))
+	assert.Equal(t, 5*time.Second, p.delay(4))
+	assert.Equal(t, 5*time.Second, p.delay(100))
+
+	// test that the delay is randomized by Jitter
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.delay(2)
+		assert.True(t, d >= time.Second && d <= 3*time.Second, d)
+	}
+
+	// test that fn is called until it succeeds
+	p = RetryPolicy{Attempts: 3, Backoff: time.Millisecond, Jitter: 0.1}
+	n := 0
+	assert.NoError(t, p.Do(context.Background(), func(context.Context) error {
+		if n++; n < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	}))
+	assert.Equal(t, 3, n)
+
+	// test that the last error is returned if the attempts are exhausted
+	n = 0
+	errUnavailable := errors.New("unavailable")
+	err := p.Do(context.Background(), func(context.Context) error {
+		n++
+		return errUnavailable
+	})
+	assert.True(t, errors.Is(err, errUnavailable))
+	assert.Equal(t, "unavailable (3 attempts)", err.Error())
+	assert.Equal(t, 3, n)
+
+	// test that fn is called once by the zero policy
+	n = 0
+	err = RetryPolicy{}.Do(context.Background(), func(context.Context) error {
+		n++
+		return errUnavailable
+	})
+	assert.Equal(t, errUnavailable, err)
+	assert.Equal(t, 1, n)
+
+	// test that the retries are stopped if the context is done
+	p = RetryPolicy{Attempts: 10, Backoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	n = 0
+	err = p.Do(ctx, func(context.Context) error {
+		n++
+		return errUnavailable
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 1, n)
+}
+
+// flakyLookuper fails the lookups until the number of the failures reaches
+// fails.
+type flakyLookuper struct {
+	MapLookuper
+	fails int
+	calls int
+}
+
+func (l *flakyLookuper) LookupContext(ctx context.Context, name string) (string, bool, error) {
+	if l.calls++; l.calls <= l.fails {
+		return "", false, errors.New("connection refused")
+	}
+	v, ok := l.Lookup(name)
+	return v, ok, nil
+}
+
+func TestWithRetry(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	port := 80
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	p := RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	// test that the failed lookups are retried by the declared policy
+	l := &flakyLookuper{MapLookuper: MapLookuper{"TEST_PORT": "8080"}, fails: 2}
+	src := WithRetry(NewSource("remote", l), p)
+	assert.Equal(t, "remote", src.Name())
+	assert.Equal(t, p, src.(RetryPolicer).RetryPolicy())
+	SetSources(src)
+	assert.NoError(t, ParseContext(context.Background()))
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, "remote", Sources()[0].Name())
+	assert.Equal(t, 3, l.calls)
+
+	// test that Parse fails if the attempts are exhausted
+	l = &flakyLookuper{MapLookuper: MapLookuper{"TEST_PORT": "9000"}, fails: 3}
+	SetSources(WithRetry(NewSource("remote", l), p))
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), "connection refused (3 attempts)")
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, 3, l.calls)
+
+	// test that the lookups are not retried without the policy
+	l = &flakyLookuper{MapLookuper: MapLookuper{"TEST_PORT": "9000"}, fails: 1}
+	SetSources(NewSource("remote", l))
+	assert.Error(t, Parse())
+	assert.Equal(t, 1, l.calls)
+}
+
+func TestLazySource(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	port := 80
+	headers := map[string]string{}
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	assert.NoError(t, CollectPrefix("TEST_HEADER_", "", &headers))
+
+	loads := 0
+	src := LazySource("remote", func(ctx context.Context) (Source, error) {
+		if loads++; loads < 2 {
+			return nil, errors.New("connection refused")
+		}
+		return NewSource("map", MapLookuper{
+			"TEST_PORT":          "8080",
+			"TEST_HEADER_ACCEPT": "text/plain",
+		}), nil
+	})
+	assert.Equal(t, 0, loads)
+
+	// test that the Source is loaded on the first lookup and the failure is
+	// retried by the policy
+	SetSources(WithRetry(src, RetryPolicy{Attempts: 2, Backoff: time.Millisecond}))
+	assert.NoError(t, Parse())
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, map[string]string{"ACCEPT": "text/plain"}, headers)
+	assert.Equal(t, 2, loads)
+
+	// test that the loaded Source is reused
+	_, ok := src.Lookup("TEST_PORT")
+	assert.True(t, ok)
+	assert.Equal(t, 2, loads)
+
+	// test that the failure of load is reported without the policy
+	src = LazySource("remote", func(ctx context.Context) (Source, error) {
+		return nil, errors.New("connection refused")
+	})
+	_, ok = src.Lookup("TEST_PORT")
+	assert.False(t, ok)
+	assert.Nil(t, src.(EnvironLookuper).Environ())
+	SetSources(src)
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), `"TEST_PORT" remote: connection refused`)
+}
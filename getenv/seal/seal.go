@@ -0,0 +1,100 @@
+// Package seal provides the getenv.Decrypter implementations that decrypt
+// the values encrypted by age or sealed by the NaCl sealed box, and the
+// functions to encrypt the values into the form of "enc:<base64>". It also
+// provides the getenv.Verifier implementations that verify the Ed25519 or
+// minisign signatures of the dotenv files.
+//
+//	d, err := seal.AgeIdentityFile(os.Getenv("AGE_IDENTITY_FILE"))
+//	if err != nil {
+//		return err
+//	}
+//	getenv.SetDecrypter(d)
+package seal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/mah0x211/go-getenv/getenv"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// AgeDecrypter returns the Decrypter that decrypts the age ciphertext with
+// the identities. The ciphertext may be armored.
+func AgeDecrypter(identities ...age.Identity) getenv.Decrypter {
+	return getenv.DecryptFunc(func(ciphertext []byte) ([]byte, error) {
+		var r io.Reader = bytes.NewReader(ciphertext)
+		if bytes.HasPrefix(ciphertext, []byte(armor.Header)) {
+			r = armor.NewReader(r)
+		}
+		pr, err := age.Decrypt(r, identities...)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(pr)
+	})
+}
+
+// AgeIdentityFile returns the Decrypter that decrypts the age ciphertext
+// with the identities read from the identity file of path.
+func AgeIdentityFile(path string) (getenv.Decrypter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", path, err)
+	}
+	return AgeDecrypter(identities...), nil
+}
+
+// AgeEncrypt encrypts plaintext to the recipients, and returns the value that
+// has getenv.EncryptedPrefix.
+func AgeEncrypt(plaintext []byte, recipients ...age.Recipient) (string, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return "", err
+	} else if _, err = w.Write(plaintext); err != nil {
+		return "", err
+	} else if err = w.Close(); err != nil {
+		return "", err
+	}
+	return getenv.EncryptedPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// ErrOpen is returned by the NaCl Decrypter if the sealed box cannot be
+// opened by the key pair.
+var ErrOpen = errors.New("cannot open the sealed box")
+
+// NaClDecrypter returns the Decrypter that opens the NaCl sealed box, which
+// is compatible with libsodium crypto_box_seal, with the key pair.
+func NaClDecrypter(publicKey, privateKey *[32]byte) getenv.Decrypter {
+	return getenv.DecryptFunc(func(ciphertext []byte) ([]byte, error) {
+		plaintext, ok := box.OpenAnonymous(nil, ciphertext, publicKey, privateKey)
+		if !ok {
+			return nil, ErrOpen
+		}
+		return plaintext, nil
+	})
+}
+
+// NaClSeal seals plaintext to the public key by the NaCl sealed box, and
+// returns the value that has getenv.EncryptedPrefix.
+func NaClSeal(plaintext []byte, publicKey *[32]byte) (string, error) {
+	b, err := box.SealAnonymous(nil, plaintext, publicKey, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return getenv.EncryptedPrefix + base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,103 @@
+package seal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/mah0x211/go-getenv/getenv"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func decode(t *testing.T, v string) []byte {
+	assert.True(t, strings.HasPrefix(v, getenv.EncryptedPrefix))
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, getenv.EncryptedPrefix))
+	assert.NoError(t, err)
+	return b
+}
+
+func TestAge(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+
+	// test that decrypts the encrypted value
+	v, err := AgeEncrypt([]byte("s3cret"), id.Recipient())
+	assert.NoError(t, err)
+	plaintext, err := AgeDecrypter(id).Decrypt(decode(t, v))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", string(plaintext))
+
+	// test that decrypts the armored ciphertext
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, id.Recipient())
+	assert.NoError(t, err)
+	_, _ = w.Write([]byte("armored"))
+	assert.NoError(t, w.Close())
+	assert.NoError(t, aw.Close())
+	plaintext, err = AgeDecrypter(id).Decrypt(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, "armored", string(plaintext))
+
+	// test that returns the error with the other identity
+	_, err = AgeDecrypter(other).Decrypt(decode(t, v))
+	assert.Error(t, err)
+
+	// test that reads the identity file
+	path := filepath.Join(t.TempDir(), "key.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# comment\n"+id.String()+"\n"), 0o600))
+	d, err := AgeIdentityFile(path)
+	assert.NoError(t, err)
+	plaintext, err = d.Decrypt(decode(t, v))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", string(plaintext))
+	assert.NoError(t, os.WriteFile(path, []byte("invalid\n"), 0o600))
+	_, err = AgeIdentityFile(path)
+	assert.Error(t, err)
+}
+
+func TestNaCl(t *testing.T) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	otherPub, otherPriv, err := box.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	// test that opens the sealed box
+	v, err := NaClSeal([]byte("s3cret"), pub)
+	assert.NoError(t, err)
+	plaintext, err := NaClDecrypter(pub, priv).Decrypt(decode(t, v))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", string(plaintext))
+
+	// test that returns ErrOpen with the other key pair
+	_, err = NaClDecrypter(otherPub, otherPriv).Decrypt(decode(t, v))
+	assert.True(t, errors.Is(err, ErrOpen))
+}
+
+func TestDecrypter(t *testing.T) {
+	defer os.Unsetenv("TEST_SEAL_PASSWORD")
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	v, err := NaClSeal([]byte("s3cret"), pub)
+	assert.NoError(t, err)
+
+	// test that Parse decrypts the value
+	set := getenv.NewEnvSet()
+	set.SetDecrypter(NaClDecrypter(pub, priv))
+	password := ""
+	assert.NoError(t, set.Set("TEST_SEAL_PASSWORD", "", &password))
+	os.Setenv("TEST_SEAL_PASSWORD", v)
+	assert.NoError(t, set.Parse())
+	assert.Equal(t, "s3cret", password)
+}
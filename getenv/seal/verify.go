@@ -0,0 +1,101 @@
+package seal
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mah0x211/go-getenv/getenv"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Ed25519Verifier returns the Verifier that verifies the file by the Ed25519
+// signature in the file of the path followed by ".sig". The signature file
+// contains the raw or the base64 encoded signature.
+func Ed25519Verifier(publicKey ed25519.PublicKey) getenv.Verifier {
+	return getenv.VerifyFunc(func(path string, data []byte) error {
+		sig, err := os.ReadFile(path + ".sig")
+		if err != nil {
+			return err
+		} else if len(sig) != ed25519.SignatureSize {
+			if sig, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig))); err != nil {
+				return fmt.Errorf("%w: %w", getenv.ErrSignature, err)
+			}
+		}
+		if len(sig) != ed25519.SignatureSize || !ed25519.Verify(publicKey, data, sig) {
+			return fmt.Errorf("%w: Ed25519 signature verification failed", getenv.ErrSignature)
+		}
+		return nil
+	})
+}
+
+const (
+	// signature algorithm of minisign that signs the data
+	minisignLegacy = "Ed"
+	// signature algorithm of minisign that signs the BLAKE2b-512 hash of
+	// the data
+	minisignHashed = "ED"
+	minisignKeyLen = 2 + 8 + ed25519.PublicKeySize
+	minisignSigLen = 2 + 8 + ed25519.SignatureSize
+)
+
+// MinisignVerifier returns the Verifier that verifies the file by the
+// minisign signature in the file of the path followed by ".minisig".
+// publicKey is the base64 encoded public key such as "RWQf6LRCGA9i53mlYecO4I
+// zT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3", or the contents of the public key
+// file. Both the trusted comment and the data are verified.
+func MinisignVerifier(publicKey string) (getenv.Verifier, error) {
+	var key []byte
+	for _, line := range strings.Split(publicKey, "\n") {
+		if b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(line)); err == nil && len(b) == minisignKeyLen {
+			key = b
+			break
+		}
+	}
+	if key == nil || string(key[:2]) != minisignLegacy {
+		return nil, fmt.Errorf("invalid minisign public key")
+	}
+	keyID, pub := key[2:10], ed25519.PublicKey(key[10:])
+
+	return getenv.VerifyFunc(func(path string, data []byte) error {
+		b, err := os.ReadFile(path + ".minisig")
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")
+		if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+			return fmt.Errorf("%w: invalid minisign signature file", getenv.ErrSignature)
+		}
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+		if err != nil || len(sig) != minisignSigLen {
+			return fmt.Errorf("%w: invalid minisign signature", getenv.ErrSignature)
+		}
+		globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+		if err != nil || len(globalSig) != ed25519.SignatureSize {
+			return fmt.Errorf("%w: invalid minisign global signature", getenv.ErrSignature)
+		} else if !bytes.Equal(sig[2:10], keyID) {
+			return fmt.Errorf("%w: signed by the other key", getenv.ErrSignature)
+		}
+
+		msg := data
+		switch string(sig[:2]) {
+		case minisignLegacy:
+		case minisignHashed:
+			h := blake2b.Sum512(data)
+			msg = h[:]
+		default:
+			return fmt.Errorf("%w: unsupported minisign signature algorithm %q", getenv.ErrSignature, sig[:2])
+		}
+		if !ed25519.Verify(pub, msg, sig[10:]) {
+			return fmt.Errorf("%w: minisign signature verification failed", getenv.ErrSignature)
+		}
+		comment := strings.TrimPrefix(lines[2], "trusted comment: ")
+		if !ed25519.Verify(pub, append(sig[10:], comment...), globalSig) {
+			return fmt.Errorf("%w: minisign trusted comment verification failed", getenv.ErrSignature)
+		}
+		return nil
+	}), nil
+}
@@ -0,0 +1,117 @@
+package seal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mah0x211/go-getenv/getenv"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestEd25519Verifier(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	data := []byte("FOO=bar\n")
+	path := filepath.Join(t.TempDir(), ".env")
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+	v := Ed25519Verifier(pub)
+
+	// test that the signature file is required
+	assert.True(t, os.IsNotExist(v.Verify(path, data)))
+
+	// test that the raw signature is verified
+	sig := ed25519.Sign(priv, data)
+	assert.NoError(t, os.WriteFile(path+".sig", sig, 0o600))
+	assert.NoError(t, v.Verify(path, data))
+
+	// test that the base64 encoded signature is verified
+	assert.NoError(t, os.WriteFile(path+".sig", []byte(base64.StdEncoding.EncodeToString(sig)+"\n"), 0o600))
+	assert.NoError(t, v.Verify(path, data))
+	src, err := getenv.VerifiedDotenvSource(v, path)
+	assert.NoError(t, err)
+	foo, ok := src.Lookup("FOO")
+	assert.True(t, ok)
+	assert.Equal(t, "bar", foo)
+
+	// test that the tampered data is rejected
+	err = v.Verify(path, []byte("FOO=baz\n"))
+	assert.True(t, errors.Is(err, getenv.ErrSignature))
+
+	// test that the malformed signature is rejected
+	assert.NoError(t, os.WriteFile(path+".sig", []byte("!!"), 0o600))
+	err = v.Verify(path, data)
+	assert.True(t, errors.Is(err, getenv.ErrSignature))
+}
+
+// minisign returns the public key file and the signature file of data in the
+// format of minisign.
+func minisign(t *testing.T, alg string, data []byte, comment string) (string, string) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	keyID := []byte("01234567")
+
+	key := append(append([]byte("Ed"), keyID...), pub...)
+	msg := data
+	if alg == "ED" {
+		h := blake2b.Sum512(data)
+		msg = h[:]
+	}
+	sig := append(append([]byte(alg), keyID...), ed25519.Sign(priv, msg)...)
+	globalSig := ed25519.Sign(priv, append(sig[10:], comment...))
+
+	enc := base64.StdEncoding.EncodeToString
+	return "untrusted comment: minisign public key 3736353433323130\n" + enc(key) + "\n",
+		"untrusted comment: signature from minisign secret key\n" + enc(sig) + "\n" +
+			"trusted comment: " + comment + "\n" + enc(globalSig) + "\n"
+}
+
+func TestMinisignVerifier(t *testing.T) {
+	data := []byte("FOO=bar\n")
+	path := filepath.Join(t.TempDir(), ".env")
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+
+	for _, alg := range []string{"Ed", "ED"} {
+		pubfile, sigfile := minisign(t, alg, data, "timestamp:1700000000\tfile:.env")
+		assert.NoError(t, os.WriteFile(path+".minisig", []byte(sigfile), 0o600))
+
+		// test that the contents of the public key file are accepted
+		v, err := MinisignVerifier(pubfile)
+		assert.NoError(t, err)
+		assert.NoError(t, v.Verify(path, data), alg)
+
+		// test that the tampered data is rejected
+		err = v.Verify(path, []byte("FOO=baz\n"))
+		assert.True(t, errors.Is(err, getenv.ErrSignature), alg)
+	}
+
+	// test that the base64 line of the public key is accepted
+	pubfile, sigfile := minisign(t, "ED", data, "trusted")
+	assert.NoError(t, os.WriteFile(path+".minisig", []byte(sigfile), 0o600))
+	v, err := MinisignVerifier(pubfile[len("untrusted comment: minisign public key 3736353433323130\n"):])
+	assert.NoError(t, err)
+	assert.NoError(t, v.Verify(path, data))
+
+	// test that the tampered trusted comment is rejected
+	tampered := strings.Replace(sigfile, "trusted comment: trusted", "trusted comment: tampered", 1)
+	assert.NoError(t, os.WriteFile(path+".minisig", []byte(tampered), 0o600))
+	err = v.Verify(path, data)
+	assert.True(t, errors.Is(err, getenv.ErrSignature))
+
+	// test that the signature by the other key is rejected
+	other, _ := minisign(t, "ED", data, "trusted")
+	v, err = MinisignVerifier(other)
+	assert.NoError(t, err)
+	err = v.Verify(path, data)
+	assert.True(t, errors.Is(err, getenv.ErrSignature))
+
+	// test that the invalid public key is rejected
+	_, err = MinisignVerifier("invalid")
+	assert.Error(t, err)
+}
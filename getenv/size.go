@@ -0,0 +1,141 @@
+package getenv
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Size represents a number of bytes. It can be parsed from a string with a
+// unit suffix such as "512", "64KB", "64MiB" or "1.5GB".
+type Size uint64
+
+var sizeUnits = []struct {
+	name string
+	size uint64
+}{
+	{"EiB", 1 << 60}, {"PiB", 1 << 50}, {"TiB", 1 << 40},
+	{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"EB", 1e18}, {"PB", 1e15}, {"TB", 1e12},
+	{"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"B", 1},
+}
+
+func lookupSizeUnit(s string) (uint64, bool) {
+	switch {
+	case s == "":
+		return 1, true
+	case len(s) == 1 && s != "B" && s != "b":
+		// allow single letter units such as "K" and "M"
+		s += "B"
+	}
+	for _, unit := range sizeUnits {
+		if strings.EqualFold(unit.name, s) {
+			return unit.size, true
+		}
+	}
+	return 0, false
+}
+
+// ParseSize parses a string with a unit suffix and returns the number of
+// bytes. The units KB, MB, GB, TB, PB and EB are powers of 1000, and the units
+// KiB, MiB, GiB, TiB, PiB and EiB are powers of 1024. The units are
+// case-insensitive.
+func ParseSize(s string) (uint64, error) {
+	str := strings.TrimSpace(s)
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || '9' < r) && r != '.'
+	})
+	if i == -1 {
+		i = len(str)
+	}
+	num, unit := str[:i], strings.TrimSpace(str[i:])
+	mul, ok := lookupSizeUnit(unit)
+	if !ok || num == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	if strings.IndexByte(num, '.') == -1 {
+		v, err := strconv.ParseUint(num, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid size %q: %w", s, err)
+		}
+		hi, lo := bits.Mul64(v, mul)
+		if hi != 0 {
+			return 0, fmt.Errorf("invalid size %q: value out of range", s)
+		}
+		return lo, nil
+	}
+
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	v *= float64(mul)
+	if v >= math.MaxUint64 {
+		return 0, fmt.Errorf("invalid size %q: value out of range", s)
+	}
+	return uint64(v), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (v *Size) UnmarshalText(b []byte) error {
+	n, err := ParseSize(string(b))
+	if err != nil {
+		return err
+	}
+	*v = Size(n)
+	return nil
+}
+
+// String returns the size with the largest unit that can represent it
+// without a fraction.
+func (v Size) String() string {
+	n, name := uint64(v), "B"
+	if v != 0 {
+		for _, unit := range sizeUnits {
+			if uint64(v)%unit.size == 0 && uint64(v)/unit.size < n {
+				n, name = uint64(v)/unit.size, unit.name
+			}
+		}
+	}
+	return strconv.FormatUint(n, 10) + name
+}
+
+// SizeParseFunc returns the ParseFunc that parses the environment variable
+// value by ParseSize and stores the number of bytes into an integer value.
+func SizeParseFunc() ParseFunc {
+	return func(iv interface{}, envName, envValue string) error {
+		ref := reflect.ValueOf(iv)
+		if ref.Kind() != reflect.Ptr || ref.IsNil() {
+			return fmt.Errorf("%w: %T is not a non-nil pointer", ErrValue, iv)
+		}
+		n, err := ParseSize(envValue)
+		if err != nil {
+			return err
+		}
+
+		ref = ref.Elem()
+		switch ref.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n > math.MaxInt64 || ref.OverflowInt(int64(n)) {
+				return fmt.Errorf("invalid size %q: value out of range", envValue)
+			}
+			ref.SetInt(int64(n))
+
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+			reflect.Uint64, reflect.Uintptr:
+			if ref.OverflowUint(n) {
+				return fmt.Errorf("invalid size %q: value out of range", envValue)
+			}
+			ref.SetUint(n)
+
+		default:
+			return fmt.Errorf("%w: %T is not a pointer to an integer", ErrValue, iv)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+package getenv
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSize(t *testing.T) {
+	// test that parse size string
+	for s, exp := range map[string]uint64{
+		"0":       0,
+		"512":     512,
+		"512B":    512,
+		"64K":     64000,
+		"64kb":    64000,
+		"64KiB":   64 << 10,
+		"64 MiB":  64 << 20,
+		"2GB":     2e9,
+		"1.5GiB":  3 << 29,
+		"3TiB":    3 << 40,
+		"1PB":     1e15,
+		"15EiB":   15 << 60,
+		" 1 kib ": 1024,
+	} {
+		v, err := ParseSize(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, exp, v, s)
+	}
+
+	// test that returns error if the value is invalid
+	for _, s := range []string{
+		"", "MiB", "-1", "1.2.3KB", "1XB", "64 M iB", "16EiB", "20EB",
+	} {
+		_, err := ParseSize(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSize(t *testing.T) {
+	// test that parse size string as encoding.TextUnmarshaler
+	var v Size
+	assert.NoError(t, defaultParseFunc(&v, "SIZE", "64MiB"))
+	assert.Equal(t, Size(64<<20), v)
+	assert.Error(t, defaultParseFunc(&v, "SIZE", "64XiB"))
+	assert.Equal(t, Size(64<<20), v)
+
+	// test that format with the largest unit
+	for exp, v := range map[string]Size{
+		"0B":    0,
+		"1023B": 1023,
+		"1KiB":  1024,
+		"64MiB": 64 << 20,
+		"2GB":   2e9,
+		"1500B": 1500,
+	} {
+		assert.Equal(t, exp, v.String())
+	}
+}
+
+func TestSizeParseFunc(t *testing.T) {
+	parsefn := SizeParseFunc()
+
+	// test that store the number of bytes into integer value
+	var i64 int64
+	assert.NoError(t, parsefn(&i64, "SIZE", "2GB"))
+	assert.Equal(t, int64(2e9), i64)
+	var u64 uint64
+	assert.NoError(t, parsefn(&u64, "SIZE", "1EiB"))
+	assert.Equal(t, uint64(1<<60), u64)
+
+	// test that returns error if the value overflows
+	var u16 uint16
+	assert.Error(t, parsefn(&u16, "SIZE", "64KiB"))
+	var i8 int8
+	assert.Error(t, parsefn(&i8, "SIZE", "128"))
+	assert.Error(t, parsefn(&i64, "SIZE", "15EiB"))
+	assert.Equal(t, int64(2e9), i64)
+
+	// test that returns ErrValue if the value is not a pointer to an integer
+	var s string
+	assert.True(t, errors.Is(parsefn(&s, "SIZE", "1KB"), ErrValue))
+	assert.True(t, errors.Is(parsefn(i64, "SIZE", "1KB"), ErrValue))
+}
@@ -0,0 +1,17 @@
+module github.com/mah0x211/go-getenv/getenv/sopssource
+
+go 1.21
+
+require (
+	github.com/mah0x211/go-getenv v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.6.1
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/mah0x211/go-getenv => ../..
@@ -0,0 +1,64 @@
+// Package sopssource provides the getenv.Source that resolves the variables
+// from the dotenv, YAML or JSON file encrypted by sops, so that the encrypted
+// configuration can live in the repository.
+//
+// The file is decrypted by the sops command, which finds the keys in the
+// same way as the command line, e.g. the age identities specified by
+// SOPS_AGE_KEY_FILE and the AWS KMS keys by the AWS credentials.
+//
+//	src, err := sopssource.New(ctx, "secrets.enc.env")
+//	if err != nil {
+//		return err
+//	}
+//	getenv.SetSources(getenv.EnvSource(), src)
+package sopssource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/mah0x211/go-getenv/getenv"
+)
+
+type options struct {
+	command string
+}
+
+// Option configures the Source.
+type Option func(o *options)
+
+// WithCommand uses the sops command of path instead of "sops" in PATH.
+func WithCommand(path string) Option {
+	return func(o *options) {
+		o.command = path
+	}
+}
+
+// New decrypts the file of path and returns the Source named "sops" that
+// supplies its values. The decrypted document is read by getenv.ReadJSON, so
+// the top-level keys are the variable names.
+func New(ctx context.Context, path string, opts ...Option) (getenv.Source, error) {
+	o := &options{command: "sops"}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, o.command, "--decrypt", "--output-type", "json", path)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("sops: %q: %w: %s", path, err, msg)
+		}
+		return nil, fmt.Errorf("sops: %q: %w", path, err)
+	}
+
+	m, err := getenv.ReadJSON(&stdout)
+	if err != nil {
+		return nil, fmt.Errorf("sops: %q: %w", path, err)
+	}
+	return getenv.NewSource("sops", getenv.MapLookuper(m)), nil
+}
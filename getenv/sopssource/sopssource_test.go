@@ -0,0 +1,49 @@
+package sopssource
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// fake sops command that outputs the decrypted document
+	dir := t.TempDir()
+	sops := filepath.Join(dir, "sops")
+	assert.NoError(t, os.WriteFile(sops, []byte(`#!/bin/sh
+if [ "$1 $2 $3" != "--decrypt --output-type json" ]; then
+	echo "unexpected arguments: $*" >&2
+	exit 2
+elif [ "$4" != "secrets.enc.env" ]; then
+	echo "Failed to get the data key" >&2
+	exit 128
+fi
+echo '{"DB_PASSWORD": "s3cret", "PORT": 5432}'
+`), 0o700))
+
+	// test that decrypts the file by the command
+	src, err := New(context.Background(), "secrets.enc.env", WithCommand(sops))
+	assert.NoError(t, err)
+	assert.Equal(t, "sops", src.Name())
+	v, _ := src.Lookup("DB_PASSWORD")
+	assert.Equal(t, "s3cret", v)
+	v, _ = src.Lookup("PORT")
+	assert.Equal(t, "5432", v)
+
+	// test that returns the error with the standard error
+	_, err = New(context.Background(), "other.env", WithCommand(sops))
+	assert.Contains(t, err.Error(), "Failed to get the data key")
+
+	// test that sops is looked up in PATH
+	t.Setenv("PATH", dir)
+	_, err = New(context.Background(), "secrets.enc.env")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,357 @@
+package getenv
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Source is the Lookuper that supplies the values to Parse as a layer of the
+// configuration, such as the process environment and the dotenv files. The
+// name of the Source is recorded in the Source field of the Env whose value
+// is supplied by it.
+type Source interface {
+	Lookuper
+	Name() string
+}
+
+// namedSource is the Source that looks up the variables from the Lookuper.
+type namedSource struct {
+	name string
+	l    Lookuper
+}
+
+// NewSource returns the Source of name that looks up the variables from l.
+// The variables collected by the prefix are supplied only if l is the
+// EnvironLookuper, and the context is passed to l only if l is the
+// ContextLookuper.
+func NewSource(name string, l Lookuper) Source {
+	return namedSource{name: name, l: l}
+}
+
+func (s namedSource) Name() string {
+	return s.name
+}
+
+func (s namedSource) Lookup(name string) (string, bool) {
+	return s.l.Lookup(name)
+}
+
+func (s namedSource) LookupContext(ctx context.Context, name string) (string, bool, error) {
+	return lookupContext(ctx, s.l, name)
+}
+
+func (s namedSource) Environ() []string {
+	if el, ok := s.l.(EnvironLookuper); ok {
+		return el.Environ()
+	}
+	return nil
+}
+
+// lazySource is the Source that is loaded on the first lookup.
+type lazySource struct {
+	name string
+	load func(ctx context.Context) (Source, error)
+	mu   sync.Mutex
+	src  Source
+}
+
+// LazySource returns the Source of name that is loaded by load on the first
+// lookup instead of at the startup, so that the failure of load is reported
+// by ParseContext and retried by the RetryPolicy declared by WithRetry. load
+// is called again by the next lookup until it succeeds. Lookup and Environ
+// load the Source with context.Background() and find nothing if load fails.
+func LazySource(name string, load func(ctx context.Context) (Source, error)) Source {
+	return &lazySource{name: name, load: load}
+}
+
+// source returns the loaded Source, and loads it if not loaded yet.
+func (s *lazySource) source(ctx context.Context) (Source, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.src == nil {
+		src, err := s.load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.src = src
+	}
+	return s.src, nil
+}
+
+func (s *lazySource) Name() string {
+	return s.name
+}
+
+func (s *lazySource) Lookup(name string) (string, bool) {
+	v, ok, _ := s.LookupContext(context.Background(), name)
+	return v, ok
+}
+
+func (s *lazySource) LookupContext(ctx context.Context, name string) (string, bool, error) {
+	src, err := s.source(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	return lookupContext(ctx, src, name)
+}
+
+func (s *lazySource) Environ() []string {
+	if src, err := s.source(context.Background()); err == nil {
+		if el, ok := src.(EnvironLookuper); ok {
+			return el.Environ()
+		}
+	}
+	return nil
+}
+
+// EnvSource returns the Source named "env" that looks up the variables from
+// the process environment.
+func EnvSource() Source {
+	return NewSource("env", OSLookuper{})
+}
+
+// SnapshotEnvSource returns the Source named "env" that looks up the
+// variables from the copy of the process environment at the call. The
+// changes of the process environment after the call are not visible.
+func SnapshotEnvSource() Source {
+	return NewSource("env", environMap(os.Environ()))
+}
+
+// DotenvSource returns the Source named "dotenv" that looks up the variables
+// from the dotenv files of paths. The later file overrides the earlier ones
+// like LoadDotenv.
+func DotenvSource(paths ...string) (Source, error) {
+	return VerifiedDotenvSource(nil, paths...)
+}
+
+// VerifiedDotenvSource is like DotenvSource but verifies the contents of the
+// files by v before reading them. The files are not verified if v is nil.
+func VerifiedDotenvSource(v Verifier, paths ...string) (Source, error) {
+	m := MapLookuper{}
+	for _, path := range paths {
+		if err := readDotenvFile(path, m, v); err != nil {
+			return nil, err
+		}
+	}
+	return NewSource("dotenv", m), nil
+}
+
+// JSONSource returns the Source named "json" that looks up the variables from
+// the JSON file of path read by ReadJSON.
+func JSONSource(path string) (Source, error) {
+	m, err := readConfigFile(path, ReadJSON)
+	if err != nil {
+		return nil, err
+	}
+	return NewSource("json", MapLookuper(m)), nil
+}
+
+// TOMLSource returns the Source named "toml" that looks up the variables from
+// the TOML file of path read by ReadTOML.
+func TOMLSource(path string) (Source, error) {
+	m, err := readConfigFile(path, ReadTOML)
+	if err != nil {
+		return nil, err
+	}
+	return NewSource("toml", MapLookuper(m)), nil
+}
+
+// Chain is the list of the Sources in the order of the precedence. The value
+// is looked up from the Sources in order, and the first Source that defines
+// the variable supplies the value, e.g. Chain{flags, EnvSource(), dotenv}
+// prefers the flags to the process environment, and the process environment
+// to the dotenv files. The registered default value is used if no Source
+// defines the variable.
+type Chain []Source
+
+// Lookup returns the value of name supplied by the first Source that defines
+// it.
+func (c Chain) Lookup(name string) (string, bool) {
+	v, _, ok := c.LookupSource(name)
+	return v, ok
+}
+
+// LookupSource is like Lookup but also returns the name of the Source that
+// supplied the value.
+func (c Chain) LookupSource(name string) (v, src string, ok bool) {
+	for _, s := range c {
+		if v, ok := s.Lookup(name); ok {
+			return v, s.Name(), true
+		}
+	}
+	return "", "", false
+}
+
+// LookupContext is like Lookup but passes ctx to the Sources that are the
+// ContextLookuper, and returns the error of the first Source that fails.
+func (c Chain) LookupContext(ctx context.Context, name string) (string, bool, error) {
+	v, _, ok, err := c.LookupSourceContext(ctx, name)
+	return v, ok, err
+}
+
+// LookupSourceContext is like LookupSource but passes ctx to the Sources
+// like LookupContext.
+func (c Chain) LookupSourceContext(ctx context.Context, name string) (v, src string, ok bool, err error) {
+	for _, s := range c {
+		if v, ok, err = lookupContext(ctx, s, name); err != nil {
+			return "", "", false, fmt.Errorf("%s: %w", s.Name(), err)
+		} else if ok {
+			return v, s.Name(), true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// Environ lists the variables of the Sources. The earlier Source takes
+// precedence if the name is defined by more than one Source.
+func (c Chain) Environ() []string {
+	ls := make([]Lookuper, 0, len(c))
+	for _, s := range c {
+		ls = append(ls, s)
+	}
+	return MultiLookuper(ls...).Environ()
+}
+
+// lookupSource returns the value of name and the name of the Source that
+// supplied it. The name is empty if l is neither the Chain nor the Source.
+func lookupSource(ctx context.Context, l Lookuper, name string) (v, src string, ok bool, err error) {
+	switch l := l.(type) {
+	case foldLookuper:
+		return l.lookupSource(ctx, name)
+	case Chain:
+		return l.LookupSourceContext(ctx, name)
+	case Source:
+		v, ok, err = lookupContext(ctx, l, name)
+		return v, l.Name(), ok, err
+	}
+	v, ok, err = lookupContext(ctx, l, name)
+	return v, "", ok, err
+}
+
+// lookupContext looks up the value of name by LookupContext if l is the
+// ContextLookuper, or by Lookup unless ctx is done. The lookups are canceled
+// by the timeout if l is the Timeouter, and the failed lookups are retried if
+// l is the RetryPolicer.
+func lookupContext(ctx context.Context, l Lookuper, name string) (v string, ok bool, err error) {
+	cl, isCL := l.(ContextLookuper)
+	if !isCL {
+		if err = ctx.Err(); err != nil {
+			return "", false, err
+		}
+		v, ok = l.Lookup(name)
+		return v, ok, nil
+	}
+
+	lookup := func(ctx context.Context) error {
+		v, ok, err = cl.LookupContext(ctx, name)
+		return err
+	}
+	if t, isT := l.(Timeouter); isT && t.Timeout() > 0 {
+		lookup = withTimeout(lookup, t.Timeout())
+	}
+	if rp, isRP := l.(RetryPolicer); isRP {
+		err = rp.RetryPolicy().Do(ctx, lookup)
+	} else {
+		err = lookup(ctx)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return v, ok, nil
+}
+
+// SetSources sets the Sources consulted by Parse in the order of the
+// precedence. Parse reads the process environment by EnvSource, or the
+// snapshot of the set created with WithSnapshot, if no Source is set.
+func SetSources(srcs ...Source) {
+	defaultSet.SetSources(srcs...)
+}
+
+// SetSources sets the Sources of the set like the SetSources function. The
+// sets returned by Sub after the call inherit the Sources.
+func (s *EnvSet) SetSources(srcs ...Source) {
+	s.sources = srcs
+}
+
+// Sources returns the Sources set by SetSources.
+func Sources() []Source {
+	return defaultSet.Sources()
+}
+
+// Sources returns the Sources of the set.
+func (s *EnvSet) Sources() []Source {
+	return append([]Source(nil), s.sources...)
+}
+
+// envSource returns the Source of the process environment of the set, that is
+// the snapshot if the set is created with WithSnapshot.
+func (s *EnvSet) envSource() Source {
+	if s.env == nil {
+		return EnvSource()
+	}
+	return s.env
+}
+
+// lookuper returns the Chain of the Sources of the set, or the process
+// environment if no Source is set.
+func (s *EnvSet) lookuper() Lookuper {
+	if len(s.sources) == 0 {
+		return s.envSource()
+	}
+	return Chain(s.sources)
+}
+
+// withFallback returns the Chain of the Sources of the set, or the process
+// environment if no Source is set, followed by src that is consulted only if
+// the variable is not supplied by any of them.
+func (s *EnvSet) withFallback(src Source) Chain {
+	if len(s.sources) == 0 {
+		return Chain{s.envSource(), src}
+	}
+	return append(append(Chain{}, s.sources...), src)
+}
+
+// ErrArgs is returned by ArgsSource if the arguments have the invalid
+// override.
+var ErrArgs = fmt.Errorf("invalid environment variable override")
+
+// ArgsSource returns the Source named "args" that supplies the variables
+// overridden by the command-line arguments of the form "-e NAME=VALUE",
+// "--env NAME=VALUE", "-e=NAME=VALUE" or "--env=NAME=VALUE", and the
+// remaining arguments in order. The arguments after "--" are not consumed.
+// The later override wins if the name is specified more than once. The args
+// should not include the program name, e.g. os.Args[1:].
+func ArgsSource(args []string) (Source, []string, error) {
+	m := MapLookuper{}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		flag, kv, hasValue := strings.Cut(arg, "=")
+		if flag != "-e" && flag != "--env" {
+			rest = append(rest, arg)
+			continue
+		} else if !hasValue {
+			if i++; i == len(args) {
+				return nil, nil, fmt.Errorf("%w: %s requires NAME=VALUE", ErrArgs, flag)
+			}
+			kv = args[i]
+		}
+
+		name, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %q is not NAME=VALUE", ErrArgs, kv)
+		} else if checkName(name) != nil {
+			return nil, nil, fmt.Errorf("%w: %q %w", ErrArgs, name, ErrName)
+		}
+		m[name] = v
+	}
+	return NewSource("args", m), rest, nil
+}
@@ -0,0 +1,142 @@
+package getenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	c := Chain{
+		NewSource("flags", MapLookuper{"A": "flag"}),
+		NewSource("fn", LookupFunc(func(name string) (string, bool) {
+			return "fn", name == "C"
+		})),
+		NewSource("defaults", MapLookuper{"A": "default", "B": "default"}),
+	}
+
+	// test that the first Source that defines the variable supplies the value
+	for name, want := range map[string][2]string{
+		"A": {"flag", "flags"},
+		"B": {"default", "defaults"},
+		"C": {"fn", "fn"},
+	} {
+		v, src, ok := c.LookupSource(name)
+		assert.True(t, ok)
+		assert.Equal(t, want, [2]string{v, src})
+		v, ok = c.Lookup(name)
+		assert.True(t, ok)
+		assert.Equal(t, want[0], v)
+	}
+	_, _, ok := c.LookupSource("D")
+	assert.False(t, ok)
+
+	// test that lists the variables of the Sources that list them
+	assert.Equal(t, []string{"A=flag", "B=default"}, c.Environ())
+}
+
+func TestSetSources(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_HOST")
+		os.Unsetenv("TEST_PORT")
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	assert.NoError(t, os.WriteFile(path, []byte("TEST_HOST=dotenv\nTEST_PORT=8080\nTEST_USER=admin\n"), 0o600))
+	dotenv, err := DotenvSource(path)
+	assert.NoError(t, err)
+	_, err = DotenvSource(filepath.Join(dir, "none"))
+	assert.True(t, os.IsNotExist(err))
+
+	host := "localhost"
+	port := 80
+	user := "guest"
+	name := "app"
+	assert.NoError(t, Set("TEST_HOST", "", &host))
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	assert.NoError(t, Set("TEST_USER", "", &user))
+	assert.NoError(t, Set("TEST_NAME", "", &name))
+
+	// test that Parse reads the process environment by default
+	os.Setenv("TEST_HOST", "env")
+	assert.Empty(t, Sources())
+	assert.NoError(t, Parse())
+	env, _ := Lookup("TEST_HOST")
+	assert.Equal(t, "env", env.Source)
+
+	// test that records the Source that supplied the value
+	flags := NewSource("flags", MapLookuper{"TEST_PORT": "9000"})
+	SetSources(flags, EnvSource(), dotenv)
+	assert.Len(t, Sources(), 3)
+	assert.NoError(t, Parse())
+	assert.Equal(t, "env", host)
+	assert.Equal(t, 9000, port)
+	assert.Equal(t, "admin", user)
+	assert.Equal(t, "app", name)
+	for name, src := range map[string]string{
+		"TEST_HOST": "env",
+		"TEST_PORT": "flags",
+		"TEST_USER": "dotenv",
+		"TEST_NAME": "",
+	} {
+		env, _ := Lookup(name)
+		assert.Equal(t, src, env.Source, name)
+	}
+
+	// test that the sub set inherits the Sources
+	os.Setenv("TEST_PORT", "1")
+	assert.NoError(t, WithPrefix("TEST_").Parse())
+	assert.Equal(t, 9000, port)
+
+	// test that LoadDotenv records the Source
+	SetSources()
+	os.Unsetenv("TEST_HOST")
+	assert.NoError(t, LoadDotenv(path))
+	env, _ = Lookup("TEST_HOST")
+	assert.Equal(t, "dotenv", env.Source)
+	env, _ = Lookup("TEST_PORT")
+	assert.Equal(t, "env", env.Source)
+}
+
+func TestArgsSource(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PORT")
+	}()
+
+	// test that consumes the overrides and returns the remaining arguments
+	src, rest, err := ArgsSource([]string{
+		"-v", "-e", "TEST_HOST=example.com", "--env=TEST_PORT=8080",
+		"serve", "--env", "TEST_OPTS=a=b", "-e=TEST_HOST=example.org",
+		"--", "-e", "TEST_USER=admin",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "args", src.Name())
+	assert.Equal(t, []string{"-v", "serve", "--", "-e", "TEST_USER=admin"}, rest)
+	assert.Equal(t, []string{
+		"TEST_HOST=example.org", "TEST_OPTS=a=b", "TEST_PORT=8080",
+	}, src.(EnvironLookuper).Environ())
+
+	// test that returns ErrArgs
+	for _, args := range [][]string{
+		{"-e"},
+		{"--env", "TEST_HOST"},
+		{"-e=1A=b"},
+	} {
+		_, _, err := ArgsSource(args)
+		assert.True(t, errors.Is(err, ErrArgs), args)
+	}
+
+	// test that overrides the process environment
+	port := 80
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	os.Setenv("TEST_PORT", "9000")
+	SetSources(src, EnvSource())
+	assert.NoError(t, Parse())
+	assert.Equal(t, 8080, port)
+}
@@ -0,0 +1,57 @@
+package getenv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned by ParseContext if the lookup of the Source that
+// declares the timeout does not complete in time. It is not returned if the
+// context passed to ParseContext is done first.
+var ErrTimeout = fmt.Errorf("source timed out")
+
+// Timeouter is implemented by the Sources that declare the timeout of each
+// lookup. ParseContext cancels the lookup of the Source that is the
+// ContextLookuper if it does not complete in the timeout, independently of
+// the deadline of the context passed to ParseContext. The timeout is applied
+// to each attempt if the Source also declares the RetryPolicy.
+type Timeouter interface {
+	Timeout() time.Duration
+}
+
+// timeoutSource is the Source that declares the timeout.
+type timeoutSource struct {
+	namedSource
+	timeout time.Duration
+}
+
+func (s timeoutSource) Timeout() time.Duration {
+	return s.timeout
+}
+
+// WithTimeout returns the Source that looks up the variables from src and
+// declares the timeout d, e.g. WithTimeout(vault, 2*time.Second). The
+// timeout is applied to each attempt of WithRetry(WithTimeout(src, d), p),
+// and to all the attempts of WithTimeout(WithRetry(src, p), d).
+func WithTimeout(src Source, d time.Duration) Source {
+	return timeoutSource{
+		namedSource: namedSource{name: src.Name(), l: src},
+		timeout:     d,
+	}
+}
+
+// withTimeout returns the function that calls fn with the context that is
+// canceled after d, and returns ErrTimeout if fn fails by the timeout.
+func withTimeout(fn func(ctx context.Context) error, d time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		tctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		err := fn(tctx)
+		if err != nil && ctx.Err() == nil && errors.Is(tctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %v: %w", ErrTimeout, d, err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,63 @@
+package getenv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeout(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+	}()
+
+	port := 80
+	assert.NoError(t, Set("TEST_PORT", "", &port))
+	m := MapLookuper{"TEST_PORT": "8080"}
+
+	// test that the lookup completed in time succeeds
+	src := WithTimeout(NewSource("vault", &slowLookuper{MapLookuper: m, delay: time.Millisecond}), time.Second)
+	assert.Equal(t, "vault", src.Name())
+	assert.Equal(t, time.Second, src.(Timeouter).Timeout())
+	SetSources(src)
+	assert.NoError(t, Parse())
+	assert.Equal(t, 8080, port)
+
+	// test that the error identifies the variable and the source that timed
+	// out
+	SetSources(
+		WithTimeout(NewSource("vault", &slowLookuper{MapLookuper: m, delay: time.Hour}), 10*time.Millisecond),
+		NewSource("env", MapLookuper{"TEST_PORT": "9000"}),
+	)
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), `"TEST_PORT" vault: source timed out after 10ms`)
+	assert.Equal(t, 8080, port)
+
+	// test that the deadline of the context is reported as is if it comes
+	// first
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	SetSources(WithTimeout(NewSource("vault", &slowLookuper{MapLookuper: m, delay: time.Hour}), time.Hour))
+	err = ParseContext(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, errors.Is(err, ErrTimeout))
+
+	// test that the timeout is applied to each attempt of the retries
+	l := &slowLookuper{MapLookuper: m, delay: time.Hour}
+	SetSources(WithRetry(WithTimeout(NewSource("vault", l), 5*time.Millisecond), RetryPolicy{Attempts: 3}))
+	err = Parse()
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.Contains(t, err.Error(), "(3 attempts)")
+
+	// test that the timeout is applied to all the attempts of the retries
+	flaky := &flakyLookuper{MapLookuper: m, fails: 100}
+	SetSources(WithTimeout(WithRetry(NewSource("vault", flaky), RetryPolicy{Attempts: 100, Backoff: time.Millisecond}), 20*time.Millisecond))
+	err = Parse()
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.Less(t, flaky.calls, 100)
+}
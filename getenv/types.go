@@ -0,0 +1,277 @@
+package getenv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	ipNetType    = reflect.TypeOf(net.IPNet{})
+	urlPtrType   = reflect.TypeOf(&url.URL{})
+)
+
+// typeParser parses s and stores the result in the value pointed to by ref.
+type typeParser func(ref reflect.Value, s string) error
+
+// typeParsers is the list of the types that need to be parsed by a dedicated
+// parser instead of the parser for their kind.
+var typeParsers = map[reflect.Type]typeParser{
+	durationType:                     parseDuration,
+	ipNetType:                        parseIPNet,
+	reflect.TypeOf(url.URL{}):        parseURL,
+	reflect.TypeOf(time.Time{}):      parseTime,
+	reflect.TypeOf(&regexp.Regexp{}): parseRegexp,
+	reflect.TypeOf(big.Int{}):        parseBigInt,
+	reflect.TypeOf(big.Float{}):      parseBigFloat,
+	reflect.TypeOf(big.Rat{}):        parseBigRat,
+	reflect.TypeOf([]byte(nil)):      parseBase64,
+	reflect.TypeOf(&time.Location{}): parseLocation,
+	reflect.TypeOf(os.FileMode(0)):   parseFileMode,
+	reflect.TypeOf(mail.Address{}):   parseMailAddress,
+}
+
+func parseDuration(ref reflect.Value, s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	ref.SetInt(int64(v))
+	return nil
+}
+
+var extendedDurationUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseDuration parses a duration string like time.ParseDuration, but it also
+// accepts the units "d" for 24 hours and "w" for 7 days, such as "7d" and
+// "2w3d12h".
+func ParseDuration(s string) (time.Duration, error) {
+	str := s
+	neg := false
+	if str != "" && (str[0] == '-' || str[0] == '+') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+	if !strings.ContainsAny(str, "dw") {
+		return time.ParseDuration(s)
+	} else if str == "" {
+		return 0, fmt.Errorf("time: invalid duration %q", s)
+	}
+
+	var d time.Duration
+	for str != "" {
+		// number followed by the unit
+		i := strings.IndexFunc(str, func(r rune) bool {
+			return (r < '0' || '9' < r) && r != '.'
+		})
+		if i <= 0 {
+			return 0, fmt.Errorf("time: invalid duration %q", s)
+		}
+		j := strings.IndexFunc(str[i:], func(r rune) bool {
+			return ('0' <= r && r <= '9') || r == '.'
+		})
+		if j == -1 {
+			j = len(str) - i
+		}
+		num, unit := str[:i], str[i:i+j]
+		str = str[i+j:]
+
+		var v time.Duration
+		if mul, ok := extendedDurationUnits[unit]; ok {
+			f, err := strconv.ParseFloat(num, 64)
+			if err != nil || float64(mul)*f > math.MaxInt64 {
+				return 0, fmt.Errorf("time: invalid duration %q", s)
+			}
+			v = time.Duration(float64(mul) * f)
+		} else {
+			var err error
+			if v, err = time.ParseDuration(num + unit); err != nil {
+				return 0, fmt.Errorf("time: invalid duration %q", s)
+			}
+		}
+		if d > math.MaxInt64-v {
+			return 0, fmt.Errorf("time: invalid duration %q", s)
+		}
+		d += v
+	}
+
+	if neg {
+		return -d, nil
+	}
+	return d, nil
+}
+
+func parseIPNet(ref reflect.Value, s string) error {
+	_, v, err := net.ParseCIDR(s)
+	if err != nil {
+		return err
+	}
+	ref.Set(reflect.ValueOf(*v))
+	return nil
+}
+
+func parseURL(ref reflect.Value, s string) error {
+	v, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	ref.Set(reflect.ValueOf(*v))
+	return nil
+}
+
+func parseTime(ref reflect.Value, s string) error {
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+	ref.Set(reflect.ValueOf(v))
+	return nil
+}
+
+func parseRegexp(ref reflect.Value, s string) error {
+	v, err := regexp.Compile(s)
+	if err != nil {
+		return err
+	}
+	ref.Set(reflect.ValueOf(v))
+	return nil
+}
+
+func parseBigInt(ref reflect.Value, s string) error {
+	v, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	ref.Set(reflect.ValueOf(*v))
+	return nil
+}
+
+func parseBigFloat(ref reflect.Value, s string) error {
+	cur := ref.Addr().Interface().(*big.Float)
+	// use the precision of the value if it is set
+	v, _, err := big.ParseFloat(s, 0, cur.Prec(), cur.Mode())
+	if err != nil {
+		return err
+	}
+	// replace the value not to overwrite the storage shared with the copies
+	ref.Set(reflect.ValueOf(*v))
+	return nil
+}
+
+func parseBigRat(ref reflect.Value, s string) error {
+	v, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return fmt.Errorf("invalid rational number %q", s)
+	}
+	ref.Set(reflect.ValueOf(*v))
+	return nil
+}
+
+func parseLocation(ref reflect.Value, s string) error {
+	v, err := time.LoadLocation(s)
+	if err != nil {
+		return err
+	}
+	ref.Set(reflect.ValueOf(v))
+	return nil
+}
+
+// parseFileMode parses the octal permission bits such as "0640", "640" or
+// "0o4755". The special bits 04000, 02000 and 01000 are converted into
+// os.ModeSetuid, os.ModeSetgid and os.ModeSticky.
+func parseFileMode(ref reflect.Value, s string) error {
+	str := s
+	if strings.HasPrefix(str, "0o") || strings.HasPrefix(str, "0O") {
+		str = str[2:]
+	}
+	v, err := strconv.ParseUint(str, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file mode %q: must be octal digits", s)
+	} else if v > 07777 {
+		return fmt.Errorf("invalid file mode %q: out of range 0000-7777", s)
+	}
+
+	mode := os.FileMode(v) & os.ModePerm
+	if v&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if v&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if v&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	ref.SetUint(uint64(mode))
+	return nil
+}
+
+func parseMailAddress(ref reflect.Value, s string) error {
+	v, err := mail.ParseAddress(s)
+	if err != nil {
+		return err
+	}
+	ref.Set(reflect.ValueOf(*v))
+	return nil
+}
+
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.URLEncoding,
+	base64.RawStdEncoding,
+	base64.RawURLEncoding,
+}
+
+// parseBase64 decodes the base64 encoded string with either the standard or
+// URL-safe alphabet, with or without padding.
+func parseBase64(ref reflect.Value, s string) error {
+	v, err := decodeBase64(s)
+	if err != nil {
+		return err
+	}
+	ref.SetBytes(v)
+	return nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	for _, enc := range base64Encodings {
+		if v, err := enc.DecodeString(s); err == nil {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid base64 string")
+}
+
+// TimeParseFunc returns the ParseFunc that parses the environment variable
+// value into a time.Time value with layout. If layout is empty, time.RFC3339
+// will be used.
+func TimeParseFunc(layout string) ParseFunc {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return func(iv interface{}, envName, envValue string) error {
+		ptr, ok := iv.(*time.Time)
+		if !ok {
+			return fmt.Errorf("%w: %T is not *time.Time", ErrValue, iv)
+		}
+		v, err := time.Parse(layout, envValue)
+		if err != nil {
+			return err
+		}
+		*ptr = v
+		return nil
+	}
+}
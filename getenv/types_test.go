@@ -0,0 +1,501 @@
+package getenv
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	// test that parse duration string
+	var v time.Duration
+	assert.NoError(t, defaultParseFunc(&v, "DURATION", "1h30m"))
+	assert.Equal(t, 90*time.Minute, v)
+
+	// test that returns error if the value is not a duration string
+	assert.Error(t, defaultParseFunc(&v, "DURATION", "90"))
+	assert.Equal(t, 90*time.Minute, v)
+}
+
+func TestParseIP(t *testing.T) {
+	// test that parse IPv4 and IPv6 addresses
+	var v net.IP
+	assert.NoError(t, defaultParseFunc(&v, "IP", "10.0.0.1"))
+	assert.Equal(t, "10.0.0.1", v.String())
+	assert.NoError(t, defaultParseFunc(&v, "IP", "::1"))
+	assert.Equal(t, "::1", v.String())
+
+	// test that returns error if the value is not an IP address
+	err := defaultParseFunc(&v, "IP", "10.0.0.256")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid IP address")
+	assert.Equal(t, "::1", v.String())
+}
+
+func TestParseIPNet(t *testing.T) {
+	// test that parse CIDR notation into the network
+	var v net.IPNet
+	assert.NoError(t, defaultParseFunc(&v, "IPNET", "10.1.2.3/8"))
+	assert.Equal(t, "10.0.0.0/8", v.String())
+
+	// test that returns error if the value is not a CIDR notation
+	for _, s := range []string{"10.0.0.1", "10.0.0.0/33", "foo/8"} {
+		err := defaultParseFunc(&v, "IPNET", s)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid CIDR address")
+	}
+	assert.Equal(t, "10.0.0.0/8", v.String())
+
+	// test that Parse returns ErrEnvVar with the reason
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_IPNET")
+	}()
+	assert.NoError(t, Set("TEST_IPNET", "", &v))
+	os.Setenv("TEST_IPNET", "10.0.0.1")
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), `"TEST_IPNET" invalid CIDR address: 10.0.0.1`)
+}
+
+func TestParseURL(t *testing.T) {
+	// test that parse URL string
+	var v url.URL
+	assert.NoError(t, defaultParseFunc(&v, "URL", "https://user@example.com:8443/path?q=1"))
+	assert.Equal(t, "https", v.Scheme)
+	assert.Equal(t, "example.com:8443", v.Host)
+	assert.Equal(t, "/path", v.Path)
+	assert.Equal(t, "1", v.Query().Get("q"))
+
+	// test that returns error if the value is not a URL
+	assert.Error(t, defaultParseFunc(&v, "URL", "http://[::1"))
+	assert.Equal(t, "example.com:8443", v.Host)
+
+	// test that checked by SchemeCheckFunc
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_URL")
+	}()
+	assert.NoError(t, Set("TEST_URL", "", &v, WithCheck(SchemeCheckFunc("https"))))
+	os.Setenv("TEST_URL", "http://example.com")
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), `scheme "http" is not allowed`)
+}
+
+func TestParseTime(t *testing.T) {
+	// test that parse RFC3339 time string by default
+	var v time.Time
+	assert.NoError(t, defaultParseFunc(&v, "TIME", "2021-07-27T10:20:30+09:00"))
+	assert.Equal(t, time.Date(2021, 7, 27, 1, 20, 30, 0, time.UTC), v.UTC())
+
+	// test that returns error if the value is not RFC3339 time string
+	assert.Error(t, defaultParseFunc(&v, "TIME", "2021-07-27"))
+	assert.Equal(t, time.Date(2021, 7, 27, 1, 20, 30, 0, time.UTC), v.UTC())
+}
+
+func TestTimeParseFunc(t *testing.T) {
+	// test that parse time string with the specified layout
+	var v time.Time
+	parsefn := TimeParseFunc("2006-01-02")
+	assert.NoError(t, parsefn(&v, "TIME", "2021-07-27"))
+	assert.Equal(t, time.Date(2021, 7, 27, 0, 0, 0, 0, time.UTC), v)
+	assert.Error(t, parsefn(&v, "TIME", "2021-07-27T10:20:30Z"))
+
+	// test that use RFC3339 if the layout is empty
+	parsefn = TimeParseFunc("")
+	assert.NoError(t, parsefn(&v, "TIME", "2021-07-27T10:20:30Z"))
+	assert.Equal(t, time.Date(2021, 7, 27, 10, 20, 30, 0, time.UTC), v)
+
+	// test that returns ErrValue if the value is not *time.Time
+	var s string
+	assert.True(t, errors.Is(parsefn(&s, "TIME", "2021-07-27T10:20:30Z"), ErrValue))
+
+	// test that registered with Set
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_TIME")
+	}()
+	assert.NoError(t, Set("TEST_TIME", "", &v, WithParse(TimeParseFunc(time.RFC1123))))
+	os.Setenv("TEST_TIME", "Tue, 27 Jul 2021 10:20:30 UTC")
+	assert.NoError(t, Parse())
+	assert.Equal(t, time.Date(2021, 7, 27, 10, 20, 30, 0, time.UTC), v.UTC())
+}
+
+func TestParseSlogLevel(t *testing.T) {
+	// test that parse level names case-insensitively
+	var v slog.Level
+	for _, c := range []struct {
+		s   string
+		exp slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"Info", slog.LevelInfo},
+		{"WARN", slog.LevelWarn},
+		{"error", slog.LevelError},
+	} {
+		assert.NoError(t, defaultParseFunc(&v, "LOG_LEVEL", c.s))
+		assert.Equal(t, c.exp, v)
+	}
+
+	// test that returns error if the level is unknown
+	assert.Error(t, defaultParseFunc(&v, "LOG_LEVEL", "verbose"))
+	assert.Equal(t, slog.LevelError, v)
+}
+
+func TestParseRegexp(t *testing.T) {
+	// test that compile the regular expression
+	var v *regexp.Regexp
+	assert.NoError(t, defaultParseFunc(&v, "PATTERN", `^/api/v[0-9]+/`))
+	assert.True(t, v.MatchString("/api/v1/users"))
+	assert.False(t, v.MatchString("/static/app.js"))
+
+	// test that returns error if the pattern is invalid
+	assert.Error(t, defaultParseFunc(&v, "PATTERN", `^/api/(v[0-9]+/`))
+	assert.Equal(t, `^/api/v[0-9]+/`, v.String())
+
+	// test that registered with Set
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_PATTERN")
+	}()
+	v = nil
+	assert.NoError(t, Set("TEST_PATTERN", "", &v))
+	os.Setenv("TEST_PATTERN", `[`)
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Nil(t, v)
+}
+
+func TestParseBigInt(t *testing.T) {
+	// test that parse arbitrarily large integer
+	var v big.Int
+	assert.NoError(t, defaultParseFunc(&v, "BIGINT", "123456789012345678901234567890"))
+	assert.Equal(t, "123456789012345678901234567890", v.String())
+	assert.NoError(t, defaultParseFunc(&v, "BIGINT", "-0xff"))
+	assert.Equal(t, "-255", v.String())
+
+	// test that the copy of the value is not changed
+	cp := v
+	assert.NoError(t, defaultParseFunc(&v, "BIGINT", "-256"))
+	assert.Equal(t, "-255", cp.String())
+	assert.NoError(t, defaultParseFunc(&v, "BIGINT", "-255"))
+
+	// test that returns error if the value is not an integer
+	assert.Error(t, defaultParseFunc(&v, "BIGINT", "12.5"))
+	assert.Equal(t, "-255", v.String())
+}
+
+func TestParseBigFloat(t *testing.T) {
+	// test that parse floating-point number
+	var v big.Float
+	assert.NoError(t, defaultParseFunc(&v, "BIGFLOAT", "1.5e100"))
+	assert.Equal(t, "1.5e+100", v.Text('g', 10))
+	assert.Equal(t, uint(64), v.Prec())
+
+	// test that keep the precision of the value
+	v.SetPrec(200)
+	assert.NoError(t, defaultParseFunc(&v, "BIGFLOAT", "0.1"))
+	assert.Equal(t, uint(200), v.Prec())
+	assert.Equal(t, "0.1000000000000000000000000000000000000000", v.Text('f', 40))
+
+	// test that the copy of the value is not changed
+	cp := v
+	assert.NoError(t, defaultParseFunc(&v, "BIGFLOAT", "0.2"))
+	assert.Equal(t, "0.1", cp.Text('g', 10))
+	assert.NoError(t, defaultParseFunc(&v, "BIGFLOAT", "0.1"))
+
+	// test that returns error if the value is not a number
+	assert.Error(t, defaultParseFunc(&v, "BIGFLOAT", "1.5x"))
+	assert.Equal(t, "0.1", v.Text('g', 10))
+}
+
+func TestParseBigRat(t *testing.T) {
+	// test that parse fraction and decimal number exactly
+	var v big.Rat
+	assert.NoError(t, defaultParseFunc(&v, "BIGRAT", "3/4"))
+	assert.Equal(t, "3/4", v.String())
+	assert.NoError(t, defaultParseFunc(&v, "BIGRAT", "0.125"))
+	assert.Equal(t, "1/8", v.String())
+
+	// test that the copy of the value is not changed
+	cp := v
+	assert.NoError(t, defaultParseFunc(&v, "BIGRAT", "1/2"))
+	assert.Equal(t, "1/8", cp.String())
+	assert.NoError(t, defaultParseFunc(&v, "BIGRAT", "1/8"))
+
+	// test that returns error if the value is not a rational number
+	assert.Error(t, defaultParseFunc(&v, "BIGRAT", "1/0"))
+	assert.Equal(t, "1/8", v.String())
+}
+
+func TestParseBase64(t *testing.T) {
+	// test that decode base64 string with standard and URL-safe alphabets
+	var v []byte
+	for _, s := range []string{
+		"+/8A/w==", "+/8A/w", "-_8A_w==", "-_8A_w",
+	} {
+		v = nil
+		assert.NoError(t, defaultParseFunc(&v, "KEY", s))
+		assert.Equal(t, []byte{0xfb, 0xff, 0x00, 0xff}, v)
+	}
+
+	// test that returns error if the value is not base64 string
+	assert.Error(t, defaultParseFunc(&v, "KEY", "+/8A/w=="+"_"))
+	assert.Error(t, defaultParseFunc(&v, "KEY", "!!"))
+	assert.Equal(t, []byte{0xfb, 0xff, 0x00, 0xff}, v)
+
+	// test that check the length of decoded bytes by LenCheckFunc
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_KEY")
+	}()
+	assert.NoError(t, Set("TEST_KEY", "", &v, WithCheck(LenCheckFunc(8, 8))))
+	os.Setenv("TEST_KEY", "AAECAwQFBgc=")
+	assert.NoError(t, Parse())
+	assert.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7}, v)
+	os.Setenv("TEST_KEY", "AAECAwQFBg==")
+	err := Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), "length must be 8, got 7")
+}
+
+func TestParseLocation(t *testing.T) {
+	// test that load the location by name
+	var v *time.Location
+	assert.NoError(t, defaultParseFunc(&v, "TZ", "UTC"))
+	assert.Equal(t, time.UTC, v)
+	if _, err := time.LoadLocation("America/New_York"); err == nil {
+		assert.NoError(t, defaultParseFunc(&v, "TZ", "America/New_York"))
+		assert.Equal(t, "America/New_York", v.String())
+	}
+
+	// test that returns error if the location is unknown
+	assert.Error(t, defaultParseFunc(&v, "TZ", "Mars/Olympus_Mons"))
+	assert.NotNil(t, v)
+
+	// test that registered with Set
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_TZ")
+	}()
+	v = nil
+	assert.NoError(t, Set("TEST_TZ", "", &v))
+	os.Setenv("TEST_TZ", "UTC")
+	assert.NoError(t, Parse())
+	assert.Equal(t, time.UTC, v)
+}
+
+func TestParseFileMode(t *testing.T) {
+	// test that parse octal permission bits
+	var v os.FileMode
+	for s, exp := range map[string]os.FileMode{
+		"0640":   0640,
+		"640":    0640,
+		"0o755":  0755,
+		"0":      0,
+		"4755":   os.ModeSetuid | 0755,
+		"02770":  os.ModeSetgid | 0770,
+		"1777":   os.ModeSticky | 0777,
+		"0o7777": os.ModeSetuid | os.ModeSetgid | os.ModeSticky | 0777,
+	} {
+		assert.NoError(t, defaultParseFunc(&v, "MODE", s), s)
+		assert.Equal(t, exp, v, s)
+	}
+
+	// test that returns error if the value is not octal or out of range
+	v = 0600
+	for _, s := range []string{"0x1ff", "0648", "rw-r--r--", "-644", "10000"} {
+		assert.Error(t, defaultParseFunc(&v, "MODE", s), s)
+	}
+	assert.Equal(t, os.FileMode(0600), v)
+}
+
+func TestExtendedDuration(t *testing.T) {
+	// test that parse durations with days and weeks
+	for s, exp := range map[string]time.Duration{
+		"7d":      7 * 24 * time.Hour,
+		"2w":      14 * 24 * time.Hour,
+		"1.5d":    36 * time.Hour,
+		"2w3d12h": (17*24 + 12) * time.Hour,
+		"1d30m":   24*time.Hour + 30*time.Minute,
+		"-1d":     -24 * time.Hour,
+		"+1w":     7 * 24 * time.Hour,
+		"1h30m":   90 * time.Minute,
+		"500ms":   500 * time.Millisecond,
+	} {
+		v, err := ParseDuration(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, exp, v, s)
+	}
+
+	// test that returns error if the value is invalid
+	for _, s := range []string{"", "d", "-", "7", "7x", "1.2.3d", "d7", "99999999w", "1d1", "1dd"} {
+		_, err := ParseDuration(s)
+		assert.Error(t, err, s)
+	}
+
+	// test that ExtendedDurationParseFunc parses durations with days and weeks
+	parsefn := ExtendedDurationParseFunc()
+	var v time.Duration
+	assert.NoError(t, parsefn(&v, "RETENTION", "30d"))
+	assert.Equal(t, 30*24*time.Hour, v)
+	var m map[string]time.Duration
+	assert.NoError(t, parsefn(&m, "RETENTIONS", "logs=7d,metrics=2w"))
+	assert.Equal(t, map[string]time.Duration{"logs": 7 * 24 * time.Hour, "metrics": 14 * 24 * time.Hour}, m)
+
+	// test that defaultParseFunc does not accept days and weeks
+	assert.Error(t, defaultParseFunc(&v, "RETENTION", "7d"))
+	assert.Equal(t, 30*24*time.Hour, v)
+}
+
+func TestParseDurationSlice(t *testing.T) {
+	// test that parse each element as duration
+	var v []time.Duration
+	assert.NoError(t, defaultParseFunc(&v, "RETRY_BACKOFFS", "100ms,500ms, 2s,10s"))
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second, 10 * time.Second,
+	}, v)
+
+	// test that returns error with the index of the invalid element
+	err := defaultParseFunc(&v, "RETRY_BACKOFFS", "100ms,500,2s")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "element 1 of RETRY_BACKOFFS is not a valid time.Duration")
+	assert.Len(t, v, 4)
+
+	// test that parse each element with days and weeks
+	assert.NoError(t, ExtendedDurationParseFunc()(&v, "RETENTIONS", "1d,1w"))
+	assert.Equal(t, []time.Duration{24 * time.Hour, 7 * 24 * time.Hour}, v)
+
+	// test that registered with Set
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_RETRY_BACKOFFS")
+	}()
+	assert.NoError(t, Set("TEST_RETRY_BACKOFFS", "", &v))
+	os.Setenv("TEST_RETRY_BACKOFFS", "1s,2s")
+	assert.NoError(t, Parse())
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, v)
+}
+
+func TestParseIPNetSlice(t *testing.T) {
+	// test that parse each element as CIDR notation
+	var v []net.IPNet
+	assert.NoError(t, defaultParseFunc(&v, "TRUSTED_PROXIES", "10.0.0.0/8, 192.168.0.0/16,fd00::/8"))
+	if assert.Len(t, v, 3) {
+		assert.Equal(t, "10.0.0.0/8", v[0].String())
+		assert.Equal(t, "192.168.0.0/16", v[1].String())
+		assert.Equal(t, "fd00::/8", v[2].String())
+	}
+	assert.True(t, v[1].Contains(net.ParseIP("192.168.1.1")))
+
+	// test that returns error with the index of the invalid element
+	err := defaultParseFunc(&v, "TRUSTED_PROXIES", "10.0.0.0/8,192.168.0.1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "element 1 of TRUSTED_PROXIES is not a valid net.IPNet")
+	assert.Len(t, v, 3)
+
+	// test that registered with Set
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_TRUSTED_PROXIES")
+	}()
+	assert.NoError(t, Set("TEST_TRUSTED_PROXIES", "", &v))
+	os.Setenv("TEST_TRUSTED_PROXIES", "127.0.0.0/8")
+	assert.NoError(t, Parse())
+	if assert.Len(t, v, 1) {
+		assert.Equal(t, "127.0.0.0/8", v[0].String())
+	}
+}
+
+func TestParseMailAddress(t *testing.T) {
+	// test that parse email address
+	var v mail.Address
+	assert.NoError(t, defaultParseFunc(&v, "ALERT_EMAIL", "ops@example.com"))
+	assert.Equal(t, mail.Address{Address: "ops@example.com"}, v)
+	assert.NoError(t, defaultParseFunc(&v, "ALERT_EMAIL", `"Ops Team" <ops@example.com>`))
+	assert.Equal(t, mail.Address{Name: "Ops Team", Address: "ops@example.com"}, v)
+
+	// test that returns error if the value is not an email address
+	for _, s := range []string{"ops", "ops@", "<ops@example.com"} {
+		assert.Error(t, defaultParseFunc(&v, "ALERT_EMAIL", s), s)
+	}
+	assert.Equal(t, mail.Address{Name: "Ops Team", Address: "ops@example.com"}, v)
+}
+
+func TestParseURLSlice(t *testing.T) {
+	// test that parse each element as URL
+	var v []*url.URL
+	assert.NoError(t, defaultParseFunc(&v, "ETCD_ENDPOINTS", "https://etcd-0:2379, https://etcd-1:2379"))
+	if assert.Len(t, v, 2) {
+		assert.Equal(t, "etcd-0:2379", v[0].Host)
+		assert.Equal(t, "etcd-1:2379", v[1].Host)
+	}
+
+	// test that returns error with the index of the invalid element
+	err := defaultParseFunc(&v, "ETCD_ENDPOINTS", "https://etcd-0:2379,https://[etcd-1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "element 1 of ETCD_ENDPOINTS is not a valid *url.URL")
+	assert.Len(t, v, 2)
+
+	// test that restrict the schemes of the elements by SchemeCheckFunc
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_ETCD_ENDPOINTS")
+	}()
+	assert.NoError(t, Set("TEST_ETCD_ENDPOINTS", "", &v, WithCheck(SchemeCheckFunc("https"))))
+	os.Setenv("TEST_ETCD_ENDPOINTS", "https://etcd-0:2379,http://etcd-1:2379")
+	err = Parse()
+	assert.True(t, errors.Is(err, ErrEnvVar))
+	assert.Contains(t, err.Error(), `element 1 of TEST_ETCD_ENDPOINTS: scheme "http" is not allowed`)
+	os.Setenv("TEST_ETCD_ENDPOINTS", "https://etcd-2:2379")
+	assert.NoError(t, Parse())
+	if assert.Len(t, v, 1) {
+		assert.Equal(t, "etcd-2:2379", v[0].Host)
+	}
+}
+
+func TestParseSQLNullTypes(t *testing.T) {
+	defer func() {
+		defaultSet = NewEnvSet()
+		os.Unsetenv("TEST_NULL_STRING")
+		os.Unsetenv("TEST_NULL_INT64")
+		os.Unsetenv("TEST_NULL_BOOL")
+	}()
+
+	// test that the values are invalid if the environment variables are not defined
+	var str sql.NullString
+	var i64 sql.NullInt64
+	var bol sql.NullBool
+	assert.NoError(t, Set("TEST_NULL_STRING", "", &str))
+	assert.NoError(t, Set("TEST_NULL_INT64", "", &i64))
+	assert.NoError(t, Set("TEST_NULL_BOOL", "", &bol))
+	assert.NoError(t, Parse())
+	assert.False(t, str.Valid)
+	assert.False(t, i64.Valid)
+	assert.False(t, bol.Valid)
+
+	// test that the values are populated if the environment variables are defined
+	os.Setenv("TEST_NULL_STRING", "foo")
+	os.Setenv("TEST_NULL_INT64", "-123")
+	os.Setenv("TEST_NULL_BOOL", "true")
+	assert.NoError(t, Parse())
+	assert.Equal(t, sql.NullString{String: "foo", Valid: true}, str)
+	assert.Equal(t, sql.NullInt64{Int64: -123, Valid: true}, i64)
+	assert.Equal(t, sql.NullBool{Bool: true, Valid: true}, bol)
+
+	// test that keep the value if the value cannot be converted
+	i64 = sql.NullInt64{}
+	assert.Error(t, defaultParseFunc(&i64, "TEST_NULL_INT64", "foo"))
+	assert.Equal(t, sql.NullInt64{}, i64)
+}
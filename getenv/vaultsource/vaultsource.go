@@ -0,0 +1,352 @@
+// Package vaultsource provides the getenv.Source that resolves the
+// registered variables from the KV secrets engine of HashiCorp Vault, so
+// that the secrets never need to be materialized as the environment
+// variables.
+//
+//	src, err := vaultsource.New(ctx, vaultsource.Config{
+//		Address: "https://vault.example.com:8200",
+//		Auth:    vaultsource.AppRoleAuth(roleID, secretID),
+//	}, map[string]vaultsource.Ref{
+//		"DB_PASSWORD": {Path: "secret/data/myapp/db", Field: "password"},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	go src.Run(ctx)
+//	getenv.SetSources(getenv.EnvSource(), src)
+package vaultsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mah0x211/go-getenv/getenv"
+)
+
+// Ref refers to the field of the secret.
+type Ref struct {
+	// path of the secret relative to /v1/, e.g. "secret/data/myapp/db" for
+	// the KV version 2 and "kv/myapp/db" for the KV version 1
+	Path string
+	// field of the secret, or the variable name if empty
+	Field string
+}
+
+// Config configures the Source.
+type Config struct {
+	// address of the Vault server such as "https://vault.example.com:8200"
+	Address string
+	// namespace of Vault Enterprise, sent as the X-Vault-Namespace header
+	Namespace string
+	// authentication method
+	Auth Auth
+	// http.DefaultClient is used if nil
+	HTTPClient *http.Client
+}
+
+// Auth is the authentication method to obtain the token.
+type Auth interface {
+	login(ctx context.Context, s *Source) (*authInfo, error)
+}
+
+// authInfo is the "auth" object of the response of the login and the renew
+// requests.
+type authInfo struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+type tokenAuth string
+
+func (a tokenAuth) login(context.Context, *Source) (*authInfo, error) {
+	// the static token is not managed by the Source
+	return &authInfo{ClientToken: string(a)}, nil
+}
+
+// TokenAuth authenticates by the token as is.
+func TokenAuth(token string) Auth {
+	return tokenAuth(token)
+}
+
+type appRoleAuth struct {
+	mount    string
+	roleID   string
+	secretID string
+}
+
+func (a appRoleAuth) login(ctx context.Context, s *Source) (*authInfo, error) {
+	var res struct {
+		Auth *authInfo `json:"auth"`
+	}
+	body := map[string]string{"role_id": a.roleID, "secret_id": a.secretID}
+	if err := s.do(ctx, http.MethodPost, "auth/"+a.mount+"/login", "", body, &res); err != nil {
+		return nil, err
+	} else if res.Auth == nil {
+		return nil, fmt.Errorf("vault: no auth in the response of the approle login")
+	}
+	return res.Auth, nil
+}
+
+// AppRoleAuth authenticates by the AppRole auth method mounted at "approle".
+// Refresh renews the token if it is about to expire, and obtains it again by
+// the login if it cannot be renewed. Run calls Refresh in the background
+// before the token expires.
+func AppRoleAuth(roleID, secretID string) Auth {
+	return appRoleAuth{mount: "approle", roleID: roleID, secretID: secretID}
+}
+
+// renewBefore is the margin to renew the token or the secrets before their
+// leases expire. Half of the lease is used instead if it is shorter.
+const renewBefore = 30 * time.Second
+
+// renewAt returns the time to renew the lease of ttl seconds, or the zero
+// time if the lease does not expire.
+func renewAt(now time.Time, ttl int) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	lease := time.Duration(ttl) * time.Second
+	if margin := lease / 2; margin < renewBefore {
+		return now.Add(lease - margin)
+	}
+	return now.Add(lease - renewBefore)
+}
+
+// earlier returns the earlier one of a and b ignoring the zero time.
+func earlier(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// Source is the getenv.Source named "vault" that supplies the fields of the
+// secrets referred by the variable names.
+type Source struct {
+	cfg  Config
+	refs map[string]Ref
+	now  func() time.Time
+
+	// serializes Refresh
+	refreshMu sync.Mutex
+	token     string
+	renewable bool
+	// time to renew the token, or zero if it does not expire
+	tokenRenewAt time.Time
+	// time to renew the token or to read the secrets again
+	renewAt time.Time
+
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+var _ getenv.Source = (*Source)(nil)
+
+// New authenticates to Vault and returns the Source that has the values of
+// refs fetched. The secrets or the fields that do not exist are treated as
+// undefined. Call Run to keep the token and the values up to date.
+func New(ctx context.Context, cfg Config, refs map[string]Ref) (*Source, error) {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	cfg.Address = strings.TrimRight(cfg.Address, "/")
+	s := &Source{cfg: cfg, refs: refs, now: time.Now}
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Name returns "vault".
+func (s *Source) Name() string {
+	return "vault"
+}
+
+// Lookup returns the value of name fetched by the last Refresh.
+func (s *Source) Lookup(name string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[name]
+	return v, ok
+}
+
+// Refresh fetches the values of the secrets again. The token is renewed or
+// obtained again if it is about to expire.
+func (s *Source) Refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	if err := s.authenticate(ctx); err != nil {
+		return err
+	}
+
+	// fetch each secret once
+	path2data := map[string]map[string]interface{}{}
+	values := map[string]string{}
+	next := s.tokenRenewAt
+	for name, ref := range s.refs {
+		data, ok := path2data[ref.Path]
+		if !ok {
+			var ttl int
+			var err error
+			if data, ttl, err = s.read(ctx, ref.Path); err != nil {
+				return err
+			}
+			path2data[ref.Path] = data
+			next = earlier(next, renewAt(s.now(), ttl))
+		}
+
+		field := ref.Field
+		if field == "" {
+			field = name
+		}
+		switch v := data[field].(type) {
+		case nil:
+		case string:
+			values[name] = v
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+
+	s.mu.Lock()
+	s.values = values
+	s.mu.Unlock()
+	s.renewAt = next
+	return nil
+}
+
+// Run calls Refresh whenever the token or the leases of the secrets are
+// about to expire until ctx is done. It returns the error of Refresh, or the
+// error of ctx.
+func (s *Source) Run(ctx context.Context) error {
+	for {
+		s.refreshMu.Lock()
+		next := s.renewAt
+		s.refreshMu.Unlock()
+
+		// wait for ctx only if nothing expires
+		var t *time.Timer
+		var c <-chan time.Time
+		if !next.IsZero() {
+			t = time.NewTimer(next.Sub(s.now()))
+			c = t.C
+		}
+		select {
+		case <-ctx.Done():
+			if t != nil {
+				t.Stop()
+			}
+			return ctx.Err()
+		case <-c:
+			if err := s.Refresh(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// authenticate obtains the token if it has not been obtained or is about to
+// expire, and renews it if possible.
+func (s *Source) authenticate(ctx context.Context) error {
+	if s.token != "" && (s.tokenRenewAt.IsZero() || s.now().Before(s.tokenRenewAt)) {
+		return nil
+	}
+
+	var auth *authInfo
+	if s.token != "" && s.renewable {
+		var res struct {
+			Auth *authInfo `json:"auth"`
+		}
+		if err := s.do(ctx, http.MethodPost, "auth/token/renew-self", s.token, struct{}{}, &res); err == nil && res.Auth != nil {
+			auth = res.Auth
+		}
+	}
+	if auth == nil {
+		var err error
+		if auth, err = s.cfg.Auth.login(ctx, s); err != nil {
+			return err
+		}
+	}
+
+	s.token, s.renewable = auth.ClientToken, auth.Renewable
+	s.tokenRenewAt = renewAt(s.now(), auth.LeaseDuration)
+	return nil
+}
+
+// read returns the data of the secret of path and the lease duration of it
+// in seconds. The data of the KV version 2 is unwrapped. The nil map is
+// returned if the secret does not exist.
+func (s *Source) read(ctx context.Context, path string) (map[string]interface{}, int, error) {
+	var res struct {
+		Data          map[string]interface{} `json:"data"`
+		LeaseDuration int                    `json:"lease_duration"`
+	}
+	if err := s.do(ctx, http.MethodGet, path, s.token, nil, &res); err == errNotFound {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	if data, ok := res.Data["data"].(map[string]interface{}); ok {
+		if _, ok = res.Data["metadata"]; ok {
+			return data, res.LeaseDuration, nil
+		}
+	}
+	return res.Data, res.LeaseDuration, nil
+}
+
+var errNotFound = fmt.Errorf("not found")
+
+// do sends the request to the path relative to /v1/ and decodes the JSON
+// response into res.
+func (s *Source) do(ctx context.Context, method, path, token string, body, res interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.Address+"/v1/"+strings.TrimLeft(path, "/"), r)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if s.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.cfg.Namespace)
+	}
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	} else if resp.StatusCode/100 != 2 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("vault: %s %s: %s %s", method, path, resp.Status, strings.Join(e.Errors, ", "))
+	}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err = dec.Decode(res); err != nil {
+		return fmt.Errorf("vault: %s %s: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package vaultsource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeVault struct {
+	logins    int
+	renews    int
+	ttl       int
+	namespace string
+	// lease of kv/myapp/api and the number of the reads of it
+	lease int
+	reads int
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON := func(v interface{}) {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	auth := func(token string) map[string]interface{} {
+		return map[string]interface{}{"auth": map[string]interface{}{
+			"client_token": token, "lease_duration": f.ttl, "renewable": true,
+		}}
+	}
+
+	switch r.URL.Path {
+	case "/v1/auth/approle/login":
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(map[string]interface{}{"errors": []string{"invalid role or secret ID"}})
+			return
+		}
+		f.logins++
+		writeJSON(auth("login-token"))
+		return
+	case "/v1/auth/token/renew-self":
+		f.renews++
+		writeJSON(auth(r.Header.Get("X-Vault-Token")))
+		return
+	}
+
+	if tok := r.Header.Get("X-Vault-Token"); tok != "login-token" && tok != "static" {
+		w.WriteHeader(http.StatusForbidden)
+		writeJSON(map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/secret/data/myapp/db":
+		f.namespace = r.Header.Get("X-Vault-Namespace")
+		writeJSON(map[string]interface{}{"data": map[string]interface{}{
+			"data":     map[string]interface{}{"password": "s3cret", "DB_PORT": 5432},
+			"metadata": map[string]interface{}{"version": 1},
+		}})
+	case "/v1/kv/myapp/api":
+		f.reads++
+		writeJSON(map[string]interface{}{
+			"data":           map[string]interface{}{"key": "api-key"},
+			"lease_duration": f.lease,
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(map[string]interface{}{"errors": []string{}})
+	}
+}
+
+func TestNew(t *testing.T) {
+	fv := &fakeVault{ttl: 60}
+	srv := httptest.NewServer(fv)
+	defer srv.Close()
+
+	refs := map[string]Ref{
+		"DB_PASSWORD": {Path: "secret/data/myapp/db", Field: "password"},
+		"DB_PORT":     {Path: "secret/data/myapp/db"},
+		"API_KEY":     {Path: "kv/myapp/api", Field: "key"},
+		"MISSING":     {Path: "secret/data/none"},
+		"NO_FIELD":    {Path: "kv/myapp/api"},
+	}
+
+	// test that resolves the fields of the KV version 1 and 2 secrets
+	src, err := New(context.Background(), Config{
+		Address:   srv.URL + "/",
+		Namespace: "ns1",
+		Auth:      AppRoleAuth("role", "secret"),
+	}, refs)
+	assert.NoError(t, err)
+	assert.Equal(t, "vault", src.Name())
+	for name, want := range map[string]string{
+		"DB_PASSWORD": "s3cret",
+		"DB_PORT":     "5432",
+		"API_KEY":     "api-key",
+	} {
+		v, ok := src.Lookup(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, v)
+	}
+	for _, name := range []string{"MISSING", "NO_FIELD"} {
+		_, ok := src.Lookup(name)
+		assert.False(t, ok, name)
+	}
+	assert.Equal(t, 1, fv.logins)
+	assert.Equal(t, "ns1", fv.namespace)
+
+	// test that renews the token only if it is about to expire
+	now := time.Now()
+	src.now = func() time.Time { return now }
+	assert.NoError(t, src.Refresh(context.Background()))
+	assert.Equal(t, 0, fv.renews)
+	now = now.Add(time.Minute)
+	assert.NoError(t, src.Refresh(context.Background()))
+	assert.Equal(t, 1, fv.renews)
+	assert.Equal(t, 1, fv.logins)
+
+	// test that uses the static token
+	_, err = New(context.Background(), Config{Address: srv.URL, Namespace: "ns1", Auth: TokenAuth("static")}, refs)
+	assert.NoError(t, err)
+
+	// test that returns the error of Vault
+	_, err = New(context.Background(), Config{Address: srv.URL, Auth: AppRoleAuth("role", "wrong")}, refs)
+	assert.Contains(t, err.Error(), "invalid role or secret ID")
+	_, err = New(context.Background(), Config{Address: srv.URL, Auth: TokenAuth("wrong")}, refs)
+	assert.Contains(t, err.Error(), "permission denied")
+}
+
+func TestSource_Run(t *testing.T) {
+	fv := &fakeVault{ttl: 1}
+	srv := httptest.NewServer(fv)
+	defer srv.Close()
+	refs := map[string]Ref{"API_KEY": {Path: "kv/myapp/api", Field: "key"}}
+
+	// test that renews the token before it expires
+	src, err := New(context.Background(), Config{Address: srv.URL, Auth: AppRoleAuth("role", "secret")}, refs)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 1200*time.Millisecond)
+	defer cancel()
+	err = src.Run(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 1, fv.logins)
+	assert.Equal(t, 2, fv.renews)
+	assert.Equal(t, 3, fv.reads)
+
+	// test that reads the secret again before its lease expires
+	fv = &fakeVault{lease: 1}
+	srv2 := httptest.NewServer(fv)
+	defer srv2.Close()
+	src, err = New(context.Background(), Config{Address: srv2.URL, Auth: TokenAuth("static")}, refs)
+	assert.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 1200*time.Millisecond)
+	defer cancel()
+	err = src.Run(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 0, fv.renews)
+	assert.Equal(t, 3, fv.reads)
+
+	// test that waits for ctx if nothing expires
+	fv = &fakeVault{}
+	srv3 := httptest.NewServer(fv)
+	defer srv3.Close()
+	src, err = New(context.Background(), Config{Address: srv3.URL, Auth: TokenAuth("static")}, refs)
+	assert.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = src.Run(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 1, fv.reads)
+
+	// test that returns the error of Refresh
+	src, err = New(context.Background(), Config{Address: srv.URL, Auth: AppRoleAuth("role", "secret")}, refs)
+	assert.NoError(t, err)
+	srv.Close()
+	err = src.Run(context.Background())
+	assert.Contains(t, err.Error(), "vault: ")
+}
@@ -1,5 +1,14 @@
 module github.com/mah0x211/go-getenv
 
-go 1.14
+go 1.21
 
-require github.com/stretchr/testify v1.6.1
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/stretchr/testify v1.6.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)